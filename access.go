@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// The bcrypt cost used by caddy hash-password
+const basicAuthBcryptCost = 14
+
+func registerAccessTools(s *server.MCPServer) {
+	restrictRouteByIP := mcp.NewTool("restrict_route_by_ip",
+		mcp.WithDescription(`
+		Use the restrict_route_by_ip tool to restrict which client IP addresses can use a route of a caddy server.
+
+		A subroute is inserted in front of the route's handlers that responds with 403 Forbidden to clients outside the allowed ranges or inside the denied ranges. Blocked requests are rejected instead of falling through to the next route.
+		The updated configuration is applied to the caddy server and returned in JSON format.
+
+		Notes:
+			Ranges are IP addresses or CIDRs such as 10.0.0.0/8, or private_ranges for all private address ranges.
+			When allowed ranges are given, only clients inside them can use the route. Denied ranges are blocked even if they are also allowed.
+			The client_ip matcher honors the server's trusted_proxies setting; use remote_ip to match the address of the direct connection.
+		`),
+		mcp.WithString("server_name",
+			mcp.Required(),
+			mcp.Description("The name of the server in apps/http/servers"),
+		),
+		mcp.WithString("route",
+			mcp.Required(),
+			mcp.Description("The route index or a host matched by the route"),
+		),
+		mcp.WithArray("allowed",
+			mcp.Description("The IP addresses or CIDRs allowed to use the route"),
+			mcp.Items(map[string]any{"type": "string"}),
+		),
+		mcp.WithArray("denied",
+			mcp.Description("The IP addresses or CIDRs denied from using the route"),
+			mcp.Items(map[string]any{"type": "string"}),
+		),
+		mcp.WithString("matcher",
+			mcp.Description("The matcher to use: client_ip or remote_ip"),
+			mcp.Enum("client_ip", "remote_ip"),
+			mcp.DefaultString("client_ip"),
+		),
+	)
+
+	// Add restrict route by IP tool handler
+	s.AddTool(restrictRouteByIP, restrictRouteByIPHandler)
+
+	protectServerBasicAuth := mcp.NewTool("protect_server_basicauth",
+		mcp.WithDescription(`
+		Use the protect_server_basicauth tool to require HTTP basic authentication for every request to a caddy server.
+
+		A route without matchers running the authentication handler is inserted as the first route of the server, so it applies to every route, including routes added later. Calling the tool again replaces the credentials.
+		The updated configuration is applied to the caddy server and returned in JSON format.
+
+		Notes:
+			The password is hashed with bcrypt before it is put in the configuration; the plain text password is never stored.
+			Basic authentication sends credentials with every request, so only use it on servers served over HTTPS.
+		`),
+		mcp.WithString("server_name",
+			mcp.Required(),
+			mcp.Description("The name of the server in apps/http/servers"),
+		),
+		mcp.WithString("username",
+			mcp.Required(),
+			mcp.Description("The username clients must provide"),
+		),
+		mcp.WithString("password",
+			mcp.Required(),
+			mcp.Description("The password clients must provide"),
+		),
+		mcp.WithString("realm",
+			mcp.Description("The realm shown by browsers in the login prompt"),
+		),
+	)
+
+	// Add protect server basic auth tool handler
+	s.AddTool(protectServerBasicAuth, protectServerBasicAuthHandler)
+}
+
+// Check that each range is an IP address, a CIDR or the private_ranges shortcut
+func validateIPRanges(ranges []string) error {
+	for _, r := range ranges {
+		if r == "private_ranges" {
+			continue
+		}
+		if _, err := netip.ParsePrefix(r); err == nil {
+			continue
+		}
+		if _, err := netip.ParseAddr(r); err == nil {
+			continue
+		}
+		return fmt.Errorf("invalid IP address or CIDR: %q", r)
+	}
+
+	return nil
+}
+
+// Build a route responding with 403 Forbidden to requests matching the matcher set
+func forbiddenRoute(match map[string]any) map[string]any {
+	return map[string]any{
+		"match": []any{match},
+		"handle": []any{
+			map[string]any{
+				"handler":     "static_response",
+				"status_code": 403,
+			},
+		},
+		"terminal": true,
+	}
+}
+
+// Restrict a route to allowed client IP ranges
+func restrictRouteByIPHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	serverName, err := request.RequireString("server_name")
+	if err != nil {
+		return nil, err
+	}
+
+	selector, err := request.RequireString("route")
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := request.GetStringSlice("allowed", nil)
+	denied := request.GetStringSlice("denied", nil)
+	if len(allowed) == 0 && len(denied) == 0 {
+		return nil, fmt.Errorf("at least one allowed or denied range is required")
+	}
+
+	if err := validateIPRanges(allowed); err != nil {
+		return nil, err
+	}
+	if err := validateIPRanges(denied); err != nil {
+		return nil, err
+	}
+
+	matcher := request.GetString("matcher", "client_ip")
+	if matcher != "client_ip" && matcher != "remote_ip" {
+		return nil, fmt.Errorf("unsupported matcher: %s", matcher)
+	}
+
+	cfg, err := fetchConfigMap(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	srv, err := httpServer(cfg, serverName)
+	if err != nil {
+		return nil, err
+	}
+
+	_, route, err := resolveRoute(srv, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	var guards []any
+	if len(denied) > 0 {
+		guards = append(guards, forbiddenRoute(map[string]any{
+			matcher: map[string]any{"ranges": denied},
+		}))
+	}
+	if len(allowed) > 0 {
+		guards = append(guards, forbiddenRoute(map[string]any{
+			"not": []any{
+				map[string]any{
+					matcher: map[string]any{"ranges": allowed},
+				},
+			},
+		}))
+	}
+
+	handlers, _ := route["handle"].([]any)
+	route["handle"] = append([]any{
+		map[string]any{
+			"handler": "subroute",
+			"routes":  guards,
+		},
+	}, handlers...)
+
+	return applyConfigMap(ctx, cfg)
+}
+
+// The @id of the basic authentication route of a server
+func basicAuthRouteID(serverName string) string {
+	return fmt.Sprintf("caddy_mcp_basicauth_%s", serverName)
+}
+
+// Require basic authentication for every request to a server
+func protectServerBasicAuthHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	serverName, err := request.RequireString("server_name")
+	if err != nil {
+		return nil, err
+	}
+
+	username, err := request.RequireString("username")
+	if err != nil {
+		return nil, err
+	}
+
+	password, err := request.RequireString("password")
+	if err != nil {
+		return nil, err
+	}
+
+	if username == "" || password == "" {
+		return nil, fmt.Errorf("username and password must not be empty")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), basicAuthBcryptCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %v", err)
+	}
+
+	basicAuth := map[string]any{
+		"hash": map[string]any{"algorithm": "bcrypt"},
+		"accounts": []any{
+			map[string]any{
+				"username": username,
+				"password": string(hash),
+			},
+		},
+	}
+	if realm := request.GetString("realm", ""); realm != "" {
+		basicAuth["realm"] = realm
+	}
+
+	cfg, err := fetchConfigMap(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	srv, err := httpServer(cfg, serverName)
+	if err != nil {
+		return nil, err
+	}
+
+	id := basicAuthRouteID(serverName)
+
+	routes := []any{
+		map[string]any{
+			"@id": id,
+			"handle": []any{
+				map[string]any{
+					"handler": "authentication",
+					"providers": map[string]any{
+						"http_basic": basicAuth,
+					},
+				},
+			},
+		},
+	}
+	for _, r := range serverRoutes(srv) {
+		if route, ok := r.(map[string]any); ok && route["@id"] == id {
+			continue
+		}
+		routes = append(routes, r)
+	}
+	srv["routes"] = routes
+
+	return applyConfigMap(ctx, cfg)
+}
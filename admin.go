@@ -0,0 +1,434 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/caddyconfig"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Returned by fetchConfig when Caddy runs without a configuration
+var errNoConfig = errors.New("no configuration currently loaded")
+
+// Returned by applyConfig when the configuration changed after the tool read it
+var errConfigChanged = errors.New("config changed underneath you, re-read and retry")
+
+// The wait before the first retry of a failed admin request, doubled for every further retry
+const retryBackoff = 250 * time.Millisecond
+
+func (e *caddyError) Error() string {
+	return fmt.Sprintf("caddy returned status %d: %s", e.StatusCode, e.Message)
+}
+
+// Check that the admin API URL of a flag is an http or https URL and return it without a trailing slash,
+// so handlers can append admin API paths to it
+func normalizeAdminURL(flagName, raw string) (string, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid -%s %q: %v", flagName, raw, err)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("invalid -%s %q: it must start with http:// or https://, for example http://127.0.0.1:2019", flagName, raw)
+	}
+
+	if u.Host == "" {
+		return "", fmt.Errorf("invalid -%s %q: missing host", flagName, raw)
+	}
+
+	if u.RawQuery != "" || u.Fragment != "" {
+		return "", fmt.Errorf("invalid -%s %q: it must not have a query or fragment", flagName, raw)
+	}
+
+	u.Path = strings.TrimRight(u.Path, "/")
+	u.RawPath = ""
+
+	return u.String(), nil
+}
+
+// Build the TLS configuration for an admin API served over HTTPS from the -admin-* flags.
+// It returns nil when no flag is set, so the default transport verifies caddy against the system pool.
+func adminTLSConfig() (*tls.Config, error) {
+	if adminCert == "" && adminKey == "" && adminCA == "" && !adminInsecure {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: adminInsecure,
+	}
+
+	if (adminCert == "") != (adminKey == "") {
+		return nil, fmt.Errorf("-admin-cert and -admin-key must be set together")
+	}
+
+	if adminCert != "" {
+		cert, err := tls.LoadX509KeyPair(adminCert, adminKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load admin client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if adminCA != "" {
+		pem, err := os.ReadFile(adminCA)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read admin CA: %v", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM certificates found in %s", adminCA)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return tlsConfig, nil
+}
+
+// Send a request built for the Caddy admin API, adding the admin token when one is set
+func adminDo(req *http.Request) (*http.Response, error) {
+	if adminToken != "" {
+		req.Header.Set("Authorization", "Bearer "+adminToken)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	recordAdminStatus(req, resp.StatusCode)
+	return resp, nil
+}
+
+// Log an admin API exchange at debug level, leaving out the bodies when -log-redact is set
+func logAdminRequest(ctx context.Context, method, path string, body []byte, status int, respBody []byte) {
+	if !slog.Default().Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+
+	attrs := []any{
+		"method", method,
+		"path", path,
+		"request_bytes", len(body),
+		"status", status,
+		"response_bytes", len(respBody),
+	}
+	if !logRedact {
+		attrs = append(attrs, "request_body", string(body), "response_body", string(respBody))
+	}
+
+	slog.DebugContext(ctx, "caddy admin request", attrs...)
+}
+
+// Send a request to the Caddy admin API and return the response status and body
+func adminRequest(ctx context.Context, method, path string, body []byte) (int, []byte, error) {
+	return adminRequestHeader(ctx, method, path, body, nil)
+}
+
+// Send a request to the Caddy admin API with extra request headers
+func adminRequestHeader(ctx context.Context, method, path string, body []byte, header http.Header) (int, []byte, error) {
+	status, _, respBody, err := adminExchange(ctx, method, path, body, header)
+	return status, respBody, err
+}
+
+// Send a request to the Caddy admin API and return the response status, headers and body
+func adminExchange(ctx context.Context, method, path string, body []byte, header http.Header) (int, http.Header, []byte, error) {
+	if err := checkTicket(ctx, method, path); err != nil {
+		return 0, nil, nil, err
+	}
+
+	// Retry connection errors and 5xx responses of idempotent requests, like a caddy briefly restarting during a reload
+	for attempt := 0; ; attempt++ {
+		status, respHeader, respBody, err := adminAttempt(ctx, method, path, body, header)
+		if attempt >= retries || !retryable(ctx, method, status, err) {
+			return status, respHeader, respBody, err
+		}
+
+		backoff := retryBackoff << attempt
+		slog.Debug("retrying caddy admin request", "method", method, "path", path, "status", status, "error", err, "backoff", backoff)
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return 0, nil, nil, ctx.Err()
+		}
+	}
+}
+
+// Check whether a failed admin request may succeed when sent again.
+// Only errors of the connection are retried: TLS and certificate errors fail the same way every time.
+func retryable(ctx context.Context, method string, status int, err error) bool {
+	if err == nil {
+		// Caddy may have applied part of a request before failing it, like a /load that failed to start the new config
+		return status >= 500 && idempotent(method)
+	}
+
+	if ctx.Err() != nil {
+		return false
+	}
+
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.ECONNRESET):
+		return true
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		// The connection closed before the response, so the request may have been applied already
+		return idempotent(method)
+	default:
+		return false
+	}
+}
+
+// Check whether sending a request twice has the same effect as sending it once
+func idempotent(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodPut || method == http.MethodDelete
+}
+
+// Send one request to the Caddy admin API
+func adminAttempt(ctx context.Context, method, path string, body []byte, header http.Header) (int, http.Header, []byte, error) {
+	var reqBody io.Reader
+	if body != nil {
+		reqBody = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("%s%s", adminURL(ctx), path), reqBody)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	resp, err := adminDo(req)
+	if err != nil {
+		slog.Debug("caddy admin request failed", "method", method, "path", path, "request_bytes", len(body), "error", err)
+		return 0, nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+
+	logAdminRequest(ctx, method, path, body, resp.StatusCode, respBody)
+
+	return resp.StatusCode, resp.Header, respBody, nil
+}
+
+// Check that a configuration is a JSON object, pointing at the position of syntax errors
+func checkConfigJSON(config []byte) error {
+	var cfg map[string]any
+	err := json.Unmarshal(config, &cfg)
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &syntaxErr):
+		line, column := 1, 1
+		for _, c := range config[:min(syntaxErr.Offset, int64(len(config)))] {
+			if c == '\n' {
+				line++
+				column = 1
+			} else {
+				column++
+			}
+		}
+		return fmt.Errorf("invalid JSON at byte offset %d (line %d, column %d): %v", syntaxErr.Offset, line, column, err)
+	case errors.As(err, &typeErr) && typeErr.Field == "":
+		return fmt.Errorf("invalid configuration: it must be a JSON object, not a JSON %s", typeErr.Value)
+	default:
+		return fmt.Errorf("invalid JSON: %v", err)
+	}
+}
+
+// Get the current Caddy JSON configuration
+func fetchConfig(ctx context.Context) ([]byte, error) {
+	status, header, body, err := adminExchange(ctx, http.MethodGet, "/config/", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("failed to get Caddy configuration: %d %s", status, http.StatusText(status))
+	}
+
+	if len(bytes.TrimSpace(body)) == 0 || string(bytes.TrimSpace(body)) == "null" {
+		return nil, errNoConfig
+	}
+
+	// Remember the version that was read so loading the modified configuration can be made conditional
+	if version, ok := ctx.Value(configVersionKey{}).(*configVersion); ok {
+		version.etag = header.Get("Etag")
+	}
+
+	return body, nil
+}
+
+// Get the current Caddy configuration decoded into a generic map
+func fetchConfigMap(ctx context.Context) (map[string]any, error) {
+	body, err := fetchConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg map[string]any
+	if err := json.Unmarshal(body, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse Caddy configuration: %v", err)
+	}
+
+	return cfg, nil
+}
+
+// Load a full JSON configuration into Caddy, or propose it when changes require confirmation
+func loadConfig(ctx context.Context, config []byte) ([]byte, error) {
+	if requireConfirmation {
+		return nil, proposeLoad(ctx, config)
+	}
+
+	return applyConfig(ctx, config)
+}
+
+type forceReloadKey struct{}
+
+// Make loading a configuration reload caddy even when it is unchanged, which caddy otherwise ignores
+func withForceReload(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forceReloadKey{}, true)
+}
+
+// Check whether loading a configuration must reload caddy even when it is unchanged
+func forcedReload(ctx context.Context) bool {
+	force, _ := ctx.Value(forceReloadKey{}).(bool)
+	return force
+}
+
+// Load a full JSON configuration into Caddy, returning a *caddyError if Caddy rejects it.
+// When the configuration was read earlier in the same tool call, it is loaded with the Etag of that read
+// in If-Match, so caddy refuses it if the configuration was changed in the meantime by anyone.
+func applyConfig(ctx context.Context, config []byte) ([]byte, error) {
+	// Caddy only checks If-Match on /config/ paths, and posting to /config/ replaces the whole configuration like /load
+	version, _ := ctx.Value(configVersionKey{}).(*configVersion)
+	path, header := "/load", http.Header{}
+	conditional := version != nil && version.etag != ""
+	if conditional {
+		path = "/config/"
+		header.Set("If-Match", version.etag)
+	}
+	if forcedReload(ctx) {
+		header.Set("Cache-Control", "must-revalidate")
+	}
+
+	status, body, err := adminRequestHeader(ctx, http.MethodPost, path, config, header)
+	if err != nil {
+		return nil, err
+	}
+
+	if status == http.StatusPreconditionFailed {
+		return nil, errConfigChanged
+	}
+
+	if status != http.StatusOK {
+		return nil, &caddyError{
+			StatusCode: status,
+			Message:    string(body),
+		}
+	}
+
+	// The Etag read before no longer matches the loaded configuration
+	if version != nil {
+		version.loaded = true
+		version.conditional = conditional
+		version.etag = ""
+	}
+
+	recordConfigWrite(ctx, config)
+
+	return body, nil
+}
+
+type loadResult struct {
+	Loaded   bool                  `json:"loaded"`
+	Warnings []caddyconfig.Warning `json:"warnings"`
+	Note     string                `json:"note,omitempty"`
+}
+
+// Parse the body of a successful /load response into its warnings
+func parseLoadResponse(body []byte) loadResult {
+	result := loadResult{
+		Loaded:   true,
+		Warnings: []caddyconfig.Warning{},
+	}
+
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		result.Note = "caddy did not return warnings for this load; warnings logged while provisioning modules only appear in caddy's logs"
+		return result
+	}
+
+	if err := json.Unmarshal(body, &result.Warnings); err != nil {
+		result.Warnings = []caddyconfig.Warning{}
+		result.Note = fmt.Sprintf("caddy returned an unrecognized response: %s", body)
+	}
+
+	return result
+}
+
+// Validate a full JSON configuration by provisioning it locally without running it
+func validateConfig(config []byte) error {
+	var cfg *caddy.Config
+	if err := caddy.StrictUnmarshalJSON(config, &cfg); err != nil {
+		return fmt.Errorf("invalid JSON configuration: %v", err)
+	}
+
+	if err := caddy.Validate(cfg); err != nil {
+		return fmt.Errorf("invalid configuration: %v", err)
+	}
+
+	return nil
+}
+
+// Turn a caddyError into a tool result so the model can see why Caddy rejected the change
+func caddyErrorResult(err error) (*mcp.CallToolResult, error) {
+	var proposal *confirmationRequired
+	if errors.As(err, &proposal) {
+		data, err := json.Marshal(proposal)
+		if err != nil {
+			return nil, err
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+
+	var caddyerr *caddyError
+	if !errors.As(err, &caddyerr) {
+		return nil, err
+	}
+
+	data, err := json.Marshal(caddyerr)
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
+}
@@ -0,0 +1,355 @@
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// An in-memory stand-in for the caddy admin API, serving and loading a single configuration
+type fakeCaddy struct {
+	mu      sync.Mutex
+	config  []byte
+	version int
+	loads   int
+
+	// The last load forced caddy to reload and was conditional on the Etag
+	forced      bool
+	conditional bool
+
+	// When set, loads are rejected with this status
+	rejectStatus int
+}
+
+// Start a fake caddy admin API with an initial configuration and point the admin helpers at it
+func newFakeCaddy(t *testing.T, config string) *fakeCaddy {
+	t.Helper()
+
+	fc := &fakeCaddy{}
+	if config != "" {
+		fc.config = []byte(config)
+	}
+
+	srv := httptest.NewServer(fc)
+	t.Cleanup(srv.Close)
+
+	oldURL := defaultURL
+	defaultURL = srv.URL
+	t.Cleanup(func() { defaultURL = oldURL })
+
+	return fc
+}
+
+func (fc *fakeCaddy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	etag := fmt.Sprintf(`"%d"`, fc.version)
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/config/":
+		w.Header().Set("Etag", etag)
+		if fc.config == nil {
+			fmt.Fprint(w, "null")
+			return
+		}
+		w.Write(fc.config)
+	case r.Method == http.MethodPost && (r.URL.Path == "/load" || r.URL.Path == "/config/"):
+		if match := r.Header.Get("If-Match"); match != "" && match != etag {
+			http.Error(w, "precondition failed", http.StatusPreconditionFailed)
+			return
+		}
+
+		if fc.rejectStatus != 0 {
+			http.Error(w, "rejected by the fake caddy", fc.rejectStatus)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		fc.config = body
+		fc.version++
+		fc.loads++
+		fc.forced = r.Header.Get("Cache-Control") == "must-revalidate"
+		fc.conditional = r.Header.Get("If-Match") != ""
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// Replace the configuration as if it was changed outside of this MCP server
+func (fc *fakeCaddy) set(config string) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	fc.config = []byte(config)
+	fc.version++
+}
+
+// Reject the following loads with a status, or accept them again with 0
+func (fc *fakeCaddy) reject(status int) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.rejectStatus = status
+}
+
+// Get the configuration currently loaded
+func (fc *fakeCaddy) current() string {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return string(fc.config)
+}
+
+// Count the configurations loaded through the admin API
+func (fc *fakeCaddy) loadCount() int {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return fc.loads
+}
+
+// Check whether the last load forced caddy to reload and was conditional on the Etag
+func (fc *fakeCaddy) lastLoad() (forced, conditional bool) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return fc.forced, fc.conditional
+}
+
+// Call a tool handler with the given arguments
+func callTool(handler func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error), name string, args map[string]any) (*mcp.CallToolResult, error) {
+	request := mcp.CallToolRequest{}
+	request.Params.Name = name
+	request.Params.Arguments = args
+	return handler(context.Background(), request)
+}
+
+// Get the text of the first content of a tool result
+func resultText(t *testing.T, result *mcp.CallToolResult) string {
+	t.Helper()
+
+	if result == nil || len(result.Content) == 0 {
+		t.Fatalf("tool returned no content")
+	}
+
+	text, ok := result.Content[0].(mcp.TextContent)
+	if !ok {
+		t.Fatalf("tool returned %T, want text", result.Content[0])
+	}
+	return text.Text
+}
+
+func TestLoadConfigRejected(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unknown module", http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	oldURL := defaultURL
+	defaultURL = srv.URL
+	defer func() { defaultURL = oldURL }()
+
+	_, err := loadConfig(context.Background(), []byte(`{}`))
+	var caddyerr *caddyError
+	if !errors.As(err, &caddyerr) {
+		t.Fatalf("loadConfig() error = %v, want a *caddyError", err)
+	}
+	if caddyerr.StatusCode != http.StatusBadRequest {
+		t.Errorf("loadConfig() status = %d, want %d", caddyerr.StatusCode, http.StatusBadRequest)
+	}
+}
+
+func TestFetchConfigNoConfig(t *testing.T) {
+	newFakeCaddy(t, "")
+
+	if _, err := fetchConfig(context.Background()); err == nil {
+		t.Errorf("fetchConfig() without a configuration returned no error")
+	}
+}
+
+func TestNormalizeAdminURL(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{raw: "http://127.0.0.1:2019", want: "http://127.0.0.1:2019"},
+		{raw: "http://127.0.0.1:2019/", want: "http://127.0.0.1:2019"},
+		{raw: "https://caddy.internal/admin//", want: "https://caddy.internal/admin"},
+		{raw: "127.0.0.1:2019", wantErr: true},
+		{raw: "localhost:2019", wantErr: true},
+		{raw: "unix:///run/caddy/admin.sock", wantErr: true},
+		{raw: "http://", wantErr: true},
+		{raw: "http://127.0.0.1:2019/?pretty", wantErr: true},
+		{raw: "http://127.0.0.1:2019/#config", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := normalizeAdminURL("url", tt.raw)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("normalizeAdminURL(%q) error = %v, want error %v", tt.raw, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("normalizeAdminURL(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestCheckConfigJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr string
+	}{
+		{name: "object", config: `{"apps":{}}`},
+		{name: "trailing comma", config: "{\n  \"apps\": {},\n}", wantErr: "invalid JSON at byte offset 17 (line 3, column 2)"},
+		{name: "truncated", config: `{"apps":`, wantErr: "invalid JSON"},
+		{name: "array", config: `[]`, wantErr: "it must be a JSON object, not a JSON array"},
+		{name: "string", config: `"apps"`, wantErr: "it must be a JSON object, not a JSON string"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkConfigJSON([]byte(tt.config))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("checkConfigJSON() error = %v", err)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("checkConfigJSON() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUpdateRejectsInvalidJSON(t *testing.T) {
+	fc := newFakeCaddy(t, `{}`)
+
+	if _, err := callTool(updateCaddyConfigHandler, "update_caddy_config", map[string]any{"json_config": `{"apps":}`}); err == nil {
+		t.Errorf("update_caddy_config with invalid JSON returned no error")
+	}
+	if fc.loadCount() != 0 {
+		t.Errorf("update_caddy_config sent invalid JSON to caddy")
+	}
+}
+
+// Wrap an error like http.Client.Do does for a failed request
+func requestError(method string, err error) error {
+	return &url.Error{Op: method, URL: "http://localhost:2019/config/", Err: err}
+}
+
+// Wrap a syscall error like a failed dial or read does
+func connError(op string, errno syscall.Errno) error {
+	return &net.OpError{Op: op, Net: "tcp", Err: os.NewSyscallError(op, errno)}
+}
+
+func TestRetryable(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		method string
+		status int
+		err    error
+		want   bool
+	}{
+		{name: "ok", method: http.MethodGet, status: http.StatusOK, want: false},
+		{name: "bad request", method: http.MethodPost, status: http.StatusBadRequest, want: false},
+		{name: "precondition failed", method: http.MethodPost, status: http.StatusPreconditionFailed, want: false},
+		{name: "bad gateway", method: http.MethodGet, status: http.StatusBadGateway, want: true},
+		{name: "service unavailable on DELETE", method: http.MethodDelete, status: http.StatusServiceUnavailable, want: true},
+		{name: "internal error on POST", method: http.MethodPost, status: http.StatusInternalServerError, want: false},
+		{name: "bad gateway on PATCH", method: http.MethodPatch, status: http.StatusBadGateway, want: false},
+		{name: "connection refused", method: http.MethodPost, err: requestError("Post", connError("connect", syscall.ECONNREFUSED)), want: true},
+		{name: "connection reset", method: http.MethodPost, err: requestError("Post", connError("read", syscall.ECONNRESET)), want: true},
+		{name: "EOF on GET", method: http.MethodGet, err: requestError("Get", io.EOF), want: true},
+		{name: "EOF on DELETE", method: http.MethodDelete, err: requestError("Delete", io.EOF), want: true},
+		{name: "EOF on POST", method: http.MethodPost, err: requestError("Post", io.EOF), want: false},
+		{name: "unexpected EOF on PATCH", method: http.MethodPatch, err: requestError("Patch", io.ErrUnexpectedEOF), want: false},
+		{name: "unknown certificate authority", method: http.MethodGet, err: requestError("Get", x509.UnknownAuthorityError{}), want: false},
+		{name: "other error", method: http.MethodGet, err: requestError("Get", errors.New("no such host")), want: false},
+		{name: "canceled", ctx: canceled, method: http.MethodGet, err: requestError("Get", context.Canceled), want: false},
+		{name: "canceled connection refused", ctx: canceled, method: http.MethodGet, err: requestError("Get", connError("connect", syscall.ECONNREFUSED)), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := tt.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+
+			if got := retryable(ctx, tt.method, tt.status, tt.err); got != tt.want {
+				t.Errorf("retryable(%s, %d, %v) = %v, want %v", tt.method, tt.status, tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdminRequestRetries(t *testing.T) {
+	var attempts int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			http.Error(w, "reloading", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `{"apps":{}}`)
+	}))
+	defer srv.Close()
+
+	oldURL := defaultURL
+	defaultURL = srv.URL
+	defer func() { defaultURL = oldURL }()
+
+	config, err := fetchConfig(context.Background())
+	if err != nil {
+		t.Fatalf("fetchConfig() error = %v", err)
+	}
+	if string(config) != `{"apps":{}}` || attempts != 2 {
+		t.Errorf("fetchConfig() = %s after %d attempts, want the configuration after 2 attempts", config, attempts)
+	}
+}
+
+func TestAdminRequestDoesNotRetryFailedLoad(t *testing.T) {
+	var attempts int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		http.Error(w, "loading new config: http app module: start: listening on :443: address already in use", http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	oldURL := defaultURL
+	defaultURL = srv.URL
+	defer func() { defaultURL = oldURL }()
+
+	_, err := loadConfig(context.Background(), []byte(`{"apps":{}}`))
+	var caddyerr *caddyError
+	if !errors.As(err, &caddyerr) || caddyerr.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("loadConfig() error = %v, want the caddy error", err)
+	}
+	if attempts != 1 {
+		t.Errorf("a POST failing with a 500 was sent %d times, want 1", attempts)
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+type configTrial struct {
+	State     string    `json:"state"`
+	AppliedAt time.Time `json:"applied_at"`
+	Deadline  time.Time `json:"deadline"`
+	Error     string    `json:"error,omitempty"`
+
+	previous []byte
+	instance string
+	timer    *time.Timer
+}
+
+var (
+	trialMu sync.Mutex
+	trial   *configTrial
+)
+
+func registerAutoRevertTools(s *server.MCPServer) {
+	tryConfigWithAutoRevert := mcp.NewTool("try_config_with_autorevert",
+		mcp.WithDescription(`
+		Use the try_config_with_autorevert tool to apply a risky configuration change that is automatically reverted unless it is confirmed in time.
+
+		The current configuration is saved, the new configuration is applied, and the saved configuration is loaded again when the timeout expires unless the confirm_keep tool is called first.
+
+		Notes:
+			You must provide the full JSON configuration, like for the update_caddy_config tool.
+			Only one trial can be pending at a time.
+			Use this for changes that could lock you out of the caddy server, then verify access before calling confirm_keep.
+			This tool is not available when the MCP server runs with -require-confirmation.
+		`),
+		mcp.WithString("json_config",
+			mcp.Required(),
+			mcp.Description("The caddy server JSON configuration to try"),
+		),
+		mcp.WithNumber("timeout_seconds",
+			mcp.Description("Seconds before the change is reverted unless confirmed (default 60, max 3600)"),
+			mcp.DefaultNumber(60),
+		),
+	)
+
+	// Add try config with autorevert tool handler
+	s.AddTool(tryConfigWithAutoRevert, tryConfigWithAutoRevertHandler)
+
+	confirmKeep := mcp.NewTool("confirm_keep",
+		mcp.WithDescription(`
+		Use the confirm_keep tool to keep the configuration applied by the try_config_with_autorevert tool and cancel its automatic revert.
+
+		The result is a JSON document with the state of the trial: "kept" when it was confirmed in time, or "reverted" or "revert_failed" when the timeout already expired.
+		`),
+	)
+
+	// Add confirm keep tool handler
+	s.AddTool(confirmKeep, confirmKeepHandler)
+}
+
+// Load the previous configuration when a trial times out
+func revertTrial(t *configTrial) {
+	configMu.Lock()
+	defer configMu.Unlock()
+
+	trialMu.Lock()
+	defer trialMu.Unlock()
+
+	if t.State != "pending" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(withInstance(context.Background(), t.instance), client.Timeout)
+	defer cancel()
+
+	if _, err := applyConfig(ctx, t.previous); err != nil {
+		t.State = "revert_failed"
+		t.Error = err.Error()
+		return
+	}
+
+	t.State = "reverted"
+}
+
+// Apply a configuration that is reverted unless confirmed
+func tryConfigWithAutoRevertHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	config, err := request.RequireString("json_config")
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := request.GetInt("timeout_seconds", 60)
+	if timeout < 1 || timeout > 3600 {
+		return nil, fmt.Errorf("timeout_seconds must be between 1 and 3600")
+	}
+
+	// A proposed change would start its trial only once confirmed, possibly long after the timeout was chosen
+	if requireConfirmation {
+		return nil, fmt.Errorf("try_config_with_autorevert cannot be used with -require-confirmation; use update_caddy_config and confirm_change instead")
+	}
+
+	trialMu.Lock()
+	defer trialMu.Unlock()
+
+	if trial != nil && trial.State == "pending" {
+		return nil, fmt.Errorf("another trial is pending until %s; call confirm_keep or wait for it to revert", trial.Deadline.Format(time.RFC3339))
+	}
+
+	previous, err := fetchConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := loadConfig(ctx, []byte(config)); err != nil {
+		return caddyErrorResult(err)
+	}
+
+	now := time.Now()
+	t := &configTrial{
+		State:     "pending",
+		AppliedAt: now,
+		Deadline:  now.Add(time.Duration(timeout) * time.Second),
+		previous:  previous,
+		instance:  instanceName(ctx),
+	}
+	t.timer = time.AfterFunc(time.Duration(timeout)*time.Second, func() {
+		revertTrial(t)
+	})
+	trial = t
+
+	data, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+// Keep the configuration of a pending trial
+func confirmKeepHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	trialMu.Lock()
+	defer trialMu.Unlock()
+
+	if trial == nil {
+		return nil, fmt.Errorf("there is no trial to confirm; use try_config_with_autorevert first")
+	}
+
+	if trial.State == "pending" {
+		trial.timer.Stop()
+		trial.State = "kept"
+	}
+
+	data, err := json.Marshal(trial)
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// Start a test without a trial
+func resetTrial(t *testing.T) {
+	t.Helper()
+
+	t.Cleanup(func() {
+		trialMu.Lock()
+		defer trialMu.Unlock()
+
+		if trial != nil && trial.timer != nil {
+			trial.timer.Stop()
+		}
+		trial = nil
+	})
+}
+
+// Get the state of the current trial
+func trialState() string {
+	trialMu.Lock()
+	defer trialMu.Unlock()
+	return trial.State
+}
+
+func TestAutoRevert(t *testing.T) {
+	resetTrial(t)
+	fc := newFakeCaddy(t, portConfig(80))
+
+	// The revert uses the -timeout of admin requests, which is only set by main
+	oldTimeout := client.Timeout
+	client.Timeout = 5 * time.Second
+	defer func() { client.Timeout = oldTimeout }()
+
+	result, err := callTool(tryConfigWithAutoRevertHandler, "try_config_with_autorevert", map[string]any{"json_config": portConfig(81), "timeout_seconds": 1})
+	if err != nil {
+		t.Fatalf("try_config_with_autorevert: %v", err)
+	}
+
+	var got configTrial
+	if err := json.Unmarshal([]byte(resultText(t, result)), &got); err != nil || got.State != "pending" {
+		t.Fatalf("try_config_with_autorevert = %s, want a pending trial", resultText(t, result))
+	}
+	if got := fc.current(); got != portConfig(81) {
+		t.Errorf("configuration during the trial = %s, want %s", got, portConfig(81))
+	}
+
+	if _, err := callTool(tryConfigWithAutoRevertHandler, "try_config_with_autorevert", map[string]any{"json_config": portConfig(82)}); err == nil {
+		t.Errorf("try_config_with_autorevert with a pending trial returned no error")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for trialState() == "pending" && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	if state := trialState(); state != "reverted" {
+		t.Fatalf("trial state after its timeout = %s, want reverted", state)
+	}
+	if got := fc.current(); got != portConfig(80) {
+		t.Errorf("configuration after the revert = %s, want %s", got, portConfig(80))
+	}
+}
+
+func TestAutoRevertConfirmKeep(t *testing.T) {
+	resetTrial(t)
+	fc := newFakeCaddy(t, portConfig(80))
+
+	if _, err := callTool(tryConfigWithAutoRevertHandler, "try_config_with_autorevert", map[string]any{"json_config": portConfig(81)}); err != nil {
+		t.Fatalf("try_config_with_autorevert: %v", err)
+	}
+	if _, err := callTool(confirmKeepHandler, "confirm_keep", nil); err != nil {
+		t.Fatalf("confirm_keep: %v", err)
+	}
+
+	if state := trialState(); state != "kept" {
+		t.Errorf("trial state after confirm_keep = %s, want kept", state)
+	}
+	if got := fc.current(); got != portConfig(81) {
+		t.Errorf("configuration after confirm_keep = %s, want %s", got, portConfig(81))
+	}
+}
+
+func TestAutoRevertRefusedWithConfirmation(t *testing.T) {
+	resetTrial(t)
+	useConfirmation(t)
+	fc := newFakeCaddy(t, portConfig(80))
+
+	if _, err := callTool(tryConfigWithAutoRevertHandler, "try_config_with_autorevert", map[string]any{"json_config": portConfig(81)}); err == nil {
+		t.Errorf("try_config_with_autorevert with -require-confirmation returned no error")
+	}
+	if fc.loadCount() != 0 {
+		t.Errorf("try_config_with_autorevert loaded a configuration with -require-confirmation")
+	}
+
+	trialMu.Lock()
+	defer trialMu.Unlock()
+	if trial != nil {
+		t.Errorf("try_config_with_autorevert started a trial with -require-confirmation")
+	}
+}
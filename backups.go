@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Backups are named caddy-config-<timestamp>.json
+const (
+	backupFilePrefix = "caddy-config-"
+	backupFileSuffix = ".json"
+	backupTimeFormat = "20060102-150405"
+)
+
+type backupInfo struct {
+	Filename  string    `json:"filename"`
+	Timestamp time.Time `json:"timestamp"`
+	Size      int64     `json:"size"`
+}
+
+type backupResult struct {
+	Filename string `json:"filename"`
+	Path     string `json:"path"`
+}
+
+func registerBackupTools(s *server.MCPServer) {
+	backupCaddyConfig := mcp.NewTool("backup_caddy_config",
+		mcp.WithDescription(`
+		Use the backup_caddy_config tool to save the current caddy server configuration to a timestamped file in the backup directory, for example before applying changes.
+
+		The result is a JSON document with the filename and full path of the backup.
+
+		Notes:
+			Backups are only available when the MCP server runs with -backup-dir; the directory is created if it does not exist.
+			Use the list_config_backups tool to see the existing backups.
+		`),
+	)
+
+	// Add backup Caddy config tool handler
+	s.AddTool(backupCaddyConfig, backupCaddyConfigHandler)
+
+	listConfigBackups := mcp.NewTool("list_config_backups",
+		mcp.WithDescription(`
+		Use the list_config_backups tool to list the configuration backups stored in the backup directory.
+
+		The result is a JSON document with the filename, timestamp and size of each backup, newest first.
+
+		Notes:
+			The timestamp is the time the backup was taken, read from the backup filename.
+			Use the restore_caddy_config tool with a filename to load a backup.
+		`),
+	)
+
+	// Add list config backups tool handler
+	s.AddTool(listConfigBackups, listConfigBackupsHandler)
+
+	restoreCaddyConfig := mcp.NewTool("restore_caddy_config",
+		mcp.WithDescription(`
+		Use the restore_caddy_config tool to load a configuration backup from the backup directory into the caddy server, for example to roll back a bad update.
+
+		The backup replaces the whole configuration, like the update_caddy_config tool, and the result is the same JSON document with the warnings of the load.
+
+		Notes:
+			The filename must be one returned by the list_config_backups or backup_caddy_config tool; paths are rejected.
+			Take a backup of the current configuration first with the backup_caddy_config tool if it may be needed again.
+		`),
+		mcp.WithString("filename",
+			mcp.Required(),
+			mcp.Description("The filename of the backup to restore, like caddy-config-20250101-120000.json"),
+		),
+	)
+
+	// Add restore Caddy config tool handler
+	s.AddTool(restoreCaddyConfig, restoreCaddyConfigHandler)
+}
+
+// Read the timestamp embedded in a backup filename
+func parseBackupFilename(name string) (time.Time, bool) {
+	if !strings.HasPrefix(name, backupFilePrefix) || !strings.HasSuffix(name, backupFileSuffix) {
+		return time.Time{}, false
+	}
+
+	stamp := strings.TrimSuffix(strings.TrimPrefix(name, backupFilePrefix), backupFileSuffix)
+	t, err := time.ParseInLocation(backupTimeFormat, stamp, time.UTC)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return t, true
+}
+
+// List the backups in the backup directory, newest first
+func listConfigBackups() ([]backupInfo, error) {
+	if backupDir == "" {
+		return nil, fmt.Errorf("backups are disabled; start the MCP server with -backup-dir")
+	}
+
+	entries, err := os.ReadDir(backupDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	backups := []backupInfo{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		timestamp, ok := parseBackupFilename(entry.Name())
+		if !ok {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+
+		backups = append(backups, backupInfo{
+			Filename:  entry.Name(),
+			Timestamp: timestamp,
+			Size:      info.Size(),
+		})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Timestamp.After(backups[j].Timestamp)
+	})
+
+	return backups, nil
+}
+
+// Write a configuration to a new backup file and return its filename
+func writeConfigBackup(config []byte) (string, error) {
+	if backupDir == "" {
+		return "", fmt.Errorf("backups are disabled; start the MCP server with -backup-dir")
+	}
+
+	if err := os.MkdirAll(backupDir, 0o750); err != nil {
+		return "", err
+	}
+
+	// Backups taken within the same second get the next free timestamp
+	stamp := time.Now().UTC()
+	for {
+		name := backupFilePrefix + stamp.Format(backupTimeFormat) + backupFileSuffix
+
+		f, err := os.OpenFile(filepath.Join(backupDir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if os.IsExist(err) {
+			stamp = stamp.Add(time.Second)
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+
+		if _, err := f.Write(config); err != nil {
+			f.Close()
+			return "", err
+		}
+
+		return name, f.Close()
+	}
+}
+
+// List the available configuration backups
+func listConfigBackupsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	backups, err := listConfigBackups()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(backups)
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+// Save the current configuration to a new backup file
+func backupCaddyConfigHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if backupDir == "" {
+		return nil, fmt.Errorf("backups are disabled; start the MCP server with -backup-dir")
+	}
+
+	config, err := fetchConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := writeConfigBackup(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write backup: %v", err)
+	}
+
+	data, err := json.Marshal(backupResult{
+		Filename: name,
+		Path:     filepath.Join(backupDir, name),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+// Load a backup from the backup directory into caddy
+func restoreCaddyConfigHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if backupDir == "" {
+		return nil, fmt.Errorf("backups are disabled; start the MCP server with -backup-dir")
+	}
+
+	filename, err := request.RequireString("filename")
+	if err != nil {
+		return nil, err
+	}
+
+	// Only files directly inside the backup directory can be restored
+	if filename == "" || strings.ContainsAny(filename, `/\`) || strings.Contains(filename, "..") {
+		return nil, fmt.Errorf("invalid backup filename: %q", filename)
+	}
+
+	config, err := os.ReadFile(filepath.Join(backupDir, filename))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup: %v", err)
+	}
+
+	body, err := loadConfig(ctx, config)
+	if err != nil {
+		return caddyErrorResult(err)
+	}
+
+	data, err := json.Marshal(parseLoadResponse(body))
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
+}
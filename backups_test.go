@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseBackupFilename(t *testing.T) {
+	tests := []struct {
+		name   string
+		want   time.Time
+		wantOK bool
+	}{
+		{name: "caddy-config-20250102-030405.json", want: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC), wantOK: true},
+		{name: "caddy-config-20250102.json"},
+		{name: "caddy-config-20250102-030405.json.tmp"},
+		{name: "other-20250102-030405.json"},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseBackupFilename(tt.name)
+		if ok != tt.wantOK || !got.Equal(tt.want) {
+			t.Errorf("parseBackupFilename(%q) = %v, %v, want %v, %v", tt.name, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestListConfigBackups(t *testing.T) {
+	oldDir := backupDir
+	backupDir = t.TempDir()
+	defer func() { backupDir = oldDir }()
+
+	files := map[string]string{
+		"caddy-config-20250101-000000.json": `{}`,
+		"caddy-config-20250301-120000.json": `{"apps":{}}`,
+		"caddy-config-20250201-000000.json": `{}`,
+		"notes.txt":                         "not a backup",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(backupDir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(backupDir, "caddy-config-20250401-000000.json"), 0o750); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := callTool(listConfigBackupsHandler, "list_config_backups", nil)
+	if err != nil {
+		t.Fatalf("list_config_backups: %v", err)
+	}
+
+	var backups []backupInfo
+	if err := json.Unmarshal([]byte(resultText(t, result)), &backups); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"caddy-config-20250301-120000.json",
+		"caddy-config-20250201-000000.json",
+		"caddy-config-20250101-000000.json",
+	}
+	if len(backups) != len(want) {
+		t.Fatalf("list_config_backups = %+v, want %v", backups, want)
+	}
+	for i, backup := range backups {
+		if backup.Filename != want[i] {
+			t.Errorf("backup %d = %s, want %s", i, backup.Filename, want[i])
+		}
+	}
+	if backups[0].Size != int64(len(`{"apps":{}}`)) {
+		t.Errorf("backup size = %d, want %d", backups[0].Size, len(`{"apps":{}}`))
+	}
+}
+
+func TestListConfigBackupsDisabled(t *testing.T) {
+	oldDir := backupDir
+	backupDir = ""
+	defer func() { backupDir = oldDir }()
+
+	if _, err := callTool(listConfigBackupsHandler, "list_config_backups", nil); err == nil {
+		t.Errorf("list_config_backups without -backup-dir returned no error")
+	}
+}
@@ -0,0 +1,411 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+type roundtripResult struct {
+	Matches     bool           `json:"matches"`
+	Reason      string         `json:"reason,omitempty"`
+	Differences []configChange `json:"differences"`
+	Caddyfile   string         `json:"caddyfile,omitempty"`
+}
+
+type routeFragment struct {
+	Routes  []any    `json:"routes"`
+	Dropped []string `json:"dropped,omitempty"`
+}
+
+func registerCaddyfileTools(s *server.MCPServer) {
+	caddyfileBlockToRoute := mcp.NewTool("caddyfile_block_to_route",
+		mcp.WithDescription(`
+		Use the caddyfile_block_to_route tool to convert a single Caddyfile site block into the JSON routes it produces, without the rest of a full configuration.
+
+		The result is a JSON document with the routes, ready to be added to the routes of an existing server, and the other configuration sections the Caddyfile adapter generated that are not included.
+
+		Notes:
+			The site address becomes a host matcher on the route. Routes of a block with several addresses or of several blocks are all returned.
+			Settings that live outside the routes, like TLS automation policies or the listen address, are listed in dropped and must be configured separately.
+			Global options and snippets can be included before the site block when it needs them.
+		`),
+		mcp.WithString("caddyfile_block",
+			mcp.Required(),
+			mcp.Description("The Caddyfile site block to convert, for example \"example.com {\\n\\treverse_proxy localhost:8080\\n}\""),
+		),
+	)
+
+	// Add Caddyfile block to route tool handler
+	s.AddTool(caddyfileBlockToRoute, caddyfileBlockToRouteHandler)
+
+	convertCaddyfileFileToJSON := mcp.NewTool("convert_caddyfile_file_to_json",
+		mcp.WithDescription(`
+		Use the convert_caddyfile_file_to_json tool to convert a Caddyfile stored on the host of this MCP server to JSON configuration, instead of passing its content to convert_caddyfile_to_json.
+
+		The result is the JSON configuration, followed by the adapter's warnings in a Warnings section when there are any.
+
+		Notes:
+			Only files inside the directory set with -caddyfile-dir can be read; the tool is disabled without it.
+			The path is relative to that directory, or an absolute path inside it.
+		`),
+		mcp.WithString("path",
+			mcp.Required(),
+			mcp.Description("The path of the Caddyfile, for example Caddyfile or sites/example.com.caddyfile"),
+		),
+	)
+
+	// Add convert Caddyfile file to JSON tool handler
+	s.AddTool(convertCaddyfileFileToJSON, convertCaddyfileFileToJSONHandler)
+
+	configToCaddyfileTool := mcp.NewTool("config_to_caddyfile",
+		mcp.WithDescription(`
+		Use the config_to_caddyfile tool to get an approximate Caddyfile for a caddy JSON configuration, for users who think in Caddyfile terms.
+
+		Caddy cannot convert JSON back to a Caddyfile, so this is a best-effort reconstruction of the common subset: http servers with a single listen address whose top-level routes match hosts, using reverse_proxy, file_server, static_response (respond and redir), encode, rewrite, response headers and subroutes with path matchers. The result is Caddyfile text formatted like caddy fmt, starting with a comment saying it is a reconstruction.
+
+		Notes:
+			Configurations using anything outside of that subset are rejected with an error naming the config path that cannot be represented, rather than silently dropping it.
+			Use the check_caddyfile_roundtrip tool on the result to confirm it adapts back to the same JSON before relying on it.
+			If json_config is not provided the current caddy server configuration is converted.
+		`),
+		mcp.WithString("json_config",
+			mcp.Description("The caddy JSON configuration to convert instead of the current configuration"),
+		),
+	)
+
+	// Add config to Caddyfile tool handler
+	s.AddTool(configToCaddyfileTool, configToCaddyfileHandler)
+
+	scaffoldCaddyfile := mcp.NewTool("scaffold_caddyfile",
+		mcp.WithDescription(`
+		Use the scaffold_caddyfile tool to generate an idiomatic Caddyfile for a site from a few high-level settings.
+
+		The result is Caddyfile text formatted like caddy fmt. It is checked with the Caddyfile adapter but not applied; convert it with the convert_caddyfile_to_json tool and apply it with the update_caddy_config tool.
+
+		Notes:
+			All domains share one site block. Provide either upstreams to reverse proxy to or a root directory to serve files from.
+			Caddy obtains certificates for the domains automatically; email is the ACME account email used for expiry notices.
+		`),
+		mcp.WithArray("domains",
+			mcp.Required(),
+			mcp.Description("The domains of the site, for example example.com and www.example.com"),
+			mcp.Items(map[string]any{"type": "string"}),
+		),
+		mcp.WithArray("upstreams",
+			mcp.Description("The upstreams to reverse proxy to, for example localhost:8080"),
+			mcp.Items(map[string]any{"type": "string"}),
+		),
+		mcp.WithString("root",
+			mcp.Description("The absolute path of a directory to serve static files from"),
+		),
+		mcp.WithString("email",
+			mcp.Description("The ACME account email set in the global options"),
+		),
+		mcp.WithBoolean("compress",
+			mcp.Description("Whether to compress responses with the encode directive"),
+			mcp.DefaultBool(true),
+		),
+	)
+
+	// Add scaffold Caddyfile tool handler
+	s.AddTool(scaffoldCaddyfile, scaffoldCaddyfileHandler)
+
+	checkCaddyfileRoundtrip := mcp.NewTool("check_caddyfile_roundtrip",
+		mcp.WithDescription(`
+		Use the check_caddyfile_roundtrip tool to check whether a Caddyfile survives a round trip through JSON: adapted to JSON, converted back to a Caddyfile and adapted again.
+
+		The result is a JSON document telling whether both JSON configurations match, the paths that differ, and the regenerated Caddyfile.
+
+		Notes:
+			Differences, or a reason when the JSON cannot be converted back, point to constructs that are not round-trippable. Keep the JSON configuration as the source of truth for those sites instead of editing a Caddyfile.
+			The conversion back to a Caddyfile is best effort and covers common sites: reverse proxies, file servers, redirects, static responses, headers, compression and path matchers.
+		`),
+		mcp.WithString("caddyfile_config",
+			mcp.Required(),
+			mcp.Description("The Caddyfile configuration to check"),
+		),
+	)
+
+	// Add check Caddyfile roundtrip tool handler
+	s.AddTool(checkCaddyfileRoundtrip, checkCaddyfileRoundtripHandler)
+}
+
+// Resolve a path inside -caddyfile-dir, following symlinks so they cannot lead outside of it
+func caddyfilePath(path string) (string, error) {
+	if caddyfileDir == "" {
+		return "", fmt.Errorf("reading Caddyfiles is disabled; start the MCP server with -caddyfile-dir")
+	}
+
+	dir, err := filepath.EvalSymlinks(caddyfileDir)
+	if err != nil {
+		return "", err
+	}
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", err
+	}
+
+	rel, err := filepath.Rel(dir, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside of the Caddyfile directory %s", path, caddyfileDir)
+	}
+
+	return resolved, nil
+}
+
+// Convert a Caddyfile read from disk to JSON configuration
+func convertCaddyfileFileToJSONHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	path, err := request.RequireString("path")
+	if err != nil {
+		return nil, err
+	}
+
+	path, err = caddyfilePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	data, warnings, err := adaptToJSON("caddyfile", config)
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(fmt.Sprintf("%s%s", data, formatWarnings(warnings))), nil
+}
+
+// Reconstruct an approximate Caddyfile from a JSON configuration
+func configToCaddyfileHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	value, err := configArgument(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("the configuration must be a JSON object")
+	}
+
+	text, err := configToCaddyfile(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("the configuration cannot be represented as a Caddyfile: %v", err)
+	}
+
+	return mcp.NewToolResultText("# Best-effort Caddyfile reconstructed from the JSON configuration by caddy-mcp; review it before use\n" + text), nil
+}
+
+// Convert a Caddyfile site block to the routes it produces
+func caddyfileBlockToRouteHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	block, err := request.RequireString("caddyfile_block")
+	if err != nil {
+		return nil, err
+	}
+
+	data, _, err := adaptToJSON("caddyfile", []byte(block))
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg map[string]any
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+
+	fragment := routeFragment{Routes: []any{}}
+	dropped := map[string]bool{}
+
+	for _, name := range httpServerNames(cfg) {
+		srv, err := httpServer(cfg, name)
+		if err != nil {
+			continue
+		}
+
+		fragment.Routes = append(fragment.Routes, serverRoutes(srv)...)
+		for key := range srv {
+			if key != "routes" {
+				dropped[joinConfigPath("apps/http/servers/"+name, key)] = true
+			}
+		}
+	}
+
+	for key := range cfg {
+		if key != "apps" {
+			dropped[key] = true
+		}
+	}
+	apps, _ := cfg["apps"].(map[string]any)
+	for name := range apps {
+		if name != "http" {
+			dropped[joinConfigPath("apps", name)] = true
+		}
+	}
+	httpApp, _ := apps["http"].(map[string]any)
+	for key := range httpApp {
+		if key != "servers" {
+			dropped[joinConfigPath("apps/http", key)] = true
+		}
+	}
+
+	if len(fragment.Routes) == 0 {
+		return nil, fmt.Errorf("the Caddyfile did not produce any routes; provide a site block with at least one directive")
+	}
+
+	for path := range dropped {
+		fragment.Dropped = append(fragment.Dropped, path)
+	}
+	sort.Strings(fragment.Dropped)
+
+	result, err := json.Marshal(fragment)
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+// Check that a Caddyfile argument is a single token
+func caddyfileToken(kind, value string) error {
+	if value == "" || strings.ContainsAny(value, " \t\r\n{}\"#") {
+		return fmt.Errorf("invalid %s: %q", kind, value)
+	}
+
+	return nil
+}
+
+// Generate a Caddyfile for a site from high-level settings
+func scaffoldCaddyfileHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	domains, err := request.RequireStringSlice("domains")
+	if err != nil {
+		return nil, err
+	}
+	if len(domains) == 0 {
+		return nil, fmt.Errorf("at least one domain is required")
+	}
+
+	upstreams := request.GetStringSlice("upstreams", nil)
+	root := request.GetString("root", "")
+	email := request.GetString("email", "")
+
+	if len(upstreams) == 0 && root == "" {
+		return nil, fmt.Errorf("either upstreams or root is required")
+	}
+	if len(upstreams) > 0 && root != "" {
+		return nil, fmt.Errorf("upstreams and root cannot be used together")
+	}
+	if root != "" && !filepath.IsAbs(root) {
+		return nil, fmt.Errorf("root must be an absolute path: %s", root)
+	}
+
+	for _, domain := range domains {
+		if err := caddyfileToken("domain", domain); err != nil {
+			return nil, err
+		}
+	}
+	for _, upstream := range upstreams {
+		if err := caddyfileToken("upstream", upstream); err != nil {
+			return nil, err
+		}
+	}
+	for kind, value := range map[string]string{"root": root, "email": email} {
+		if value != "" {
+			if err := caddyfileToken(kind, value); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var b strings.Builder
+	if email != "" {
+		fmt.Fprintf(&b, "{\nemail %s\n}\n\n", email)
+	}
+
+	fmt.Fprintf(&b, "%s {\n", strings.Join(domains, ", "))
+	if request.GetBool("compress", true) {
+		b.WriteString("encode\n")
+	}
+	if root != "" {
+		fmt.Fprintf(&b, "root * %s\nfile_server\n", root)
+	} else {
+		fmt.Fprintf(&b, "reverse_proxy %s\n", strings.Join(upstreams, " "))
+	}
+	b.WriteString("}\n")
+
+	formatted := caddyfile.Format([]byte(b.String()))
+
+	if _, _, err := adaptToJSON("caddyfile", formatted); err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(formatted)), nil
+}
+
+// Adapt a Caddyfile, convert it back and compare both JSON configurations
+func checkCaddyfileRoundtripHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	config, err := request.RequireString("caddyfile_config")
+	if err != nil {
+		return nil, err
+	}
+
+	first, _, err := adaptToJSON("caddyfile", []byte(config))
+	if err != nil {
+		return nil, err
+	}
+
+	var firstConfig map[string]any
+	if err := json.Unmarshal(first, &firstConfig); err != nil {
+		return nil, err
+	}
+
+	result := roundtripResult{
+		Differences: []configChange{},
+	}
+
+	regenerated, err := configToCaddyfile(firstConfig)
+	if err != nil {
+		result.Reason = fmt.Sprintf("the adapted JSON cannot be converted back to a Caddyfile: %v", err)
+	} else {
+		result.Caddyfile = regenerated
+
+		second, _, err := adaptToJSON("caddyfile", []byte(regenerated))
+		if err != nil {
+			result.Reason = fmt.Sprintf("the regenerated Caddyfile does not adapt: %v", err)
+		} else {
+			var secondConfig map[string]any
+			if err := json.Unmarshal(second, &secondConfig); err != nil {
+				return nil, err
+			}
+
+			result.Differences = diffJSON("", firstConfig, secondConfig)
+			result.Matches = len(result.Differences) == 0
+		}
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
+}
@@ -0,0 +1,536 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+)
+
+// Builds an approximate Caddyfile for the common subset of JSON configurations
+type caddyfileWriter struct {
+	global []string
+	sites  []string
+	// Counter for the names of generated named matchers within a site
+	matchers int
+}
+
+// Quote a Caddyfile token when it contains spaces, quotes or braces
+func caddyfileQuote(token string) string {
+	if token != "" && !strings.ContainsAny(token, " \t\r\n\"`") {
+		return token
+	}
+
+	return `"` + strings.ReplaceAll(token, `"`, `\"`) + `"`
+}
+
+// Convert a JSON configuration to an approximate Caddyfile, failing on anything it cannot represent
+func configToCaddyfile(cfg map[string]any) (string, error) {
+	w := &caddyfileWriter{}
+
+	for key, value := range cfg {
+		switch key {
+		case "apps":
+		case "admin":
+			if err := w.writeAdmin(value); err != nil {
+				return "", err
+			}
+		default:
+			return "", fmt.Errorf("%s: top-level section is not supported", key)
+		}
+	}
+
+	apps, _ := cfg["apps"].(map[string]any)
+	for name, app := range apps {
+		switch name {
+		case "http":
+			if err := w.writeHTTPApp(app); err != nil {
+				return "", err
+			}
+		case "tls":
+			if err := w.writeTLSApp(app); err != nil {
+				return "", err
+			}
+		default:
+			return "", fmt.Errorf("apps/%s: the %s app is not supported", name, name)
+		}
+	}
+
+	if len(w.sites) == 0 {
+		return "", fmt.Errorf("the configuration has no sites to convert")
+	}
+
+	var b strings.Builder
+	if len(w.global) > 0 {
+		sort.Strings(w.global)
+		b.WriteString("{\n")
+		for _, line := range w.global {
+			b.WriteString(line + "\n")
+		}
+		b.WriteString("}\n\n")
+	}
+	b.WriteString(strings.Join(w.sites, "\n"))
+
+	return string(caddyfile.Format([]byte(b.String()))), nil
+}
+
+// Convert the admin section to the admin global option
+func (w *caddyfileWriter) writeAdmin(value any) error {
+	admin, _ := value.(map[string]any)
+	for key, v := range admin {
+		switch key {
+		case "disabled":
+			if v == true {
+				w.global = append(w.global, "admin off")
+			}
+		case "listen":
+			w.global = append(w.global, "admin "+caddyfileQuote(fmt.Sprint(v)))
+		default:
+			return fmt.Errorf("admin/%s: not supported", key)
+		}
+	}
+
+	return nil
+}
+
+// Convert the tls app, which can only hold the ACME email
+func (w *caddyfileWriter) writeTLSApp(value any) error {
+	app, _ := value.(map[string]any)
+	automation, _ := app["automation"].(map[string]any)
+	if len(app) != 1 || len(automation) != 1 {
+		return fmt.Errorf("apps/tls: only the ACME account email can be converted")
+	}
+
+	policies, _ := automation["policies"].([]any)
+	email := ""
+	for _, p := range policies {
+		policy, _ := p.(map[string]any)
+		issuers, _ := policy["issuers"].([]any)
+		if len(policy) != 1 || len(issuers) == 0 {
+			return fmt.Errorf("apps/tls/automation/policies: only the ACME account email can be converted")
+		}
+
+		for _, i := range issuers {
+			issuer, _ := i.(map[string]any)
+			e, _ := issuer["email"].(string)
+			if issuer["module"] != "acme" || e == "" || (email != "" && e != email) {
+				return fmt.Errorf("apps/tls/automation/policies: only a single ACME account email can be converted")
+			}
+			email = e
+		}
+	}
+
+	if email != "" {
+		w.global = append(w.global, "email "+caddyfileQuote(email))
+	}
+
+	return nil
+}
+
+// Convert the servers of the http app to site blocks
+func (w *caddyfileWriter) writeHTTPApp(value any) error {
+	app, _ := value.(map[string]any)
+
+	for key, v := range app {
+		switch key {
+		case "servers":
+		case "http_port", "https_port":
+			w.global = append(w.global, fmt.Sprintf("%s %v", key, v))
+		default:
+			return fmt.Errorf("apps/http/%s: not supported", key)
+		}
+	}
+
+	servers, _ := app["servers"].(map[string]any)
+	names := make([]string, 0, len(servers))
+	for name := range servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		srv, _ := servers[name].(map[string]any)
+		if err := w.writeServer(name, srv); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Convert a server to one site block per route
+func (w *caddyfileWriter) writeServer(name string, srv map[string]any) error {
+	path := "apps/http/servers/" + name
+
+	for key, v := range srv {
+		switch key {
+		case "listen", "routes":
+		case "automatic_https":
+			autoHTTPS, _ := v.(map[string]any)
+			for option, enabled := range autoHTTPS {
+				switch {
+				case option == "disable" && enabled == true:
+					w.global = append(w.global, "auto_https off")
+				case option == "disable_redirects" && enabled == true:
+					w.global = append(w.global, "auto_https disable_redirects")
+				default:
+					return fmt.Errorf("%s/automatic_https/%s: not supported", path, option)
+				}
+			}
+		default:
+			return fmt.Errorf("%s/%s: not supported", path, key)
+		}
+	}
+
+	listen, _ := srv["listen"].([]any)
+	if len(listen) != 1 {
+		return fmt.Errorf("%s/listen: only servers with a single listen address can be converted", path)
+	}
+
+	address, _ := listen[0].(string)
+	addr, err := caddy.ParseNetworkAddress(address)
+	if err != nil || addr.Network != "tcp" || addr.Host != "" || addr.StartPort != addr.EndPort {
+		return fmt.Errorf("%s/listen: only listen addresses like :443 can be converted", path)
+	}
+	port := int(addr.StartPort)
+
+	for i, r := range serverRoutes(srv) {
+		route, _ := r.(map[string]any)
+		if err := w.writeSite(joinConfigPath(path, "routes/"+strconv.Itoa(i)), port, route); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Build the address of a site block from its hosts and port
+func siteAddress(hosts []string, port int) string {
+	if len(hosts) == 0 {
+		return fmt.Sprintf(":%d", port)
+	}
+
+	addresses := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		switch port {
+		case 443:
+			addresses = append(addresses, host)
+		case 80:
+			addresses = append(addresses, "http://"+host)
+		default:
+			addresses = append(addresses, fmt.Sprintf("%s:%d", host, port))
+		}
+	}
+
+	return strings.Join(addresses, ", ")
+}
+
+// Convert a top-level route, matched only by host, to a site block
+func (w *caddyfileWriter) writeSite(path string, port int, route map[string]any) error {
+	for key := range route {
+		switch key {
+		case "match", "handle", "terminal", "@id":
+		default:
+			return fmt.Errorf("%s/%s: not supported", path, key)
+		}
+	}
+
+	var hosts []string
+	matchSets, _ := route["match"].([]any)
+	for _, m := range matchSets {
+		matchSet, _ := m.(map[string]any)
+		if len(matchSet) != 1 || matchSet["host"] == nil {
+			return fmt.Errorf("%s/match: only host matchers can be converted on top-level routes", path)
+		}
+		hosts = append(hosts, routeHosts(map[string]any{"match": []any{matchSet}})...)
+	}
+
+	w.matchers = 0
+	var named, lines []string
+	handlers, _ := route["handle"].([]any)
+	if err := w.writeHandlers(joinConfigPath(path, "handle"), handlers, "", &named, &lines); err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s {\n", siteAddress(hosts, port))
+	for _, line := range named {
+		b.WriteString(line + "\n")
+	}
+	for _, line := range lines {
+		b.WriteString(line + "\n")
+	}
+	b.WriteString("}\n")
+
+	w.sites = append(w.sites, b.String())
+
+	return nil
+}
+
+// Get the Caddyfile matcher token of a route inside a subroute
+func (w *caddyfileWriter) routeMatcher(path string, route map[string]any, named *[]string) (string, error) {
+	matchSets, _ := route["match"].([]any)
+	if len(matchSets) == 0 {
+		return "", nil
+	}
+
+	matchSet, _ := matchSets[0].(map[string]any)
+	if len(matchSets) != 1 || len(matchSet) != 1 || matchSet["path"] == nil {
+		return "", fmt.Errorf("%s/match: only path matchers can be converted inside a site", path)
+	}
+
+	paths, _ := matchSet["path"].([]any)
+	if len(paths) == 1 {
+		if p, ok := paths[0].(string); ok && strings.HasPrefix(p, "/") {
+			return caddyfileQuote(p), nil
+		}
+	}
+
+	w.matchers++
+	name := fmt.Sprintf("@path%d", w.matchers)
+	tokens := []string{name, "path"}
+	for _, p := range paths {
+		tokens = append(tokens, caddyfileQuote(fmt.Sprint(p)))
+	}
+	*named = append(*named, strings.Join(tokens, " "))
+
+	return name, nil
+}
+
+// Convert a list of handlers to directives, using matcher for each of them
+func (w *caddyfileWriter) writeHandlers(path string, handlers []any, matcher string, named, lines *[]string) error {
+	directive := func(name string, args ...string) {
+		tokens := []string{name}
+		if matcher != "" {
+			tokens = append(tokens, matcher)
+		}
+		*lines = append(*lines, strings.Join(append(tokens, args...), " "))
+	}
+
+	for i, h := range handlers {
+		handlerPath := joinConfigPath(path, strconv.Itoa(i))
+		handler, _ := h.(map[string]any)
+		name, _ := handler["handler"].(string)
+
+		unsupported := func(key string) error {
+			return fmt.Errorf("%s/%s: not supported for the %s handler", handlerPath, key, name)
+		}
+
+		switch name {
+		case "subroute":
+			routes, _ := handler["routes"].([]any)
+			for j, r := range routes {
+				routePath := joinConfigPath(handlerPath, "routes/"+strconv.Itoa(j))
+				route, _ := r.(map[string]any)
+
+				if tryFiles, ok := tryFilesDirective(route); ok {
+					if matcher != "" {
+						return fmt.Errorf("%s: try_files cannot be combined with a matcher", routePath)
+					}
+					*lines = append(*lines, tryFiles)
+					continue
+				}
+
+				inner, err := w.routeMatcher(routePath, route, named)
+				if err != nil {
+					return err
+				}
+				if inner != "" && matcher != "" {
+					return fmt.Errorf("%s/match: nested matchers cannot be converted", routePath)
+				}
+
+				innerHandlers, _ := route["handle"].([]any)
+				if err := w.writeHandlers(joinConfigPath(routePath, "handle"), innerHandlers, cmpOrString(inner, matcher), named, lines); err != nil {
+					return err
+				}
+			}
+
+		case "reverse_proxy":
+			var args []string
+			for _, dial := range proxyUpstreams(handler) {
+				args = append(args, caddyfileQuote(dial))
+			}
+
+			var block []string
+			for key, v := range handler {
+				switch key {
+				case "handler", "upstreams":
+				case "headers":
+					headers, _ := v.(map[string]any)
+					req, _ := headers["request"].(map[string]any)
+					set, _ := req["set"].(map[string]any)
+					if len(headers) != 1 || len(req) != 1 || len(set) == 0 {
+						return unsupported(key)
+					}
+					for field, values := range set {
+						for _, value := range values.([]any) {
+							block = append(block, fmt.Sprintf("header_up %s %s", field, caddyfileQuote(fmt.Sprint(value))))
+						}
+					}
+				default:
+					return unsupported(key)
+				}
+			}
+
+			if len(block) > 0 {
+				sort.Strings(block)
+				args = append(args, "{\n"+strings.Join(block, "\n")+"\n}")
+			}
+			directive("reverse_proxy", args...)
+
+		case "file_server":
+			var args, block []string
+			for key, v := range handler {
+				switch key {
+				case "handler", "hide":
+				case "root":
+					block = append(block, "root "+caddyfileQuote(fmt.Sprint(v)))
+				case "browse":
+					args = append(args, "browse")
+				default:
+					return unsupported(key)
+				}
+			}
+
+			if len(block) > 0 {
+				args = append(args, "{\n"+strings.Join(block, "\n")+"\n}")
+			}
+			directive("file_server", args...)
+
+		case "vars":
+			for key, v := range handler {
+				switch key {
+				case "handler":
+				case "root":
+					*lines = append(*lines, fmt.Sprintf("root %s %s", cmpOrString(matcher, "*"), caddyfileQuote(fmt.Sprint(v))))
+				default:
+					return unsupported(key)
+				}
+			}
+
+		case "encode":
+			var args []string
+			prefer, _ := handler["prefer"].([]any)
+			for _, p := range prefer {
+				args = append(args, fmt.Sprint(p))
+			}
+			if len(args) == 0 {
+				encodings, _ := handler["encodings"].(map[string]any)
+				for encoding := range encodings {
+					args = append(args, encoding)
+				}
+				sort.Strings(args)
+			}
+			for key := range handler {
+				if key != "handler" && key != "encodings" && key != "prefer" {
+					return unsupported(key)
+				}
+			}
+			directive("encode", args...)
+
+		case "static_response":
+			status := ""
+			if code, ok := handler["status_code"]; ok {
+				status = fmt.Sprint(code)
+			}
+
+			headers, _ := handler["headers"].(map[string]any)
+			location, _ := headers["Location"].([]any)
+			if len(headers) > 0 {
+				if len(headers) != 1 || len(location) != 1 || handler["body"] != nil {
+					return unsupported("headers")
+				}
+				directive("redir", caddyfileQuote(fmt.Sprint(location[0])), status)
+				continue
+			}
+
+			for key := range handler {
+				if key != "handler" && key != "status_code" && key != "body" {
+					return unsupported(key)
+				}
+			}
+
+			var args []string
+			if body, ok := handler["body"].(string); ok {
+				args = append(args, caddyfileQuote(body))
+			}
+			if status != "" {
+				args = append(args, status)
+			}
+			directive("respond", args...)
+
+		case "rewrite":
+			uri, ok := handler["uri"].(string)
+			if !ok || len(handler) != 2 {
+				return fmt.Errorf("%s: only rewrites of the uri can be converted", handlerPath)
+			}
+			directive("rewrite", caddyfileQuote(uri))
+
+		case "headers":
+			response, _ := handler["response"].(map[string]any)
+			set, _ := response["set"].(map[string]any)
+			if len(handler) != 2 || len(response) != 1 || len(set) == 0 {
+				return fmt.Errorf("%s: only setting response headers can be converted", handlerPath)
+			}
+
+			fields := make([]string, 0, len(set))
+			for field := range set {
+				fields = append(fields, field)
+			}
+			sort.Strings(fields)
+
+			for _, field := range fields {
+				values, _ := set[field].([]any)
+				for _, value := range values {
+					directive("header", field, caddyfileQuote(fmt.Sprint(value)))
+				}
+			}
+
+		default:
+			return fmt.Errorf("%s: the %s handler is not supported", handlerPath, cmpOrString(name, "unnamed"))
+		}
+	}
+
+	return nil
+}
+
+// Recognize the route the try_files directive adapts to
+func tryFilesDirective(route map[string]any) (string, bool) {
+	matchSets, _ := route["match"].([]any)
+	handlers, _ := route["handle"].([]any)
+	if len(matchSets) != 1 || len(handlers) != 1 {
+		return "", false
+	}
+
+	matchSet, _ := matchSets[0].(map[string]any)
+	file, _ := matchSet["file"].(map[string]any)
+	rewrite, _ := handlers[0].(map[string]any)
+	if len(matchSet) != 1 || len(file) != 1 || rewrite["handler"] != "rewrite" || rewrite["uri"] != "{http.matchers.file.relative}" {
+		return "", false
+	}
+
+	tryFiles, _ := file["try_files"].([]any)
+	if len(tryFiles) == 0 {
+		return "", false
+	}
+
+	tokens := []string{"try_files"}
+	for _, f := range tryFiles {
+		tokens = append(tokens, caddyfileQuote(fmt.Sprint(f)))
+	}
+
+	return strings.Join(tokens, " "), true
+}
+
+// Return the first non-empty string
+func cmpOrString(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,497 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/caddyserver/caddy/v2/caddyconfig"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+type certStatus struct {
+	Domain   string   `json:"domain"`
+	Status   string   `json:"status"`
+	Subject  string   `json:"subject,omitempty"`
+	SANs     []string `json:"sans,omitempty"`
+	Issuer   string   `json:"issuer,omitempty"`
+	NotAfter string   `json:"not_after,omitempty"`
+	Error    string   `json:"error,omitempty"`
+
+	leaf *x509.Certificate
+}
+
+type loadedCertificate struct {
+	Subject     string   `json:"subject"`
+	SANs        []string `json:"sans"`
+	Issuer      string   `json:"issuer"`
+	NotAfter    string   `json:"not_after"`
+	DaysLeft    int      `json:"days_left"`
+	Fingerprint string   `json:"sha256_fingerprint"`
+	ServedFor   []string `json:"served_for"`
+	Status      string   `json:"status"`
+}
+
+type loadedCertificates struct {
+	Certificates []loadedCertificate `json:"certificates"`
+	Unavailable  []certStatus        `json:"unavailable"`
+}
+
+type manageCertsResult struct {
+	Domains    []string              `json:"domains"`
+	Added      []string              `json:"added"`
+	StatusCode int                   `json:"status_code"`
+	Warnings   []caddyconfig.Warning `json:"warnings"`
+}
+
+type applyCertsResult struct {
+	Applied      bool         `json:"applied"`
+	NewDomains   []string     `json:"new_domains"`
+	Certificates []certStatus `json:"certificates"`
+}
+
+func registerCertificateTools(s *server.MCPServer) {
+	applyAndReportCerts := mcp.NewTool("apply_and_report_certs",
+		mcp.WithDescription(`
+		Use the apply_and_report_certs tool to update the caddy server configuration and then report whether HTTPS came up for the domains the new configuration adds.
+
+		After loading the configuration the tool waits for the certificates to settle and then connects to the caddy HTTPS listener for each new domain to inspect the certificate it serves.
+
+		Notes:
+			You must provide the full JSON configuration, like for the update_caddy_config tool.
+			Status is "valid" for a publicly trusted certificate, "untrusted" for a certificate that does not chain to a trusted root (for example caddy's internal CA or a certificate still being provisioned), "mismatch" when the certificate does not cover the domain and "unavailable" when no certificate could be retrieved.
+			Wildcard domains are reported without being probed.
+		`),
+		mcp.WithString("json_config",
+			mcp.Required(),
+			mcp.Description("The caddy server JSON configuration to update the caddy server with"),
+		),
+		mcp.WithNumber("settle_seconds",
+			mcp.Description("How long to wait after loading before checking certificates (default 5, max 60)"),
+			mcp.DefaultNumber(5),
+		),
+		mcp.WithString("https_address",
+			mcp.Description("The host:port of the caddy HTTPS listener (default the admin host on port 443)"),
+		),
+	)
+
+	// Add apply and report certs tool handler
+	s.AddTool(applyAndReportCerts, applyAndReportCertsHandler)
+
+	listLoadedCertificates := mcp.NewTool("list_loaded_certificates",
+		mcp.WithDescription(`
+		Use the list_loaded_certificates tool to list the TLS certificates caddy is actually serving right now, as opposed to what is merely configured.
+
+		The admin API does not expose caddy's certificate cache, so the tool connects to the caddy HTTPS listener once for each configured domain and inspects the certificate served. The result is a JSON document with one entry per distinct certificate: subject, SANs, issuer, expiry, SHA-256 fingerprint and the domains it was served for, plus the domains for which no certificate could be retrieved.
+
+		Notes:
+			Domains are the host matchers of every server and the names in apps/tls/certificates/automate. Wildcard names cannot be probed and are reported as unavailable.
+			Certificates caddy holds but does not serve for any configured domain are not listed.
+		`),
+		mcp.WithString("https_address",
+			mcp.Description("The host:port of the caddy HTTPS listener (default the admin host on port 443)"),
+		),
+	)
+
+	// Add list loaded certificates tool handler
+	s.AddTool(listLoadedCertificates, listLoadedCertificatesHandler)
+
+	manageCertificates := mcp.NewTool("manage_certificates",
+		mcp.WithDescription(`
+		Use the manage_certificates tool to make caddy obtain or renew the certificates of domains now, for example after fixing DNS for a domain whose certificate failed.
+
+		The domains are added to apps/tls/certificates/automate, so caddy manages their certificates even without a host matcher, and the configuration is reloaded with cache revalidation forced so caddy re-evaluates its certificates even when nothing changed.
+		The result is a JSON document with the domains, the ones newly added to automate, the status caddy answered the reload with and its warnings.
+
+		Notes:
+			This only works when the TLS app has automation policies, or the default issuers, able to issue certificates for the domains; use the explain_tls_for_domain tool to see which policy applies.
+			Caddy obtains certificates in the background, so the reload succeeding does not mean the certificates were issued; check with the list_loaded_certificates tool after a while.
+			Certificates that are still valid and not close to expiry are kept rather than renewed.
+			When changes require confirmation, the reload is proposed like any other change and only happens once it is confirmed.
+		`),
+		mcp.WithArray("domains",
+			mcp.Required(),
+			mcp.Description("The domains to manage certificates for, for example [\"example.com\", \"www.example.com\"]"),
+			mcp.Items(map[string]any{"type": "string"}),
+		),
+	)
+
+	// Add manage certificates tool handler
+	s.AddTool(manageCertificates, manageCertificatesHandler)
+
+	getPKICA := mcp.NewTool("get_pki_ca",
+		mcp.WithDescription(`
+		Use the get_pki_ca tool to get the details of one of caddy's internal certificate authorities, for example to get the root certificate that clients must trust for sites using tls internal.
+
+		The result is the JSON document from caddy's /pki/ca endpoint with the CA's id, name, the common names of its root and intermediate and both certificates in PEM format.
+
+		Notes:
+			The default CA is "local"; other ids are configured in apps/pki/certificate_authorities.
+			A CA only exists once it has been used or configured, so a CA that issued no certificate yet may not be found.
+		`),
+		mcp.WithString("id",
+			mcp.Description("The id of the certificate authority"),
+			mcp.DefaultString("local"),
+		),
+	)
+
+	// Add get PKI CA tool handler
+	s.AddTool(getPKICA, getPKICAHandler)
+}
+
+// Get the address of the Caddy HTTPS listener, defaulting to the admin host on port 443
+func httpsAddress(ctx context.Context, address string) (string, error) {
+	if address != "" {
+		if _, _, err := net.SplitHostPort(address); err != nil {
+			return "", fmt.Errorf("invalid https_address %q: %v", address, err)
+		}
+		return address, nil
+	}
+
+	u, err := url.Parse(adminURL(ctx))
+	if err != nil {
+		return "", err
+	}
+
+	return net.JoinHostPort(u.Hostname(), "443"), nil
+}
+
+// Connect to the HTTPS listener with the domain as SNI and inspect the certificate it serves
+func probeCertificate(ctx context.Context, address, domain string) certStatus {
+	status := certStatus{Domain: domain}
+
+	if strings.Contains(domain, "*") {
+		status.Status = "unavailable"
+		status.Error = "wildcard domains cannot be probed"
+		return status
+	}
+
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: client.Timeout},
+		Config: &tls.Config{
+			ServerName:         domain,
+			InsecureSkipVerify: true,
+		},
+	}
+
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		status.Status = "unavailable"
+		status.Error = err.Error()
+		return status
+	}
+	defer conn.Close()
+
+	certs := conn.(*tls.Conn).ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		status.Status = "unavailable"
+		status.Error = "no certificate presented"
+		return status
+	}
+
+	leaf := certs[0]
+	status.leaf = leaf
+	status.Subject = leaf.Subject.String()
+	status.SANs = leaf.DNSNames
+	status.Issuer = leaf.Issuer.String()
+	status.NotAfter = leaf.NotAfter.Format(time.RFC3339)
+
+	if err := leaf.VerifyHostname(domain); err != nil {
+		status.Status = "mismatch"
+		status.Error = err.Error()
+		return status
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	if _, err := leaf.Verify(x509.VerifyOptions{DNSName: domain, Intermediates: intermediates}); err != nil {
+		status.Status = "untrusted"
+		status.Error = err.Error()
+		return status
+	}
+
+	status.Status = "valid"
+	return status
+}
+
+// Collect the host matcher values of every server in a decoded config
+func configDomains(cfg map[string]any) map[string]bool {
+	domains := map[string]bool{}
+	for _, name := range httpServerNames(cfg) {
+		srv, err := httpServer(cfg, name)
+		if err != nil {
+			continue
+		}
+
+		walkRoutes(serverRoutes(srv), func(route map[string]any) {
+			for _, host := range routeHosts(route) {
+				domains[host] = true
+			}
+		})
+	}
+
+	return domains
+}
+
+// Apply a configuration and report the certificates served for the domains it adds
+func applyAndReportCertsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	config, err := request.RequireString("json_config")
+	if err != nil {
+		return nil, err
+	}
+
+	settle := request.GetInt("settle_seconds", 5)
+	if settle < 0 || settle > 60 {
+		return nil, fmt.Errorf("settle_seconds must be between 0 and 60")
+	}
+
+	address, err := httpsAddress(ctx, request.GetString("https_address", ""))
+	if err != nil {
+		return nil, err
+	}
+
+	var newCfg map[string]any
+	if err := json.Unmarshal([]byte(config), &newCfg); err != nil {
+		return nil, fmt.Errorf("json_config is not valid JSON: %v", err)
+	}
+
+	oldDomains := map[string]bool{}
+	oldCfg, err := fetchConfigMap(ctx)
+	switch {
+	case errors.Is(err, errNoConfig):
+	case err != nil:
+		return nil, err
+	default:
+		oldDomains = configDomains(oldCfg)
+	}
+
+	if _, err := loadConfig(ctx, []byte(config)); err != nil {
+		return caddyErrorResult(err)
+	}
+
+	// The configuration is applied, so other changes need not wait for the certificates
+	releaseConfigLock(ctx)
+
+	result := applyCertsResult{
+		Applied:      true,
+		NewDomains:   []string{},
+		Certificates: []certStatus{},
+	}
+	for domain := range configDomains(newCfg) {
+		if !oldDomains[domain] {
+			result.NewDomains = append(result.NewDomains, domain)
+		}
+	}
+	sort.Strings(result.NewDomains)
+
+	if len(result.NewDomains) > 0 {
+		select {
+		case <-time.After(time.Duration(settle) * time.Second):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	for _, domain := range result.NewDomains {
+		result.Certificates = append(result.Certificates, probeCertificate(ctx, address, domain))
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+// List the distinct certificates served for the configured domains
+func listLoadedCertificatesHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	address, err := httpsAddress(ctx, request.GetString("https_address", ""))
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := fetchConfigMap(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	domains := configDomains(cfg)
+	if tlsCerts, err := configObject(cfg, false, "apps", "tls", "certificates"); err == nil {
+		automate, _ := tlsCerts["automate"].([]any)
+		for _, name := range automate {
+			if domain, ok := name.(string); ok {
+				domains[domain] = true
+			}
+		}
+	}
+
+	names := make([]string, 0, len(domains))
+	for domain := range domains {
+		names = append(names, domain)
+	}
+	sort.Strings(names)
+
+	statuses := make([]certStatus, len(names))
+	sem := make(chan struct{}, healthSweepWorkers)
+	var wg sync.WaitGroup
+	for i, domain := range names {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			statuses[i] = probeCertificate(ctx, address, domain)
+		}()
+	}
+	wg.Wait()
+
+	result := loadedCertificates{
+		Certificates: []loadedCertificate{},
+		Unavailable:  []certStatus{},
+	}
+
+	byFingerprint := map[string]int{}
+	for _, status := range statuses {
+		if status.leaf == nil {
+			result.Unavailable = append(result.Unavailable, status)
+			continue
+		}
+
+		sum := sha256.Sum256(status.leaf.Raw)
+		fingerprint := hex.EncodeToString(sum[:])
+
+		i, ok := byFingerprint[fingerprint]
+		if !ok {
+			i = len(result.Certificates)
+			byFingerprint[fingerprint] = i
+			result.Certificates = append(result.Certificates, loadedCertificate{
+				Subject:     status.Subject,
+				SANs:        status.SANs,
+				Issuer:      status.Issuer,
+				NotAfter:    status.NotAfter,
+				DaysLeft:    int(time.Until(status.leaf.NotAfter).Hours() / 24),
+				Fingerprint: fingerprint,
+				ServedFor:   []string{},
+				Status:      status.Status,
+			})
+		}
+
+		cert := &result.Certificates[i]
+		cert.ServedFor = append(cert.ServedFor, status.Domain)
+		if status.Status != "valid" {
+			cert.Status = status.Status
+		}
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+// Add domains to the automated certificates and reload the configuration so caddy manages them
+func manageCertificatesHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	domains, err := request.RequireStringSlice("domains")
+	if err != nil {
+		return nil, err
+	}
+
+	if len(domains) == 0 {
+		return nil, fmt.Errorf("domains must not be empty")
+	}
+	for _, domain := range domains {
+		if domain == "" || strings.ContainsAny(domain, "/: ") {
+			return nil, fmt.Errorf("invalid domain: %q", domain)
+		}
+	}
+
+	cfg, err := fetchConfigMap(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	certificates, err := configObject(cfg, true, "apps", "tls", "certificates")
+	if err != nil {
+		return nil, err
+	}
+
+	automate, _ := certificates["automate"].([]any)
+	result := manageCertsResult{
+		Domains: domains,
+		Added:   []string{},
+	}
+	for _, domain := range domains {
+		if !slices.Contains(automate, any(domain)) {
+			automate = append(automate, domain)
+			result.Added = append(result.Added, domain)
+		}
+	}
+	certificates["automate"] = automate
+
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Caddy ignores loading an unchanged configuration unless revalidation is forced
+	body, err := loadConfig(withForceReload(ctx), data)
+	if err != nil {
+		return caddyErrorResult(err)
+	}
+
+	load := parseLoadResponse(body)
+	result.StatusCode = http.StatusOK
+	result.Warnings = load.Warnings
+
+	data, err = json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+// Get the root and intermediate certificates of an internal CA
+func getPKICAHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	id := request.GetString("id", "local")
+	if id == "" || strings.ContainsAny(id, "/?#") {
+		return nil, fmt.Errorf("invalid CA id: %q", id)
+	}
+
+	status, body, err := adminRequest(ctx, http.MethodGet, "/pki/ca/"+url.PathEscape(id), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	switch status {
+	case http.StatusOK:
+		return mcp.NewToolResultText(string(body)), nil
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("no certificate authority with id %q; the default CA is \"local\" and others are configured in apps/pki/certificate_authorities", id)
+	default:
+		return caddyErrorResult(&caddyError{
+			StatusCode: status,
+			Message:    string(body),
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const certsTestConfig = `{"apps":{"tls":{"certificates":{"automate":["example.com"]}}}}`
+
+func TestManageCertificatesForcesReload(t *testing.T) {
+	fc := newFakeCaddy(t, certsTestConfig)
+
+	// The domain is already managed, so only the forced reload makes caddy re-evaluate its certificate
+	result, err := callTool(configLockMiddleware(manageCertificatesHandler), "manage_certificates", map[string]any{"domains": []any{"example.com"}})
+	if err != nil {
+		t.Fatalf("manage_certificates: %v", err)
+	}
+
+	var got manageCertsResult
+	if err := json.Unmarshal([]byte(resultText(t, result)), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Added) != 0 {
+		t.Errorf("manage_certificates added %v, want nothing added", got.Added)
+	}
+
+	if fc.loadCount() != 1 {
+		t.Fatalf("manage_certificates loaded %d configurations, want 1", fc.loadCount())
+	}
+	if forced, conditional := fc.lastLoad(); !forced || !conditional {
+		t.Errorf("manage_certificates reload forced = %v, conditional = %v, want both", forced, conditional)
+	}
+}
+
+func TestManageCertificatesWithConfirmation(t *testing.T) {
+	useConfirmation(t)
+	fc := newFakeCaddy(t, certsTestConfig)
+
+	callAndConfirm(t, manageCertificatesHandler, "manage_certificates", map[string]any{"domains": []any{"example.com"}})
+
+	if fc.loadCount() != 1 {
+		t.Fatalf("confirmed manage_certificates loaded %d configurations, want 1", fc.loadCount())
+	}
+	if forced, _ := fc.lastLoad(); !forced {
+		t.Errorf("confirmed manage_certificates reload was not forced")
+	}
+}
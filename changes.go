@@ -0,0 +1,509 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// The configuration this server last wrote to each instance
+type configWrite struct {
+	config []byte
+	hash   string
+}
+
+var (
+	lastWriteMu sync.Mutex
+	lastWrites  = map[string]configWrite{}
+)
+
+type configHashResult struct {
+	Algorithm string `json:"algorithm"`
+	Hash      string `json:"hash"`
+}
+
+type configChange struct {
+	Path string `json:"path"`
+	Op   string `json:"op"`
+	Old  any    `json:"old,omitempty"`
+	New  any    `json:"new,omitempty"`
+}
+
+type configDiffResult struct {
+	Changed bool           `json:"changed"`
+	Changes []configChange `json:"changes"`
+	Diff    string         `json:"diff"`
+}
+
+// Line pairs above which unifiedDiff stops looking for common lines in the changed region
+const maxDiffCells = 4_000_000
+
+// Number of unchanged lines shown around each change in a unified diff
+const diffContext = 3
+
+type diffLine struct {
+	op   byte
+	text string
+}
+
+type externalChangeResult struct {
+	Changed       bool           `json:"changed"`
+	LastWriteHash string         `json:"last_write_hash,omitempty"`
+	CurrentHash   string         `json:"current_hash"`
+	Changes       []configChange `json:"changes,omitempty"`
+	Message       string         `json:"message"`
+}
+
+func registerChangeTools(s *server.MCPServer) {
+	getConfigHash := mcp.NewTool("get_config_hash",
+		mcp.WithDescription(`
+		Use the get_config_hash tool to get a SHA-256 hash of the current caddy server configuration.
+
+		Notes:
+			The configuration is normalized before hashing so the hash does not depend on key ordering or whitespace.
+			Compare hashes across calls to detect whether the configuration changed.
+		`),
+	)
+
+	// Add get config hash tool handler
+	s.AddTool(getConfigHash, getConfigHashHandler)
+
+	detectExternalChange := mcp.NewTool("detect_external_change",
+		mcp.WithDescription(`
+		Use the detect_external_change tool to check whether the caddy server configuration was changed outside of this MCP server since its last write.
+
+		The result is a JSON document with the recorded and current hashes and, when they differ, the list of changed configuration paths.
+
+		Notes:
+			Call this tool before updating the configuration to avoid overwriting changes made by someone else.
+			Nothing can be compared until this MCP server has written a configuration.
+		`),
+	)
+
+	// Add detect external change tool handler
+	s.AddTool(detectExternalChange, detectExternalChangeHandler)
+
+	diffCaddyConfig := mcp.NewTool("diff_caddy_config",
+		mcp.WithDescription(`
+		Use the diff_caddy_config tool to show what a proposed configuration would change compared to the current caddy server configuration, before applying it.
+
+		Both configurations are normalized with sorted keys and indentation. The result is a JSON document with the list of added, removed and changed configuration paths and a unified text diff of the two documents.
+
+		Notes:
+			Show the diff to the user and review it before calling the update_caddy_config tool.
+			When no configuration is currently loaded, the whole proposed configuration is reported as added.
+		`),
+		mcp.WithString("proposed_json",
+			mcp.Required(),
+			mcp.Description("The proposed caddy JSON configuration"),
+		),
+	)
+
+	// Add diff Caddy config tool handler
+	s.AddTool(diffCaddyConfig, diffCaddyConfigHandler)
+}
+
+// Normalize a JSON document by decoding and re-encoding it with sorted keys
+func normalizeJSON(data []byte) ([]byte, error) {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %v", err)
+	}
+
+	return json.Marshal(v)
+}
+
+// Compute the SHA-256 hash of a normalized JSON configuration
+func configHash(config []byte) (string, error) {
+	normalized, err := normalizeJSON(config)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(normalized)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Get the hash of the current Caddy configuration
+func getConfigHashHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	config, err := fetchConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := configHash(config)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(configHashResult{
+		Algorithm: "sha256",
+		Hash:      hash,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+// Remember the configuration this server last wrote so external changes can be detected
+func recordConfigWrite(ctx context.Context, config []byte) {
+	normalized, err := normalizeJSON(config)
+	if err != nil {
+		return
+	}
+
+	sum := sha256.Sum256(normalized)
+
+	lastWriteMu.Lock()
+	defer lastWriteMu.Unlock()
+	lastWrites[instanceName(ctx)] = configWrite{
+		config: normalized,
+		hash:   hex.EncodeToString(sum[:]),
+	}
+}
+
+// Compare two decoded JSON documents and list the changed paths
+func diffJSON(path string, oldValue, newValue any) []configChange {
+	oldMap, oldIsMap := oldValue.(map[string]any)
+	newMap, newIsMap := newValue.(map[string]any)
+	if oldIsMap && newIsMap {
+		keys := make([]string, 0, len(oldMap)+len(newMap))
+		for key := range oldMap {
+			keys = append(keys, key)
+		}
+		for key := range newMap {
+			if _, ok := oldMap[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+
+		var changes []configChange
+		for _, key := range keys {
+			childPath := joinConfigPath(path, key)
+			oldChild, inOld := oldMap[key]
+			newChild, inNew := newMap[key]
+			switch {
+			case !inOld:
+				changes = append(changes, configChange{Path: childPath, Op: "added", New: newChild})
+			case !inNew:
+				changes = append(changes, configChange{Path: childPath, Op: "removed", Old: oldChild})
+			default:
+				changes = append(changes, diffJSON(childPath, oldChild, newChild)...)
+			}
+		}
+		return changes
+	}
+
+	oldSlice, oldIsSlice := oldValue.([]any)
+	newSlice, newIsSlice := newValue.([]any)
+	if oldIsSlice && newIsSlice {
+		var changes []configChange
+		for i := 0; i < len(oldSlice) || i < len(newSlice); i++ {
+			childPath := joinConfigPath(path, strconv.Itoa(i))
+			switch {
+			case i >= len(oldSlice):
+				changes = append(changes, configChange{Path: childPath, Op: "added", New: newSlice[i]})
+			case i >= len(newSlice):
+				changes = append(changes, configChange{Path: childPath, Op: "removed", Old: oldSlice[i]})
+			default:
+				changes = append(changes, diffJSON(childPath, oldSlice[i], newSlice[i])...)
+			}
+		}
+		return changes
+	}
+
+	if reflect.DeepEqual(oldValue, newValue) {
+		return nil
+	}
+
+	return []configChange{{Path: path, Op: "changed", Old: oldValue, New: newValue}}
+}
+
+// Join config path segments the same way the admin API addresses them
+func joinConfigPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "/" + key
+}
+
+// Compare the current Caddy configuration against the one this server last wrote
+func detectExternalChangeHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	config, err := fetchConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	currentHash, err := configHash(config)
+	if err != nil {
+		return nil, err
+	}
+
+	lastWriteMu.Lock()
+	written := lastWrites[instanceName(ctx)]
+	lastWriteMu.Unlock()
+
+	writtenConfig, writtenHash := written.config, written.hash
+
+	result := externalChangeResult{
+		LastWriteHash: writtenHash,
+		CurrentHash:   currentHash,
+	}
+
+	switch {
+	case writtenHash == "":
+		result.Message = "This MCP server has not written a configuration yet, so there is nothing to compare against."
+	case writtenHash == currentHash:
+		result.Message = "The configuration has not changed since this MCP server last wrote it."
+	default:
+		var oldValue, newValue any
+		if err := json.Unmarshal(writtenConfig, &oldValue); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(config, &newValue); err != nil {
+			return nil, err
+		}
+
+		result.Changed = true
+		result.Changes = diffJSON("", oldValue, newValue)
+		result.Message = "The configuration was changed outside of this MCP server since its last write. Review the changes before overwriting them."
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+// Compute the lines removed, added and kept between two texts
+func diffLines(a, b []string) []diffLine {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var lines []diffLine
+	for _, line := range a[:prefix] {
+		lines = append(lines, diffLine{' ', line})
+	}
+
+	am, bm := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if len(am)*len(bm) > maxDiffCells {
+		for _, line := range am {
+			lines = append(lines, diffLine{'-', line})
+		}
+		for _, line := range bm {
+			lines = append(lines, diffLine{'+', line})
+		}
+	} else {
+		// Longest common subsequence of the changed region, built from the end
+		lcs := make([][]int, len(am)+1)
+		for i := range lcs {
+			lcs[i] = make([]int, len(bm)+1)
+		}
+		for i := len(am) - 1; i >= 0; i-- {
+			for j := len(bm) - 1; j >= 0; j-- {
+				if am[i] == bm[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else {
+					lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+				}
+			}
+		}
+
+		i, j := 0, 0
+		for i < len(am) || j < len(bm) {
+			switch {
+			case i < len(am) && j < len(bm) && am[i] == bm[j]:
+				lines = append(lines, diffLine{' ', am[i]})
+				i++
+				j++
+			case j == len(bm) || (i < len(am) && lcs[i+1][j] >= lcs[i][j+1]):
+				lines = append(lines, diffLine{'-', am[i]})
+				i++
+			default:
+				lines = append(lines, diffLine{'+', bm[j]})
+				j++
+			}
+		}
+	}
+
+	for _, line := range a[len(a)-suffix:] {
+		lines = append(lines, diffLine{' ', line})
+	}
+
+	return lines
+}
+
+// Format the differences between two texts as a unified diff
+func unifiedDiff(oldName, newName, oldText, newText string) string {
+	split := func(text string) []string {
+		if text == "" {
+			return nil
+		}
+		return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	}
+
+	lines := diffLines(split(oldText), split(newText))
+
+	var b strings.Builder
+	oldLine, newLine := 1, 1
+	for start := 0; start < len(lines); {
+		if lines[start].op == ' ' {
+			start++
+			oldLine++
+			newLine++
+			continue
+		}
+
+		// Extend the hunk until a run of unchanged lines is long enough to separate hunks
+		end := start
+		for end < len(lines) {
+			if lines[end].op != ' ' {
+				end++
+				continue
+			}
+			run := end
+			for run < len(lines) && lines[run].op == ' ' {
+				run++
+			}
+			if run == len(lines) || run-end > 2*diffContext {
+				break
+			}
+			end = run
+		}
+
+		before := min(diffContext, start)
+		after := 0
+		for after < diffContext && end+after < len(lines) && lines[end+after].op == ' ' {
+			after++
+		}
+
+		hunk := lines[start-before : end+after]
+		oldCount, newCount := 0, 0
+		for _, line := range hunk {
+			if line.op != '+' {
+				oldCount++
+			}
+			if line.op != '-' {
+				newCount++
+			}
+		}
+
+		oldStart, newStart := oldLine-before, newLine-before
+		if oldCount == 0 {
+			oldStart--
+		}
+		if newCount == 0 {
+			newStart--
+		}
+
+		if b.Len() == 0 {
+			fmt.Fprintf(&b, "--- %s\n+++ %s\n", oldName, newName)
+		}
+		fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart, newCount)
+		for _, line := range hunk {
+			b.WriteByte(line.op)
+			b.WriteString(line.text)
+			b.WriteByte('\n')
+		}
+
+		for _, line := range lines[start:end] {
+			if line.op != '+' {
+				oldLine++
+			}
+			if line.op != '-' {
+				newLine++
+			}
+		}
+		start = end
+	}
+
+	return b.String()
+}
+
+// Indent a decoded JSON document with sorted keys, or return an empty text for no document
+func indentJSON(value any) (string, error) {
+	if value == nil {
+		return "", nil
+	}
+
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	return string(data) + "\n", nil
+}
+
+// Compare a proposed configuration with the current one
+func diffCaddyConfigHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	proposedJSON, err := request.RequireString("proposed_json")
+	if err != nil {
+		return nil, err
+	}
+
+	var proposed any
+	if err := json.Unmarshal([]byte(proposedJSON), &proposed); err != nil {
+		return nil, fmt.Errorf("proposed_json is not valid JSON: %v", err)
+	}
+
+	var current any
+	config, err := fetchConfig(ctx)
+	switch {
+	case errors.Is(err, errNoConfig):
+	case err != nil:
+		return nil, err
+	default:
+		if err := json.Unmarshal(config, &current); err != nil {
+			return nil, fmt.Errorf("failed to parse Caddy configuration: %v", err)
+		}
+	}
+
+	oldText, err := indentJSON(current)
+	if err != nil {
+		return nil, err
+	}
+
+	newText, err := indentJSON(proposed)
+	if err != nil {
+		return nil, err
+	}
+
+	result := configDiffResult{
+		Changes: pathChanges("", current, proposed),
+		Diff:    unifiedDiff("current", "proposed", oldText, newText),
+	}
+	if result.Changes == nil {
+		result.Changes = []configChange{}
+	}
+	result.Changed = len(result.Changes) > 0
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestConfigHashIgnoresFormatting(t *testing.T) {
+	a, err := configHash([]byte(`{"apps":{"http":{"servers":{}}},"admin":{"listen":":2019"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := configHash([]byte("{\n  \"admin\": {\"listen\": \":2019\"},\n  \"apps\": {\"http\": {\"servers\": {}}}\n}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if a != b {
+		t.Errorf("configHash() differs for the same configuration: %s and %s", a, b)
+	}
+
+	c, err := configHash([]byte(`{"apps":{"http":{"servers":{}}},"admin":{"listen":":2020"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if a == c {
+		t.Errorf("configHash() is the same for two different configurations")
+	}
+}
+
+func TestConfigHashInvalidJSON(t *testing.T) {
+	if _, err := configHash([]byte(`{"apps":`)); err == nil {
+		t.Errorf("configHash() of invalid JSON returned no error")
+	}
+}
+
+func TestGetConfigHash(t *testing.T) {
+	fc := newFakeCaddy(t, `{"apps":{}}`)
+
+	hash := func() string {
+		result, err := callTool(getConfigHashHandler, "get_config_hash", nil)
+		if err != nil {
+			t.Fatalf("get_config_hash: %v", err)
+		}
+
+		var got configHashResult
+		if err := json.Unmarshal([]byte(resultText(t, result)), &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Algorithm != "sha256" {
+			t.Errorf("get_config_hash algorithm = %q, want sha256", got.Algorithm)
+		}
+		return got.Hash
+	}
+
+	before := hash()
+	fc.set(`{"apps":{"http":{}}}`)
+	if hash() == before {
+		t.Errorf("get_config_hash did not change with the configuration")
+	}
+}
+
+func TestDiffJSON(t *testing.T) {
+	var oldValue, newValue any
+	if err := json.Unmarshal([]byte(`{
+		"apps": {"http": {"servers": {"srv0": {"listen": [":443"], "routes": [{"@id": "a"}, {"@id": "b"}]}}}},
+		"logging": {}
+	}`), &oldValue); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(`{
+		"apps": {"http": {"servers": {"srv0": {"listen": [":8443"], "routes": [{"@id": "a"}]}}}, "tls": {}}
+	}`), &newValue); err != nil {
+		t.Fatal(err)
+	}
+
+	changes := diffJSON("", oldValue, newValue)
+
+	want := []configChange{
+		{Path: "apps/http/servers/srv0/listen/0", Op: "changed"},
+		{Path: "apps/http/servers/srv0/routes/1", Op: "removed"},
+		{Path: "apps/tls", Op: "added"},
+		{Path: "logging", Op: "removed"},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("diffJSON() = %+v, want %d changes", changes, len(want))
+	}
+	for i, change := range changes {
+		if change.Path != want[i].Path || change.Op != want[i].Op {
+			t.Errorf("diffJSON() change %d = %s %s, want %s %s", i, change.Op, change.Path, want[i].Op, want[i].Path)
+		}
+	}
+
+	if changes := diffJSON("", oldValue, oldValue); len(changes) != 0 {
+		t.Errorf("diffJSON() of equal documents = %+v, want no changes", changes)
+	}
+}
+
+func TestDetectExternalChange(t *testing.T) {
+	fc := newFakeCaddy(t, `{"apps":{}}`)
+
+	detect := func() externalChangeResult {
+		result, err := callTool(detectExternalChangeHandler, "detect_external_change", nil)
+		if err != nil {
+			t.Fatalf("detect_external_change: %v", err)
+		}
+
+		var got externalChangeResult
+		if err := json.Unmarshal([]byte(resultText(t, result)), &got); err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	if _, err := loadConfig(context.Background(), []byte(`{"apps":{"http":{"http_port":8080}}}`)); err != nil {
+		t.Fatal(err)
+	}
+	if got := detect(); got.Changed {
+		t.Errorf("detect_external_change after our own write = %+v, want no change", got)
+	}
+
+	fc.set(`{"apps":{"http":{"http_port":9090}}}`)
+	got := detect()
+	if !got.Changed || len(got.Changes) != 1 || got.Changes[0].Path != "apps/http/http_port" {
+		t.Errorf("detect_external_change after an external write = %+v, want a change of apps/http/http_port", got)
+	}
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/caddyserver/caddy/v2"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+var envPlaceholderRegexp = regexp.MustCompile(`\{env\.([^{}]+)\}`)
+
+type envReference struct {
+	Variable string   `json:"variable"`
+	Set      bool     `json:"set"`
+	Paths    []string `json:"paths"`
+}
+
+type envReferenceReport struct {
+	References []envReference `json:"references"`
+	Unresolved []string       `json:"unresolved"`
+}
+
+type duplicateID struct {
+	ID    string   `json:"id"`
+	Paths []string `json:"paths"`
+}
+
+type matcherReference struct {
+	Server  string `json:"server"`
+	Path    string `json:"path"`
+	Matcher string `json:"matcher"`
+	Problem string `json:"problem"`
+}
+
+func registerCheckTools(s *server.MCPServer) {
+	checkEnvReferences := mcp.NewTool("check_env_references",
+		mcp.WithDescription(`
+		Use the check_env_references tool to find {env.*} placeholders in a caddy JSON configuration and check whether each referenced environment variable is set.
+
+		The result is a JSON document listing each referenced variable, whether it is set and where it is used, plus the list of unresolved variables.
+
+		Notes:
+			Variables are checked in the environment of this MCP server, which only matches caddy's environment when both run on the same host with the same environment.
+			If json_config is not provided the current caddy server configuration is checked.
+		`),
+		mcp.WithString("json_config",
+			mcp.Description("The caddy JSON configuration to check instead of the current configuration"),
+		),
+	)
+
+	// Add check env references tool handler
+	s.AddTool(checkEnvReferences, checkEnvReferencesHandler)
+
+	checkDuplicateIDs := mcp.NewTool("check_duplicate_ids",
+		mcp.WithDescription(`
+		Use the check_duplicate_ids tool to find @id values that are used by more than one object in a caddy JSON configuration.
+
+		The result is a JSON document listing each duplicated id with the configuration paths where it appears. An empty list means every id is unique.
+
+		Notes:
+			Duplicate ids make the /id/ admin endpoint ambiguous; give each object a unique @id before using id-based paths.
+			If json_config is not provided the current caddy server configuration is checked.
+		`),
+		mcp.WithString("json_config",
+			mcp.Description("The caddy JSON configuration to check instead of the current configuration"),
+		),
+	)
+
+	// Add check duplicate ids tool handler
+	s.AddTool(checkDuplicateIDs, checkDuplicateIDsHandler)
+
+	checkMatcherReferences := mcp.NewTool("check_matcher_references",
+		mcp.WithDescription(`
+		Use the check_matcher_references tool to find route matchers in a caddy JSON configuration that caddy cannot resolve, before loading it.
+
+		Every matcher set of every route, including routes in subroutes and sets inside not matchers, is checked. The result is a JSON document listing each dangling reference with its server, config path and the problem. An empty list means every matcher resolves.
+
+		Notes:
+			JSON configurations have no named matchers: the Caddyfile adapter inlines the conditions of a named matcher like @api into each route using it. A matcher key starting with @ is a reference left over from a Caddyfile and must be replaced by the matchers it stands for.
+			Other matcher keys must be the name of a matcher module in this build, such as host, path or header.
+			If json_config is not provided the current caddy server configuration is checked.
+		`),
+		mcp.WithString("json_config",
+			mcp.Description("The caddy JSON configuration to check instead of the current configuration"),
+		),
+	)
+
+	// Add check matcher references tool handler
+	s.AddTool(checkMatcherReferences, checkMatcherReferencesHandler)
+}
+
+// Decode the json_config argument, or the current Caddy configuration when it is not provided
+func configArgument(ctx context.Context, request mcp.CallToolRequest) (any, error) {
+	config := []byte(request.GetString("json_config", ""))
+	if len(config) == 0 {
+		var err error
+		config, err = fetchConfig(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var cfg any
+	if err := json.Unmarshal(config, &cfg); err != nil {
+		return nil, fmt.Errorf("json_config is not valid JSON: %v", err)
+	}
+
+	return cfg, nil
+}
+
+// Report the environment variables referenced by a configuration
+func checkEnvReferencesHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	cfg, err := configArgument(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := map[string][]string{}
+	walkJSON("", cfg, func(path string, value any) {
+		str, ok := value.(string)
+		if !ok {
+			return
+		}
+
+		for _, match := range envPlaceholderRegexp.FindAllStringSubmatch(str, -1) {
+			paths[match[1]] = append(paths[match[1]], path)
+		}
+	})
+
+	report := envReferenceReport{
+		References: []envReference{},
+		Unresolved: []string{},
+	}
+	for variable, usedAt := range paths {
+		_, set := os.LookupEnv(variable)
+		sort.Strings(usedAt)
+
+		report.References = append(report.References, envReference{
+			Variable: variable,
+			Set:      set,
+			Paths:    usedAt,
+		})
+		if !set {
+			report.Unresolved = append(report.Unresolved, variable)
+		}
+	}
+
+	sort.Slice(report.References, func(i, j int) bool {
+		return report.References[i].Variable < report.References[j].Variable
+	})
+	sort.Strings(report.Unresolved)
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+// Report @id values used by more than one object
+func checkDuplicateIDsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	cfg, err := configArgument(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := map[string][]string{}
+	walkJSON("", cfg, func(path string, value any) {
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return
+		}
+
+		if id, ok := obj["@id"].(string); ok {
+			paths[id] = append(paths[id], path)
+		}
+	})
+
+	duplicates := []duplicateID{}
+	for id, usedAt := range paths {
+		if len(usedAt) > 1 {
+			sort.Strings(usedAt)
+			duplicates = append(duplicates, duplicateID{ID: id, Paths: usedAt})
+		}
+	}
+
+	sort.Slice(duplicates, func(i, j int) bool {
+		return duplicates[i].ID < duplicates[j].ID
+	})
+
+	data, err := json.Marshal(duplicates)
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+// Check whether a config path points at a matcher set of a route, .../routes/N/match/N, or of a not matcher in one,
+// .../routes/N/match/N/not/N. Other objects under a "match" key, like the response matcher of handle_response, are not matcher sets.
+func isMatcherSetPath(path string) bool {
+	parts := strings.Split(path, "/")
+	n := len(parts)
+	if n < 4 || !isIndexSegment(parts[n-1]) {
+		return false
+	}
+
+	switch parts[n-2] {
+	case "match":
+		return parts[n-4] == "routes" && isIndexSegment(parts[n-3])
+	case "not":
+		return isMatcherSetPath(strings.Join(parts[:n-2], "/"))
+	default:
+		return false
+	}
+}
+
+// Check whether a config path segment is an array index
+func isIndexSegment(segment string) bool {
+	index, err := strconv.Atoi(segment)
+	return err == nil && index >= 0
+}
+
+// Report route matchers that do not resolve to a matcher module
+func checkMatcherReferencesHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	value, err := configArgument(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("the configuration must be a JSON object")
+	}
+
+	problems := []matcherReference{}
+	for _, name := range httpServerNames(cfg) {
+		srv, err := httpServer(cfg, name)
+		if err != nil {
+			continue
+		}
+
+		walkJSON(joinConfigPath("apps/http/servers/"+name, "routes"), srv["routes"], func(path string, value any) {
+			matchSet, ok := value.(map[string]any)
+			if !ok || !isMatcherSetPath(path) {
+				return
+			}
+
+			for key := range matchSet {
+				problem := ""
+				if strings.HasPrefix(key, "@") {
+					problem = fmt.Sprintf("named matcher %s is not defined; JSON configurations cannot reference named matchers, inline its conditions instead", key)
+				} else if _, err := caddy.GetModule("http.matchers." + key); err != nil {
+					problem = fmt.Sprintf("unknown matcher %q", key)
+				}
+
+				if problem != "" {
+					problems = append(problems, matcherReference{
+						Server:  name,
+						Path:    joinConfigPath(path, key),
+						Matcher: key,
+						Problem: problem,
+					})
+				}
+			}
+		})
+	}
+
+	sort.Slice(problems, func(i, j int) bool {
+		return problems[i].Path < problems[j].Path
+	})
+
+	data, err := json.Marshal(problems)
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestIsMatcherSetPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{path: "apps/http/servers/srv0/routes/0/match/0", want: true},
+		{path: "apps/http/servers/srv0/routes/2/match/1", want: true},
+		{path: "apps/http/servers/srv0/routes/0/handle/0/routes/1/match/0", want: true},
+		{path: "apps/http/servers/srv0/routes/0/match/0/not/0", want: true},
+		{path: "apps/http/servers/srv0/routes/0/match", want: false},
+		{path: "apps/http/servers/srv0/routes/0", want: false},
+		{path: "match", want: false},
+		{path: "apps/http/servers/srv0/routes/0/match/0/not/0/not/1", want: true},
+		{path: "apps/http/servers/srv0/routes/0/handle/0/handle_response/0/match", want: false},
+		{path: "apps/http/servers/srv0/routes/0/handle/0/handle_response/0/match/headers", want: false},
+		{path: "apps/http/servers/srv0/routes/0/handle/0/match/0", want: false},
+		{path: "apps/http/servers/srv0/routes/0/match/0/header/not", want: false},
+		{path: "apps/http/servers/srv0/routes/0/handle/0/not/0", want: false},
+		{path: "apps/http/servers/srv0/routes/x/match/0", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := isMatcherSetPath(tt.path); got != tt.want {
+			t.Errorf("isMatcherSetPath(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestCheckMatcherReferences(t *testing.T) {
+	config := `{"apps":{"http":{"servers":{"srv0":{"routes":[
+		{"match":[{"host":["example.com"],"@api":{}}],"handle":[{"handler":"subroute","routes":[
+			{"match":[{"hostt":["example.com"]}]}
+		]}]},
+		{"match":[{"path":["/static/*"]}]}
+	]}}}}}`
+
+	result, err := callTool(checkMatcherReferencesHandler, "check_matcher_references", map[string]any{"json_config": config})
+	if err != nil {
+		t.Fatalf("check_matcher_references: %v", err)
+	}
+
+	var problems []matcherReference
+	if err := json.Unmarshal([]byte(resultText(t, result)), &problems); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"apps/http/servers/srv0/routes/0/handle/0/routes/0/match/0/hostt",
+		"apps/http/servers/srv0/routes/0/match/0/@api",
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("check_matcher_references = %+v, want %d problems", problems, len(want))
+	}
+	for i, problem := range problems {
+		if problem.Path != want[i] || problem.Server != "srv0" {
+			t.Errorf("problem %d = %+v, want path %s", i, problem, want[i])
+		}
+	}
+}
+
+func TestCheckMatcherReferencesIgnoresResponseMatchers(t *testing.T) {
+	config := `{"apps":{"http":{"servers":{"srv0":{"routes":[
+		{"match":[{"host":["example.com"]}],"handle":[{"handler":"reverse_proxy","handle_response":[
+			{"match":{"status_code":[404],"headers":{"X-Accel-Redirect":["*"]}},"routes":[{"handle":[{"handler":"file_server"}]}]}
+		]}]}
+	]}}}}}`
+
+	result, err := callTool(checkMatcherReferencesHandler, "check_matcher_references", map[string]any{"json_config": config})
+	if err != nil {
+		t.Fatalf("check_matcher_references: %v", err)
+	}
+
+	if got := resultText(t, result); got != "[]" {
+		t.Errorf("check_matcher_references = %s, want no problems for a handle_response matcher", got)
+	}
+}
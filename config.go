@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Look up a nested object in a decoded config, creating missing objects when create is set
+func configObject(parent map[string]any, create bool, keys ...string) (map[string]any, error) {
+	current := parent
+	for i, key := range keys {
+		next, ok := current[key]
+		if !ok || next == nil {
+			if !create {
+				return nil, fmt.Errorf("%s not found in configuration", strings.Join(keys[:i+1], "/"))
+			}
+			next = map[string]any{}
+			current[key] = next
+		}
+
+		obj, ok := next.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%s is not an object", strings.Join(keys[:i+1], "/"))
+		}
+		current = obj
+	}
+
+	return current, nil
+}
+
+// Call fn for every value in a decoded JSON document with its config path
+func walkJSON(path string, value any, fn func(path string, value any)) {
+	fn(path, value)
+
+	switch v := value.(type) {
+	case map[string]any:
+		for key, child := range v {
+			walkJSON(joinConfigPath(path, key), child, fn)
+		}
+	case []any:
+		for i, child := range v {
+			walkJSON(joinConfigPath(path, strconv.Itoa(i)), child, fn)
+		}
+	}
+}
+
+// Find a server in the http app of a decoded config
+func httpServer(cfg map[string]any, name string) (map[string]any, error) {
+	servers, err := configObject(cfg, false, "apps", "http", "servers")
+	if err != nil {
+		return nil, err
+	}
+
+	srv, ok := servers[name].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("server %q not found in apps/http/servers", name)
+	}
+
+	return srv, nil
+}
+
+// Load a modified config into Caddy, returning the JSON that was loaded
+func loadConfigMap(ctx context.Context, cfg map[string]any) ([]byte, error) {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := loadConfig(ctx, data); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// Load a modified config into Caddy and return it as the tool result
+func applyConfigMap(ctx context.Context, cfg map[string]any) (*mcp.CallToolResult, error) {
+	data, err := loadConfigMap(ctx, cfg)
+	if err != nil {
+		return caddyErrorResult(err)
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
+}
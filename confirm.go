@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// How long a proposed change can be confirmed
+const confirmationTTL = 15 * time.Minute
+
+type confirmationRequired struct {
+	Status    string         `json:"status"`
+	Token     string         `json:"token"`
+	Method    string         `json:"method"`
+	Path      string         `json:"path"`
+	Changes   []configChange `json:"changes"`
+	ExpiresAt time.Time      `json:"expires_at"`
+}
+
+func (c *confirmationRequired) Error() string {
+	return fmt.Sprintf("change requires confirmation: call confirm_change with token %s", c.Token)
+}
+
+type pendingChange struct {
+	proposal *confirmationRequired
+	baseHash string
+	instance string
+	apply    func(ctx context.Context) ([]byte, error)
+}
+
+var (
+	pendingMu      sync.Mutex
+	pendingChanges = map[string]*pendingChange{}
+)
+
+func registerConfirmTools(s *server.MCPServer) {
+	confirmChange := mcp.NewTool("confirm_change",
+		mcp.WithDescription(`
+		Use the confirm_change tool to apply a configuration change that is waiting for confirmation.
+
+		When the MCP server runs with -require-confirmation, tools that change the caddy configuration do not apply it. They return a "confirmation_required" result with the proposed changes and a token instead. Show the changes to the user and call this tool with the token once they approve.
+
+		Notes:
+			Tokens can be used once and expire after 15 minutes.
+			The change is refused when the configuration was modified after it was proposed; run the original tool again to get a new proposal.
+		`),
+		mcp.WithString("token",
+			mcp.Required(),
+			mcp.Description("The token of the proposed change"),
+		),
+	)
+
+	// Add confirm change tool handler
+	s.AddTool(confirmChange, confirmChangeHandler)
+}
+
+// Get the hash of the current configuration, or an empty string when none is loaded
+func currentConfigHash(ctx context.Context) string {
+	current, err := fetchConfig(ctx)
+	if err != nil {
+		return ""
+	}
+
+	hash, err := configHash(current)
+	if err != nil {
+		return ""
+	}
+
+	return hash
+}
+
+// Store a change to apply once it is confirmed and return the error telling the model so
+func proposeChange(ctx context.Context, method, path string, changes []configChange, apply func(ctx context.Context) ([]byte, error)) error {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return err
+	}
+
+	if changes == nil {
+		changes = []configChange{}
+	}
+
+	proposal := &confirmationRequired{
+		Status:    "confirmation_required",
+		Token:     hex.EncodeToString(buf),
+		Method:    method,
+		Path:      path,
+		Changes:   changes,
+		ExpiresAt: time.Now().Add(confirmationTTL),
+	}
+
+	// Hash before locking so a slow admin API does not hold up other proposals and confirmations
+	baseHash := currentConfigHash(ctx)
+
+	pendingMu.Lock()
+	defer pendingMu.Unlock()
+
+	for token, pending := range pendingChanges {
+		if time.Now().After(pending.proposal.ExpiresAt) {
+			delete(pendingChanges, token)
+		}
+	}
+
+	pendingChanges[proposal.Token] = &pendingChange{
+		proposal: proposal,
+		baseHash: baseHash,
+		instance: instanceName(ctx),
+		apply:    apply,
+	}
+
+	return proposal
+}
+
+// Propose loading a full configuration, with the changes it makes to the current one
+func proposeLoad(ctx context.Context, config []byte) error {
+	var proposed any
+	if err := json.Unmarshal(config, &proposed); err != nil {
+		return fmt.Errorf("invalid JSON configuration: %v", err)
+	}
+
+	var current any
+	data, err := fetchConfig(ctx)
+	switch {
+	case errors.Is(err, errNoConfig):
+	case err != nil:
+		return err
+	default:
+		if err := json.Unmarshal(data, &current); err != nil {
+			return err
+		}
+	}
+
+	// update_caddy_config remembers the configuration it replaces for undo_caddy_config, also when the change is confirmed.
+	// Confirming checks that the configuration did not change since, so it is still the one being replaced.
+	// An undo keeps its snapshot until it is confirmed, so a change that is never confirmed can still be undone.
+	call, _ := ctx.Value(toolCallKey{}).(toolCall)
+	undoable := call.tool == "update_caddy_config" && data != nil
+	undoing := call.tool == "undo_caddy_config"
+	force := forcedReload(ctx)
+
+	return proposeChange(ctx, "POST", "/load", diffJSON("", current, proposed), func(ctx context.Context) ([]byte, error) {
+		if force {
+			ctx = withForceReload(ctx)
+		}
+
+		body, err := applyConfig(ctx, config)
+		switch {
+		case err != nil:
+		case undoable:
+			pushUndo(ctx, data)
+		case undoing:
+			dropUndo(ctx, config)
+		}
+		return body, err
+	})
+}
+
+// Apply a proposed change
+func confirmChangeHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	token, err := request.RequireString("token")
+	if err != nil {
+		return nil, err
+	}
+
+	pendingMu.Lock()
+	pending, ok := pendingChanges[token]
+	delete(pendingChanges, token)
+	pendingMu.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown or already used token: %s", token)
+	}
+
+	if time.Now().After(pending.proposal.ExpiresAt) {
+		return nil, fmt.Errorf("the change expired at %s; run the original tool again", pending.proposal.ExpiresAt.Format(time.RFC3339))
+	}
+
+	// The change is applied to the instance it was proposed for, whatever the instance argument of this call
+	ctx = withInstance(ctx, pending.instance)
+
+	if currentConfigHash(ctx) != pending.baseHash {
+		return nil, fmt.Errorf("the configuration changed after this change was proposed; run the original tool again")
+	}
+
+	body, err := pending.apply(ctx)
+	if err != nil {
+		return caddyErrorResult(err)
+	}
+
+	data, err := json.Marshal(parseLoadResponse(body))
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// Turn on -require-confirmation for the duration of a test
+func useConfirmation(t *testing.T) {
+	t.Helper()
+
+	old := requireConfirmation
+	requireConfirmation = true
+	t.Cleanup(func() { requireConfirmation = old })
+}
+
+// Call update_caddy_config and return the proposal it made
+func proposeUpdate(t *testing.T, config string) confirmationRequired {
+	t.Helper()
+
+	result, err := callTool(updateCaddyConfigHandler, "update_caddy_config", map[string]any{"json_config": config})
+	if err != nil {
+		t.Fatalf("update_caddy_config: %v", err)
+	}
+
+	var proposal confirmationRequired
+	if err := json.Unmarshal([]byte(resultText(t, result)), &proposal); err != nil {
+		t.Fatal(err)
+	}
+	if proposal.Status != "confirmation_required" || proposal.Token == "" {
+		t.Fatalf("update_caddy_config = %+v, want a confirmation_required proposal", proposal)
+	}
+
+	return proposal
+}
+
+func TestConfirmChange(t *testing.T) {
+	useConfirmation(t)
+	fc := newFakeCaddy(t, `{"apps":{"http":{"http_port":80}}}`)
+
+	proposal := proposeUpdate(t, `{"apps":{"http":{"http_port":8080}}}`)
+	if fc.loadCount() != 0 {
+		t.Fatalf("the proposed change was loaded before it was confirmed")
+	}
+	if len(proposal.Changes) != 1 || proposal.Changes[0].Path != "apps/http/http_port" {
+		t.Errorf("proposal changes = %+v, want a change of apps/http/http_port", proposal.Changes)
+	}
+
+	if _, err := callTool(confirmChangeHandler, "confirm_change", map[string]any{"token": proposal.Token}); err != nil {
+		t.Fatalf("confirm_change: %v", err)
+	}
+	if got := fc.current(); got != `{"apps":{"http":{"http_port":8080}}}` {
+		t.Errorf("configuration after confirm_change = %s, want the proposed one", got)
+	}
+
+	// Tokens can only be used once
+	if _, err := callTool(confirmChangeHandler, "confirm_change", map[string]any{"token": proposal.Token}); err == nil {
+		t.Errorf("confirming a change twice returned no error")
+	}
+}
+
+func TestConfirmChangeAfterExternalChange(t *testing.T) {
+	useConfirmation(t)
+	fc := newFakeCaddy(t, `{"apps":{"http":{"http_port":80}}}`)
+
+	proposal := proposeUpdate(t, `{"apps":{"http":{"http_port":8080}}}`)
+	fc.set(`{"apps":{"http":{"http_port":9090}}}`)
+
+	if _, err := callTool(confirmChangeHandler, "confirm_change", map[string]any{"token": proposal.Token}); err == nil {
+		t.Fatalf("confirm_change after the configuration changed returned no error")
+	}
+	if got := fc.current(); got != `{"apps":{"http":{"http_port":9090}}}` {
+		t.Errorf("configuration after a refused confirm_change = %s, want the external change kept", got)
+	}
+}
+
+func TestConfirmChangeUnknownToken(t *testing.T) {
+	if _, err := callTool(confirmChangeHandler, "confirm_change", map[string]any{"token": "unknown"}); err == nil {
+		t.Errorf("confirm_change with an unknown token returned no error")
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+var (
+	defaultCORSMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+	defaultCORSHeaders = []string{"Content-Type", "Authorization"}
+)
+
+func registerCORSTools(s *server.MCPServer) {
+	enableCORS := mcp.NewTool("enable_cors",
+		mcp.WithDescription(`
+		Use the enable_cors tool to add CORS headers to a route of a caddy server.
+
+		A subroute is inserted in front of the route's handlers which answers preflight (OPTIONS) requests from the allowed origins with a 204 response and adds the CORS headers to all other requests from those origins.
+		The updated configuration is applied to the caddy server and returned in JSON format.
+
+		Notes:
+			The route is selected by its index in the server's routes or by one of its host matcher values.
+			Use "*" as the only allowed origin to allow any origin. Credentials cannot be allowed together with "*".
+			Calling the tool again for the same route replaces its CORS settings instead of adding a second subroute.
+		`),
+		mcp.WithString("server_name",
+			mcp.Required(),
+			mcp.Description("The name of the server in apps/http/servers"),
+		),
+		mcp.WithString("route",
+			mcp.Required(),
+			mcp.Description("The route index or a host matched by the route"),
+		),
+		mcp.WithArray("allowed_origins",
+			mcp.Required(),
+			mcp.Description("The allowed origins, for example https://app.example.com"),
+			mcp.Items(map[string]any{"type": "string"}),
+		),
+		mcp.WithArray("allowed_methods",
+			mcp.Description("The allowed methods (default GET, POST, PUT, PATCH, DELETE, OPTIONS)"),
+			mcp.Items(map[string]any{"type": "string"}),
+		),
+		mcp.WithArray("allowed_headers",
+			mcp.Description("The allowed request headers (default Content-Type, Authorization)"),
+			mcp.Items(map[string]any{"type": "string"}),
+		),
+		mcp.WithBoolean("allow_credentials",
+			mcp.Description("Whether to allow credentials (cookies and authorization headers)"),
+			mcp.DefaultBool(false),
+		),
+	)
+
+	// Add enable CORS tool handler
+	s.AddTool(enableCORS, enableCORSHandler)
+}
+
+// Build the subroute handler implementing CORS for the given origins
+func corsHandler(origins, methods, headers []string, allowCredentials bool) map[string]any {
+	allowOrigin := "{http.request.header.Origin}"
+	if slices.Equal(origins, []string{"*"}) {
+		allowOrigin = "*"
+	}
+
+	corsHeaders := map[string]any{
+		"Access-Control-Allow-Origin": []string{allowOrigin},
+		"Vary":                        []string{"Origin"},
+	}
+	if allowCredentials {
+		corsHeaders["Access-Control-Allow-Credentials"] = []string{"true"}
+	}
+
+	preflightHeaders := map[string]any{
+		"Access-Control-Allow-Methods": []string{strings.Join(methods, ", ")},
+		"Access-Control-Allow-Headers": []string{strings.Join(headers, ", ")},
+	}
+	for name, value := range corsHeaders {
+		preflightHeaders[name] = value
+	}
+
+	return map[string]any{
+		"handler": "subroute",
+		"routes": []any{
+			map[string]any{
+				"match": []any{
+					map[string]any{
+						"method": []string{"OPTIONS"},
+						"header": map[string]any{
+							"Origin":                        origins,
+							"Access-Control-Request-Method": []string{"*"},
+						},
+					},
+				},
+				"handle": []any{
+					map[string]any{
+						"handler":  "headers",
+						"response": map[string]any{"set": preflightHeaders},
+					},
+					map[string]any{
+						"handler":     "static_response",
+						"status_code": 204,
+					},
+				},
+				"terminal": true,
+			},
+			map[string]any{
+				"match": []any{
+					map[string]any{
+						"header": map[string]any{"Origin": origins},
+					},
+				},
+				"handle": []any{
+					map[string]any{
+						"handler":  "headers",
+						"response": map[string]any{"set": corsHeaders},
+					},
+				},
+			},
+		},
+	}
+}
+
+// Add CORS handling to a route
+func enableCORSHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	serverName, err := request.RequireString("server_name")
+	if err != nil {
+		return nil, err
+	}
+
+	selector, err := request.RequireString("route")
+	if err != nil {
+		return nil, err
+	}
+
+	origins, err := request.RequireStringSlice("allowed_origins")
+	if err != nil {
+		return nil, err
+	}
+
+	if len(origins) == 0 {
+		return nil, fmt.Errorf("at least one allowed origin is required")
+	}
+
+	if slices.Contains(origins, "*") && len(origins) > 1 {
+		return nil, fmt.Errorf(`"*" must be the only allowed origin`)
+	}
+
+	methods := request.GetStringSlice("allowed_methods", defaultCORSMethods)
+	headers := request.GetStringSlice("allowed_headers", defaultCORSHeaders)
+	allowCredentials := request.GetBool("allow_credentials", false)
+
+	if allowCredentials && slices.Contains(origins, "*") {
+		return nil, fmt.Errorf(`credentials cannot be allowed when the allowed origin is "*"`)
+	}
+
+	cfg, err := fetchConfigMap(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	srv, err := httpServer(cfg, serverName)
+	if err != nil {
+		return nil, err
+	}
+
+	index, route, err := resolveRoute(srv, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	// The subroute is named after the hosts of the route, or its index when it has none, and replaces the one of an earlier call
+	key := strings.Join(routeHosts(route), ",")
+	if key == "" {
+		key = strconv.Itoa(index)
+	}
+
+	cors := corsHandler(origins, methods, headers, allowCredentials)
+	cors["@id"] = generatedRouteID("cors", serverName, key)
+
+	handlers := []any{cors}
+	existing, _ := route["handle"].([]any)
+	for _, h := range existing {
+		handler, _ := h.(map[string]any)
+		if id, _ := handler["@id"].(string); strings.HasPrefix(id, generatedRouteID("cors", serverName, "")) {
+			continue
+		}
+		handlers = append(handlers, h)
+	}
+	route["handle"] = handlers
+
+	return applyConfigMap(ctx, cfg)
+}
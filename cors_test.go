@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const corsTestConfig = `{"apps":{"http":{"servers":{"srv0":{"listen":[":443"],"routes":[
+	{"match":[{"host":["api.example.com"]}],"handle":[{"handler":"reverse_proxy","upstreams":[{"dial":"localhost:8080"}]}]}
+]}}}}}`
+
+// Get the handlers of the first route of srv0 in the configuration loaded into a fake caddy
+func firstRouteHandlers(t *testing.T, fc *fakeCaddy) []any {
+	t.Helper()
+
+	var cfg map[string]any
+	if err := json.Unmarshal([]byte(fc.current()), &cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	srv, err := httpServer(cfg, "srv0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	route := serverRoutes(srv)[0].(map[string]any)
+	handlers, _ := route["handle"].([]any)
+	return handlers
+}
+
+func TestEnableCORS(t *testing.T) {
+	fc := newFakeCaddy(t, corsTestConfig)
+
+	_, err := callTool(enableCORSHandler, "enable_cors", map[string]any{
+		"server_name":     "srv0",
+		"route":           "api.example.com",
+		"allowed_origins": []any{"https://app.example.com"},
+	})
+	if err != nil {
+		t.Fatalf("enable_cors: %v", err)
+	}
+
+	handlers := firstRouteHandlers(t, fc)
+	if len(handlers) != 2 {
+		t.Fatalf("route has %d handlers after enable_cors, want 2", len(handlers))
+	}
+
+	cors := handlers[0].(map[string]any)
+	if cors["handler"] != "subroute" {
+		t.Errorf("first handler = %v, want the CORS subroute", cors["handler"])
+	}
+	if proxy := handlers[1].(map[string]any); proxy["handler"] != "reverse_proxy" {
+		t.Errorf("second handler = %v, want the original reverse_proxy", proxy["handler"])
+	}
+
+	// The preflight route answers OPTIONS requests itself
+	preflight := cors["routes"].([]any)[0].(map[string]any)
+	if preflight["terminal"] != true {
+		t.Errorf("preflight route is not terminal")
+	}
+}
+
+func TestEnableCORSReplacesEarlierCall(t *testing.T) {
+	fc := newFakeCaddy(t, corsTestConfig)
+
+	for _, origin := range []string{"https://app.example.com", "https://admin.example.com"} {
+		_, err := callTool(enableCORSHandler, "enable_cors", map[string]any{
+			"server_name":     "srv0",
+			"route":           "api.example.com",
+			"allowed_origins": []any{origin},
+		})
+		if err != nil {
+			t.Fatalf("enable_cors: %v", err)
+		}
+	}
+
+	handlers := firstRouteHandlers(t, fc)
+	if len(handlers) != 2 {
+		t.Fatalf("route has %d handlers after two enable_cors calls, want 2", len(handlers))
+	}
+
+	cors := handlers[0].(map[string]any)
+	if want := generatedRouteID("cors", "srv0", "api.example.com"); cors["@id"] != want {
+		t.Errorf("CORS subroute @id = %v, want %s", cors["@id"], want)
+	}
+
+	config, _ := json.Marshal(cors)
+	if !strings.Contains(string(config), "https://admin.example.com") || strings.Contains(string(config), "https://app.example.com") {
+		t.Errorf("CORS subroute = %s, want only the origin of the last call", config)
+	}
+}
+
+func TestEnableCORSRouteWithoutHosts(t *testing.T) {
+	fc := newFakeCaddy(t, `{"apps":{"http":{"servers":{"srv0":{"routes":[{"handle":[{"handler":"file_server"}]}]}}}}}`)
+
+	_, err := callTool(enableCORSHandler, "enable_cors", map[string]any{
+		"server_name":     "srv0",
+		"route":           "0",
+		"allowed_origins": []any{"*"},
+	})
+	if err != nil {
+		t.Fatalf("enable_cors: %v", err)
+	}
+
+	cors := firstRouteHandlers(t, fc)[0].(map[string]any)
+	if want := generatedRouteID("cors", "srv0", "0"); cors["@id"] != want {
+		t.Errorf("CORS subroute @id = %v, want %s", cors["@id"], want)
+	}
+}
+
+func TestEnableCORSInvalidOrigins(t *testing.T) {
+	newFakeCaddy(t, corsTestConfig)
+
+	tests := []struct {
+		name string
+		args map[string]any
+	}{
+		{
+			name: "no origin",
+			args: map[string]any{"allowed_origins": []any{}},
+		},
+		{
+			name: "wildcard with another origin",
+			args: map[string]any{"allowed_origins": []any{"*", "https://app.example.com"}},
+		},
+		{
+			name: "wildcard with credentials",
+			args: map[string]any{"allowed_origins": []any{"*"}, "allow_credentials": true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.args["server_name"] = "srv0"
+			tt.args["route"] = "0"
+
+			if _, err := callTool(enableCORSHandler, "enable_cors", tt.args); err == nil {
+				t.Errorf("enable_cors returned no error")
+			}
+		})
+	}
+}
+
+func TestCORSHandlerWildcardOrigin(t *testing.T) {
+	handler := corsHandler([]string{"*"}, defaultCORSMethods, defaultCORSHeaders, false)
+
+	route := handler["routes"].([]any)[1].(map[string]any)
+	headers := route["handle"].([]any)[0].(map[string]any)["response"].(map[string]any)["set"].(map[string]any)
+
+	if got := headers["Access-Control-Allow-Origin"].([]string)[0]; got != "*" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want *", got)
+	}
+	if _, ok := headers["Access-Control-Allow-Credentials"]; ok {
+		t.Errorf("Access-Control-Allow-Credentials is set without allow_credentials")
+	}
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"runtime"
+	"runtime/debug"
+	"syscall"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+type diagnosisStep struct {
+	Name     string `json:"name"`
+	OK       bool   `json:"ok"`
+	Skipped  bool   `json:"skipped,omitempty"`
+	Detail   string `json:"detail,omitempty"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration,omitempty"`
+}
+
+type connectionDiagnosis struct {
+	URL        string          `json:"url"`
+	Steps      []diagnosisStep `json:"steps"`
+	Conclusion string          `json:"conclusion"`
+}
+
+type pingResult struct {
+	Reachable  bool   `json:"reachable"`
+	Latency    string `json:"latency"`
+	StatusCode int    `json:"status_code,omitempty"`
+	ErrorKind  string `json:"error_kind,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+type versionInfo struct {
+	CaddyMCPVersion  string `json:"caddy_mcp_version"`
+	CaddyMCPRevision string `json:"caddy_mcp_revision,omitempty"`
+	CaddyVersion     string `json:"caddy_version"`
+	GoVersion        string `json:"go_version"`
+	Note             string `json:"note"`
+}
+
+func registerDiagnosticTools(s *server.MCPServer) {
+	caddyPing := mcp.NewTool("caddy_ping",
+		mcp.WithDescription(`
+		Use the caddy_ping tool to quickly check that the caddy admin API is reachable, for example before making changes.
+
+		The result is a JSON document telling whether the admin API answered, the latency of the request and, if it did not, the kind of error: connection_refused, timeout, dns, tls, status (caddy answered with an unexpected status) or request_failed.
+
+		Notes:
+			The ping is a single GET of /config/, which does not change anything. Failed pings are not retried.
+			Use the diagnose_connection tool for a phase by phase analysis when the ping fails.
+		`),
+	)
+
+	// Add Caddy ping tool handler
+	s.AddTool(caddyPing, caddyPingHandler)
+
+	diagnoseConnection := mcp.NewTool("diagnose_connection",
+		mcp.WithDescription(`
+		Use the diagnose_connection tool to troubleshoot the connection between this MCP server and the caddy admin API.
+
+		The result is a JSON document describing each phase of the connection (DNS resolution, TCP connect, TLS handshake for https URLs and the HTTP request) with the time each phase took and a conclusion.
+
+		Notes:
+			Use this tool when other tools fail to reach the caddy server to find out whether the problem is networking, TLS or authentication.
+		`),
+	)
+
+	// Add diagnose connection tool handler
+	s.AddTool(diagnoseConnection, diagnoseConnectionHandler)
+
+	caddyVersion := mcp.NewTool("caddy_version",
+		mcp.WithDescription(`
+		Use the caddy_version tool to get the versions of caddy-mcp and of the caddy it was built with, for example when a configuration adapted by this tool is rejected by the caddy server.
+
+		The result is a JSON document with the caddy-mcp version and revision, the version of the caddy module caddy-mcp was compiled against and the Go version.
+
+		Notes:
+			The caddy admin API does not expose the version of the running caddy server, so the caddy version is the one caddy-mcp uses to validate and adapt configurations. Run caddy version on the caddy host to compare it with the running server.
+			A different caddy version can adapt a Caddyfile differently; use the remote_adapt_config tool to adapt with the running server instead.
+		`),
+	)
+
+	// Add Caddy version tool handler
+	s.AddTool(caddyVersion, caddyVersionHandler)
+}
+
+// Diagnose the connection to the Caddy admin API phase by phase
+func diagnoseConnectionHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	diagnosis := diagnoseConnection(ctx, adminURL(ctx))
+
+	data, err := json.Marshal(diagnosis)
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+// Check that the admin API answers and classify the failure when it does not
+func caddyPingHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// A single attempt, so the latency does not include the backoff of -retries
+	start := time.Now()
+	status, _, body, err := adminAttempt(ctx, http.MethodGet, "/config/", nil, nil)
+
+	result := pingResult{
+		Latency:    time.Since(start).Round(time.Microsecond).String(),
+		StatusCode: status,
+	}
+
+	var (
+		netErr net.Error
+		dnsErr *net.DNSError
+		tlsErr *tls.CertificateVerificationError
+	)
+	switch {
+	case err == nil && status == http.StatusOK:
+		result.Reachable = true
+	case err == nil:
+		result.ErrorKind = "status"
+		result.Error = fmt.Sprintf("caddy returned status %d: %s", status, body)
+	case errors.Is(err, syscall.ECONNREFUSED):
+		result.ErrorKind = "connection_refused"
+	case errors.As(err, &dnsErr):
+		result.ErrorKind = "dns"
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		result.ErrorKind = "timeout"
+	case errors.As(err, &tlsErr):
+		result.ErrorKind = "tls"
+	default:
+		result.ErrorKind = "request_failed"
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+// Run each connection phase against the admin URL and stop at the first failure
+func diagnoseConnection(ctx context.Context, adminURL string) *connectionDiagnosis {
+	diagnosis := &connectionDiagnosis{URL: adminURL}
+
+	u, err := url.Parse(adminURL)
+	if err != nil || u.Host == "" {
+		diagnosis.Steps = append(diagnosis.Steps, diagnosisStep{
+			Name:  "parse_url",
+			Error: fmt.Sprintf("invalid admin URL %q", adminURL),
+		})
+		diagnosis.Conclusion = "The admin URL is invalid. It must look like http://host:port."
+		return diagnosis
+	}
+
+	host := u.Hostname()
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	// DNS resolution
+	start := time.Now()
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	step := diagnosisStep{Name: "dns", Duration: time.Since(start).String()}
+	if err != nil {
+		step.Error = err.Error()
+		diagnosis.Steps = append(diagnosis.Steps, step)
+		diagnosis.Conclusion = fmt.Sprintf("The host %q could not be resolved. Check the -url flag and DNS configuration.", host)
+		return diagnosis
+	}
+	step.OK = true
+	step.Detail = fmt.Sprintf("resolved to %v", addrs)
+	diagnosis.Steps = append(diagnosis.Steps, step)
+
+	// TCP connect
+	dialer := &net.Dialer{Timeout: client.Timeout}
+	address := net.JoinHostPort(host, port)
+	start = time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	step = diagnosisStep{Name: "tcp_connect", Duration: time.Since(start).String()}
+	if err != nil {
+		step.Error = err.Error()
+		diagnosis.Steps = append(diagnosis.Steps, step)
+		diagnosis.Conclusion = fmt.Sprintf("Could not open a TCP connection to %s. Check that caddy is running and that the admin listener is bound to this address.", address)
+		return diagnosis
+	}
+	step.OK = true
+	step.Detail = fmt.Sprintf("connected to %s", conn.RemoteAddr())
+	diagnosis.Steps = append(diagnosis.Steps, step)
+
+	// TLS handshake
+	if u.Scheme == "https" {
+		// Handshake with the -admin-* settings the admin API client uses
+		tlsConfig, err := adminTLSConfig()
+		if err != nil {
+			conn.Close()
+			diagnosis.Steps = append(diagnosis.Steps, diagnosisStep{Name: "tls_handshake", Error: err.Error()})
+			diagnosis.Conclusion = "The admin TLS settings could not be loaded. Check the -admin-cert, -admin-key and -admin-ca files."
+			return diagnosis
+		}
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{}
+		} else {
+			tlsConfig = tlsConfig.Clone()
+		}
+		tlsConfig.ServerName = host
+
+		tlsConn := tls.Client(conn, tlsConfig)
+		start = time.Now()
+		err = tlsConn.HandshakeContext(ctx)
+		step = diagnosisStep{Name: "tls_handshake", Duration: time.Since(start).String()}
+		if err != nil {
+			conn.Close()
+			step.Error = err.Error()
+			diagnosis.Steps = append(diagnosis.Steps, step)
+			diagnosis.Conclusion = "The TLS handshake with the admin endpoint failed. Check the server certificate and TLS settings."
+			return diagnosis
+		}
+		state := tlsConn.ConnectionState()
+		step.OK = true
+		step.Detail = fmt.Sprintf("%s with %s", tls.VersionName(state.Version), tls.CipherSuiteName(state.CipherSuite))
+		diagnosis.Steps = append(diagnosis.Steps, step)
+		tlsConn.Close()
+	} else {
+		conn.Close()
+		diagnosis.Steps = append(diagnosis.Steps, diagnosisStep{
+			Name:    "tls_handshake",
+			Skipped: true,
+			Detail:  "admin URL does not use https",
+		})
+	}
+
+	// HTTP request
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/config/", adminURL), nil)
+	if err != nil {
+		diagnosis.Steps = append(diagnosis.Steps, diagnosisStep{Name: "http_request", Error: err.Error()})
+		diagnosis.Conclusion = "Could not build the HTTP request for the admin API."
+		return diagnosis
+	}
+
+	start = time.Now()
+	resp, err := adminDo(req)
+	step = diagnosisStep{Name: "http_request", Duration: time.Since(start).String()}
+	if err != nil {
+		step.Error = err.Error()
+		diagnosis.Steps = append(diagnosis.Steps, step)
+		diagnosis.Conclusion = "The connection succeeded but the HTTP request to the admin API failed."
+		return diagnosis
+	}
+	resp.Body.Close()
+	step.Detail = resp.Status
+	diagnosis.Steps = append(diagnosis.Steps, step)
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		diagnosis.Steps[len(diagnosis.Steps)-1].OK = true
+		diagnosis.Conclusion = "The caddy admin API is reachable and responding."
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		diagnosis.Conclusion = "The admin API rejected the request. Authentication or origin enforcement is blocking access."
+	default:
+		diagnosis.Conclusion = fmt.Sprintf("The admin API responded with an unexpected status: %s.", resp.Status)
+	}
+
+	return diagnosis
+}
+
+// Report the versions caddy-mcp was built with
+func caddyVersionHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	result := versionInfo{
+		CaddyMCPVersion: caddyMCPVersion,
+		CaddyVersion:    "unknown",
+		GoVersion:       runtime.Version(),
+		Note:            "the caddy admin API does not expose the running server's version; run caddy version on the caddy host to compare",
+	}
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range info.Deps {
+			if dep.Path != "github.com/caddyserver/caddy/v2" {
+				continue
+			}
+			result.CaddyVersion = dep.Version
+			if dep.Replace != nil {
+				result.CaddyVersion = fmt.Sprintf("%s => %s %s", dep.Version, dep.Replace.Path, dep.Replace.Version)
+			}
+		}
+
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" {
+				result.CaddyMCPRevision = setting.Value
+			}
+		}
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
+}
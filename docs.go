@@ -0,0 +1,199 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func registerDocsTools(s *server.MCPServer) {
+	generateConfigDocs := mcp.NewTool("generate_config_docs",
+		mcp.WithDescription(`
+		Use the generate_config_docs tool to generate a human readable Markdown document describing the current caddy server configuration.
+
+		The document has a section per server with its listen addresses, a table of its routes with their matchers and handlers, the configured upstreams with their load balancing and health check settings, and the TLS automation policies.
+		`),
+	)
+
+	// Add generate config docs tool handler
+	s.AddTool(generateConfigDocs, generateConfigDocsHandler)
+}
+
+// Escape a value for use inside a Markdown table cell
+func markdownCell(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return strings.ReplaceAll(value, "|", `\|`)
+}
+
+// Join the string values of a decoded JSON array
+func joinStrings(value any, sep string) string {
+	values, _ := value.([]any)
+	parts := make([]string, 0, len(values))
+	for _, v := range values {
+		parts = append(parts, fmt.Sprint(v))
+	}
+	return strings.Join(parts, sep)
+}
+
+// Describe the health check settings of a reverse_proxy handler
+func describeHealthChecks(handler map[string]any) (string, string) {
+	active, passive := "", ""
+
+	healthChecks, _ := handler["health_checks"].(map[string]any)
+	if a, ok := healthChecks["active"].(map[string]any); ok {
+		uri, _ := a["uri"].(string)
+		if uri == "" {
+			uri, _ = a["path"].(string)
+		}
+		active = fmt.Sprintf("uri %s", cmp.Or(uri, "/"))
+		if interval, ok := a["interval"]; ok {
+			active += fmt.Sprintf(" every %v", formatDuration(interval))
+		}
+	}
+	if p, ok := healthChecks["passive"].(map[string]any); ok {
+		var parts []string
+		if maxFails, ok := p["max_fails"]; ok {
+			parts = append(parts, fmt.Sprintf("max_fails %v", maxFails))
+		}
+		if failDuration, ok := p["fail_duration"]; ok {
+			parts = append(parts, fmt.Sprintf("fail_duration %v", formatDuration(failDuration)))
+		}
+		passive = cmp.Or(strings.Join(parts, ", "), "enabled")
+	}
+
+	return active, passive
+}
+
+// Format a Caddy duration, which is either a string or a number of nanoseconds
+func formatDuration(value any) string {
+	if n, ok := value.(float64); ok {
+		return fmt.Sprint(time.Duration(n))
+	}
+	return fmt.Sprint(value)
+}
+
+// Generate Markdown documentation for the current Caddy configuration
+func generateConfigDocsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	cfg, err := fetchConfigMap(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	b.WriteString("# Caddy configuration\n")
+
+	names := httpServerNames(cfg)
+	if len(names) == 0 {
+		b.WriteString("\nNo HTTP servers are configured.\n")
+	}
+
+	for _, name := range names {
+		srv, err := httpServer(cfg, name)
+		if err != nil {
+			return nil, err
+		}
+
+		fmt.Fprintf(&b, "\n## Server `%s`\n\n", name)
+		fmt.Fprintf(&b, "Listen addresses: %s\n", markdownCell(joinStrings(srv["listen"], ", ")))
+
+		routes := serverRoutes(srv)
+		b.WriteString("\n### Routes\n\n")
+		if len(routes) == 0 {
+			b.WriteString("No routes.\n")
+		} else {
+			b.WriteString("| # | Matchers | Handlers | Terminal |\n|---|---|---|---|\n")
+			for i, r := range routes {
+				route, ok := r.(map[string]any)
+				if !ok {
+					continue
+				}
+				terminal := "no"
+				if route["terminal"] == true {
+					terminal = "yes"
+				}
+				fmt.Fprintf(&b, "| %d | %s | %s | %s |\n", i,
+					markdownCell(describeMatchers(route)),
+					markdownCell(strings.Join(routeHandlers(route), ", ")),
+					terminal)
+			}
+		}
+
+		var upstreamRows []string
+		walkHandlers(routes, func(handler map[string]any) {
+			if handler["handler"] != "reverse_proxy" {
+				return
+			}
+
+			policy := "random"
+			if lb, ok := handler["load_balancing"].(map[string]any); ok {
+				if selection, ok := lb["selection_policy"].(map[string]any); ok {
+					name, _ := selection["policy"].(string)
+					policy = cmp.Or(name, policy)
+				}
+			}
+			active, passive := describeHealthChecks(handler)
+
+			upstreams := proxyUpstreams(handler)
+			if dynamic, ok := handler["dynamic_upstreams"].(map[string]any); ok {
+				upstreams = append(upstreams, fmt.Sprintf("dynamic (%v)", dynamic["source"]))
+			}
+
+			for _, upstream := range upstreams {
+				upstreamRows = append(upstreamRows, fmt.Sprintf("| %s | %s | %s | %s |\n",
+					markdownCell(upstream), markdownCell(policy), markdownCell(active), markdownCell(passive)))
+			}
+		})
+
+		if len(upstreamRows) > 0 {
+			b.WriteString("\n### Upstreams\n\n")
+			b.WriteString("| Upstream | Load balancing | Active health checks | Passive health checks |\n|---|---|---|---|\n")
+			for _, row := range upstreamRows {
+				b.WriteString(row)
+			}
+		}
+	}
+
+	b.WriteString("\n## TLS automation\n\n")
+	var policies []any
+	if automation, err := configObject(cfg, false, "apps", "tls", "automation"); err == nil {
+		policies, _ = automation["policies"].([]any)
+	}
+	if len(policies) == 0 {
+		b.WriteString("Default automation policy: certificates are managed for all qualifying host names using the default issuers.\n")
+	} else {
+		b.WriteString("| # | Subjects | Issuers | On-demand |\n|---|---|---|---|\n")
+		for i, p := range policies {
+			policy, ok := p.(map[string]any)
+			if !ok {
+				continue
+			}
+
+			var issuers []string
+			issuerList, _ := policy["issuers"].([]any)
+			for _, is := range issuerList {
+				if issuer, ok := is.(map[string]any); ok {
+					issuers = append(issuers, fmt.Sprint(issuer["module"]))
+				}
+			}
+
+			onDemand := "no"
+			if policy["on_demand"] == true {
+				onDemand = "yes"
+			}
+
+			fmt.Fprintf(&b, "| %d | %s | %s | %s |\n", i,
+				markdownCell(cmp.Or(joinStrings(policy["subjects"], ", "), "all other names")),
+				markdownCell(cmp.Or(strings.Join(issuers, ", "), "default")),
+				onDemand)
+		}
+	}
+
+	return mcp.NewToolResultText(b.String()), nil
+}
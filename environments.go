@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+var environmentNameRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+type environmentInfo struct {
+	Name     string `json:"name"`
+	Size     int64  `json:"size"`
+	Modified string `json:"modified"`
+}
+
+func registerEnvironmentTools(s *server.MCPServer) {
+	saveEnvironment := mcp.NewTool("save_environment",
+		mcp.WithDescription(`
+		Use the save_environment tool to save a full caddy JSON configuration as a named environment (for example "dev", "staging" or "maint").
+
+		Notes:
+			If json_config is not provided the current caddy server configuration is saved.
+			Saving an environment with an existing name overwrites it.
+			Use the load_environment tool to apply a saved environment.
+		`),
+		mcp.WithString("name",
+			mcp.Required(),
+			mcp.Description("The name of the environment (letters, numbers, dashes and underscores)"),
+		),
+		mcp.WithString("json_config",
+			mcp.Description("The full caddy JSON configuration to save instead of the current configuration"),
+		),
+	)
+
+	// Add save environment tool handler
+	s.AddTool(saveEnvironment, saveEnvironmentHandler)
+
+	loadEnvironment := mcp.NewTool("load_environment",
+		mcp.WithDescription(`
+		Use the load_environment tool to apply a previously saved environment to the caddy server.
+
+		Notes:
+			The saved configuration is validated before it is applied and replaces the full caddy server configuration.
+			Use the list_environments tool to see which environments are available.
+		`),
+		mcp.WithString("name",
+			mcp.Required(),
+			mcp.Description("The name of the environment to apply"),
+		),
+	)
+
+	// Add load environment tool handler
+	s.AddTool(loadEnvironment, loadEnvironmentHandler)
+
+	listEnvironments := mcp.NewTool("list_environments",
+		mcp.WithDescription("List the saved environments with their size and last modified time as a JSON document."),
+	)
+
+	// Add list environments tool handler
+	s.AddTool(listEnvironments, listEnvironmentsHandler)
+}
+
+// Build the path of the file backing an environment
+func environmentPath(name string) (string, error) {
+	if envDir == "" {
+		return "", fmt.Errorf("environments are disabled; start the MCP server with -env-dir")
+	}
+
+	if !environmentNameRegexp.MatchString(name) {
+		return "", fmt.Errorf("invalid environment name %q: only letters, numbers, dashes and underscores are allowed", name)
+	}
+
+	return filepath.Join(envDir, name+".json"), nil
+}
+
+// Save a full configuration as a named environment
+func saveEnvironmentHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	name, err := request.RequireString("name")
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := environmentPath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	config := []byte(request.GetString("json_config", ""))
+	if len(config) == 0 {
+		config, err = fetchConfig(ctx)
+		if err != nil {
+			return nil, err
+		}
+	} else if !json.Valid(config) {
+		return nil, fmt.Errorf("json_config is not valid JSON")
+	}
+
+	if err := os.MkdirAll(envDir, 0o750); err != nil {
+		return nil, err
+	}
+
+	if err := os.WriteFile(path, config, 0o600); err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(fmt.Sprintf("Saved environment %q to %s", name, path)), nil
+}
+
+// Validate and apply a saved environment
+func loadEnvironmentHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	name, err := request.RequireString("name")
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := environmentPath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("environment %q does not exist", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateConfig(config); err != nil {
+		return nil, fmt.Errorf("environment %q is not valid: %v", name, err)
+	}
+
+	if _, err := loadConfig(ctx, config); err != nil {
+		return caddyErrorResult(err)
+	}
+
+	return mcp.NewToolResultText(fmt.Sprintf("Applied environment %q", name)), nil
+}
+
+// List the saved environments
+func listEnvironmentsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if envDir == "" {
+		return nil, fmt.Errorf("environments are disabled; start the MCP server with -env-dir")
+	}
+
+	entries, err := os.ReadDir(envDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	environments := []environmentInfo{}
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || entry.IsDir() || !environmentNameRegexp.MatchString(name) {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+
+		environments = append(environments, environmentInfo{
+			Name:     name,
+			Size:     info.Size(),
+			Modified: info.ModTime().Format(time.RFC3339),
+		})
+	}
+
+	sort.Slice(environments, func(i, j int) bool {
+		return environments[i].Name < environments[j].Name
+	})
+
+	data, err := json.Marshal(environments)
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
+}
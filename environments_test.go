@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+)
+
+// Point -env-dir at a temporary directory for the duration of a test
+func useEnvDir(t *testing.T) string {
+	t.Helper()
+
+	oldDir := envDir
+	envDir = t.TempDir()
+	t.Cleanup(func() { envDir = oldDir })
+
+	return envDir
+}
+
+func TestEnvironmentPath(t *testing.T) {
+	dir := useEnvDir(t)
+
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "staging", want: filepath.Join(dir, "staging.json")},
+		{name: "maint_2-b", want: filepath.Join(dir, "maint_2-b.json")},
+		{name: "", wantErr: true},
+		{name: "../etc/passwd", wantErr: true},
+		{name: "a/b", wantErr: true},
+		{name: "prod.json", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := environmentPath(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("environmentPath(%q) error = %v, want error %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("environmentPath(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEnvironmentPathDisabled(t *testing.T) {
+	oldDir := envDir
+	envDir = ""
+	defer func() { envDir = oldDir }()
+
+	if _, err := environmentPath("staging"); err == nil {
+		t.Errorf("environmentPath() without -env-dir returned no error")
+	}
+}
+
+func TestSaveAndLoadEnvironment(t *testing.T) {
+	useEnvDir(t)
+	fc := newFakeCaddy(t, `{"apps":{"http":{"servers":{}}}}`)
+
+	// Saving without json_config saves the current configuration
+	if _, err := callTool(saveEnvironmentHandler, "save_environment", map[string]any{"name": "prod"}); err != nil {
+		t.Fatalf("save_environment: %v", err)
+	}
+	if _, err := callTool(saveEnvironmentHandler, "save_environment", map[string]any{"name": "maint", "json_config": `{"apps":{}}`}); err != nil {
+		t.Fatalf("save_environment: %v", err)
+	}
+
+	if _, err := callTool(loadEnvironmentHandler, "load_environment", map[string]any{"name": "maint"}); err != nil {
+		t.Fatalf("load_environment: %v", err)
+	}
+	if got := fc.current(); got != `{"apps":{}}` {
+		t.Errorf("configuration after loading maint = %s, want {\"apps\":{}}", got)
+	}
+
+	if _, err := callTool(loadEnvironmentHandler, "load_environment", map[string]any{"name": "missing"}); err == nil {
+		t.Errorf("load_environment of a missing environment returned no error")
+	}
+
+	result, err := callTool(listEnvironmentsHandler, "list_environments", nil)
+	if err != nil {
+		t.Fatalf("list_environments: %v", err)
+	}
+
+	var environments []environmentInfo
+	if err := json.Unmarshal([]byte(resultText(t, result)), &environments); err != nil {
+		t.Fatal(err)
+	}
+	if len(environments) != 2 || environments[0].Name != "maint" || environments[1].Name != "prod" {
+		t.Errorf("list_environments = %+v, want maint and prod", environments)
+	}
+}
+
+func TestSaveEnvironmentInvalidJSON(t *testing.T) {
+	useEnvDir(t)
+
+	if _, err := callTool(saveEnvironmentHandler, "save_environment", map[string]any{"name": "broken", "json_config": `{"apps":`}); err == nil {
+		t.Errorf("save_environment with invalid JSON returned no error")
+	}
+}
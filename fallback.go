@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func registerFallbackTools(s *server.MCPServer) {
+	setFallbackHandler := mcp.NewTool("set_fallback_handler",
+		mcp.WithDescription(`
+		Use the set_fallback_handler tool to set the default response for requests that match no other route of a server.
+
+		A catch-all route is appended as the last route of the server so it never shadows more specific routes. Calling the tool again replaces the previous fallback route.
+		The updated configuration is applied to the caddy server and returned in JSON format.
+
+		Notes:
+			Either respond with a status code and optional body, or serve files from a root directory by providing root.
+		`),
+		mcp.WithString("server_name",
+			mcp.Required(),
+			mcp.Description("The name of the server in apps/http/servers"),
+		),
+		mcp.WithNumber("status",
+			mcp.Description("The HTTP status code of the response (default 404)"),
+			mcp.DefaultNumber(404),
+		),
+		mcp.WithString("body",
+			mcp.Description("The body of the response"),
+		),
+		mcp.WithString("root",
+			mcp.Description("An absolute directory to serve files from instead of a static response"),
+		),
+	)
+
+	// Add set fallback handler tool handler
+	s.AddTool(setFallbackHandler, setFallbackHandlerHandler)
+}
+
+// The @id of the fallback route of a server
+func fallbackRouteID(serverName string) string {
+	return fmt.Sprintf("caddy_mcp_fallback_%s", serverName)
+}
+
+// Append a catch-all route to a server
+func setFallbackHandlerHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	serverName, err := request.RequireString("server_name")
+	if err != nil {
+		return nil, err
+	}
+
+	status := request.GetInt("status", 404)
+	if status < 100 || status > 599 {
+		return nil, fmt.Errorf("invalid status code: %d", status)
+	}
+
+	body := request.GetString("body", "")
+	root := request.GetString("root", "")
+	if root != "" && !filepath.IsAbs(root) {
+		return nil, fmt.Errorf("root must be an absolute path: %s", root)
+	}
+
+	cfg, err := fetchConfigMap(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	srv, err := httpServer(cfg, serverName)
+	if err != nil {
+		return nil, err
+	}
+
+	var handlers []any
+	if root != "" {
+		handlers = []any{
+			map[string]any{
+				"handler": "file_server",
+				"root":    root,
+			},
+		}
+	} else {
+		response := map[string]any{
+			"handler":     "static_response",
+			"status_code": status,
+		}
+		if body != "" {
+			response["body"] = body
+		}
+		handlers = []any{response}
+	}
+
+	id := fallbackRouteID(serverName)
+
+	routes := []any{}
+	for _, r := range serverRoutes(srv) {
+		if route, ok := r.(map[string]any); ok && route["@id"] == id {
+			continue
+		}
+		routes = append(routes, r)
+	}
+
+	srv["routes"] = append(routes, map[string]any{
+		"@id":      id,
+		"handle":   handlers,
+		"terminal": true,
+	})
+
+	return applyConfigMap(ctx, cfg)
+}
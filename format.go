@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func registerFormatTools(s *server.MCPServer) {
+	formatCaddyJSON := mcp.NewTool("format_caddy_json",
+		mcp.WithDescription(`
+		Use the format_caddy_json tool to reformat caddy JSON, either indented for reading or minified for storage and tool arguments.
+
+		The result is the reformatted JSON. Keys keep their order and values are not changed.
+
+		Notes:
+			Any JSON value is accepted, not only full configurations, so it also works on routes or handlers.
+			Invalid JSON is rejected with the byte offset of the syntax error.
+		`),
+		mcp.WithString("json",
+			mcp.Required(),
+			mcp.Description("The JSON to reformat"),
+		),
+		mcp.WithString("mode",
+			mcp.Description("pretty to indent the JSON or minified to remove all whitespace"),
+			mcp.Enum("pretty", "minified"),
+			mcp.DefaultString("pretty"),
+		),
+	)
+
+	// Add format Caddy JSON tool handler
+	s.AddTool(formatCaddyJSON, formatCaddyJSONHandler)
+}
+
+// Indent or compact a JSON document
+func formatCaddyJSONHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	input, err := request.RequireString("json")
+	if err != nil {
+		return nil, err
+	}
+
+	var value any
+	if err := json.Unmarshal([]byte(input), &value); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return nil, fmt.Errorf("invalid JSON at byte offset %d: %v", syntaxErr.Offset, err)
+		}
+		return nil, fmt.Errorf("invalid JSON: %v", err)
+	}
+
+	var out bytes.Buffer
+	switch mode := request.GetString("mode", "pretty"); mode {
+	case "pretty":
+		err = json.Indent(&out, []byte(input), "", "  ")
+	case "minified":
+		err = json.Compact(&out, []byte(input))
+	default:
+		return nil, fmt.Errorf("unsupported mode: %s", mode)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(out.String()), nil
+}
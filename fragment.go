@@ -0,0 +1,339 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Handlers that write the response; other handlers are middleware that must come before them
+var responderHandlers = []string{"reverse_proxy", "file_server", "static_response", "error", "php_fastcgi"}
+
+type fragmentResult struct {
+	Applied bool            `json:"applied"`
+	Kind    string          `json:"kind"`
+	Actions []string        `json:"actions"`
+	Config  json.RawMessage `json:"config"`
+}
+
+func registerFragmentTools(s *server.MCPServer) {
+	smartApplyFragment := mcp.NewTool("smart_apply_fragment",
+		mcp.WithDescription(`
+		Use the smart_apply_fragment tool to merge a piece of caddy JSON configuration into the current configuration without working out its exact config path.
+
+		The kind of fragment is detected and it is placed where it belongs:
+			A handler, or a list of handlers, goes into the route matching host. A handler of the same type is replaced; otherwise middleware is inserted before the handler writing the response and responders are appended. When no route matches host, a new route for host is created.
+			A route, or a list of routes, is added to the server before its fallback route, with a host matcher for host when it has no matchers.
+			A server, recognized by its listen addresses, is stored as server_name.
+
+		The result is a JSON document describing what was done, with the config paths, and the resulting configuration.
+
+		Notes:
+			The configuration is only applied to the caddy server when apply is true. Review the actions first.
+			server_name can be omitted when the configuration has a single server or, for handlers, when only one server has a route for host.
+		`),
+		mcp.WithString("fragment",
+			mcp.Required(),
+			mcp.Description("The JSON fragment: a handler, a route, a list of handlers or routes, or a server"),
+		),
+		mcp.WithString("host",
+			mcp.Description("The host the fragment is for, for example example.com"),
+		),
+		mcp.WithString("server_name",
+			mcp.Description("The name of the server in apps/http/servers"),
+		),
+		mcp.WithBoolean("apply",
+			mcp.Description("Whether to apply the resulting configuration to the caddy server"),
+			mcp.DefaultBool(false),
+		),
+	)
+
+	// Add smart apply fragment tool handler
+	s.AddTool(smartApplyFragment, smartApplyFragmentHandler)
+}
+
+// Detect whether a fragment is a handler, route or server
+func fragmentKind(value any) (string, []map[string]any, error) {
+	var objects []map[string]any
+	switch v := value.(type) {
+	case map[string]any:
+		objects = []map[string]any{v}
+	case []any:
+		for _, item := range v {
+			obj, ok := item.(map[string]any)
+			if !ok {
+				return "", nil, fmt.Errorf("list fragments must only contain objects")
+			}
+			objects = append(objects, obj)
+		}
+	default:
+		return "", nil, fmt.Errorf("the fragment must be a JSON object or a list of objects")
+	}
+
+	if len(objects) == 0 {
+		return "", nil, fmt.Errorf("the fragment is empty")
+	}
+
+	kind := ""
+	for _, obj := range objects {
+		objKind := ""
+		switch {
+		case obj["handler"] != nil:
+			objKind = "handler"
+		case obj["handle"] != nil || obj["match"] != nil:
+			objKind = "route"
+		case obj["listen"] != nil:
+			objKind = "server"
+		default:
+			return "", nil, fmt.Errorf("cannot tell what the fragment is: expected a handler (with \"handler\"), a route (with \"handle\" or \"match\") or a server (with \"listen\")")
+		}
+
+		if kind != "" && objKind != kind {
+			return "", nil, fmt.Errorf("list fragments must contain a single kind of object")
+		}
+		kind = objKind
+	}
+
+	if kind == "server" && len(objects) > 1 {
+		return "", nil, fmt.Errorf("only one server can be applied at a time")
+	}
+
+	return kind, objects, nil
+}
+
+// Find the server and route answering for a host
+func findHostRoute(cfg map[string]any, serverName, host string) (string, int, map[string]any, error) {
+	names := httpServerNames(cfg)
+	if serverName != "" {
+		names = []string{serverName}
+	}
+
+	var found []string
+	var foundIndex int
+	var foundRoute map[string]any
+	for _, name := range names {
+		srv, err := httpServer(cfg, name)
+		if err != nil {
+			return "", 0, nil, err
+		}
+
+		if !slices.ContainsFunc(serverRoutes(srv), func(r any) bool {
+			route, _ := r.(map[string]any)
+			return slices.Contains(routeHosts(route), host)
+		}) {
+			continue
+		}
+
+		index, route, err := resolveRoute(srv, host)
+		if err != nil {
+			return "", 0, nil, err
+		}
+
+		found = append(found, name)
+		foundIndex, foundRoute = index, route
+	}
+
+	switch len(found) {
+	case 0:
+		return "", 0, nil, nil
+	case 1:
+		return found[0], foundIndex, foundRoute, nil
+	default:
+		return "", 0, nil, fmt.Errorf("host %q has routes on servers %v; set server_name", host, found)
+	}
+}
+
+// Merge a handler into a list of handlers, returning the action taken
+func mergeHandler(handlers []any, handler map[string]any, path string) ([]any, string) {
+	name, _ := handler["handler"].(string)
+
+	for i, h := range handlers {
+		existing, _ := h.(map[string]any)
+		if existing["handler"] == name {
+			handlers[i] = handler
+			return handlers, fmt.Sprintf("replaced the %s handler at %s", name, joinConfigPath(path, strconv.Itoa(i)))
+		}
+	}
+
+	if slices.Contains(responderHandlers, name) {
+		return append(handlers, handler), fmt.Sprintf("appended the %s handler at %s", name, joinConfigPath(path, strconv.Itoa(len(handlers))))
+	}
+
+	for i, h := range handlers {
+		existing, _ := h.(map[string]any)
+		if responder, _ := existing["handler"].(string); slices.Contains(responderHandlers, responder) {
+			return slices.Insert(handlers, i, any(handler)), fmt.Sprintf("inserted the %s handler at %s, before the %s handler", name, joinConfigPath(path, strconv.Itoa(i)), responder)
+		}
+	}
+
+	return append(handlers, handler), fmt.Sprintf("appended the %s handler at %s", name, joinConfigPath(path, strconv.Itoa(len(handlers))))
+}
+
+// Merge a handler into a route, looking inside the route when it only holds a subroute
+func mergeRouteHandler(route map[string]any, handler map[string]any, path string) string {
+	handlers, _ := route["handle"].([]any)
+
+	if len(handlers) == 1 {
+		if subroute, ok := handlers[0].(map[string]any); ok && subroute["handler"] == "subroute" {
+			routesPath := joinConfigPath(path, "handle/0/routes")
+			routes, _ := subroute["routes"].([]any)
+			name, _ := handler["handler"].(string)
+
+			for i, r := range routes {
+				inner, _ := r.(map[string]any)
+				innerHandlers, _ := inner["handle"].([]any)
+				if slices.ContainsFunc(innerHandlers, func(h any) bool {
+					existing, _ := h.(map[string]any)
+					return existing["handler"] == name
+				}) {
+					var action string
+					inner["handle"], action = mergeHandler(innerHandlers, handler, joinConfigPath(joinConfigPath(routesPath, strconv.Itoa(i)), "handle"))
+					return action
+				}
+			}
+
+			newRoute := map[string]any{"handle": []any{handler}}
+			if slices.Contains(responderHandlers, name) {
+				subroute["routes"] = append(routes, newRoute)
+				return fmt.Sprintf("appended a route with the %s handler at %s", name, joinConfigPath(routesPath, strconv.Itoa(len(routes))))
+			}
+			subroute["routes"] = append([]any{newRoute}, routes...)
+			return fmt.Sprintf("inserted a route with the %s handler at %s", name, joinConfigPath(routesPath, "0"))
+		}
+	}
+
+	var action string
+	route["handle"], action = mergeHandler(handlers, handler, joinConfigPath(path, "handle"))
+	return action
+}
+
+// Merge a fragment into the current configuration at the place it belongs
+func smartApplyFragmentHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	data, err := request.RequireString("fragment")
+	if err != nil {
+		return nil, err
+	}
+
+	var value any
+	if err := json.Unmarshal([]byte(data), &value); err != nil {
+		return nil, fmt.Errorf("invalid JSON fragment: %v", err)
+	}
+
+	kind, objects, err := fragmentKind(value)
+	if err != nil {
+		return nil, err
+	}
+
+	host := request.GetString("host", "")
+	serverName := request.GetString("server_name", "")
+
+	cfg, err := fetchConfigMap(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := fragmentResult{
+		Kind:    kind,
+		Actions: []string{},
+	}
+
+	switch kind {
+	case "server":
+		if serverName == "" {
+			return nil, fmt.Errorf("server_name is required to apply a server")
+		}
+
+		servers, err := configObject(cfg, true, "apps", "http", "servers")
+		if err != nil {
+			return nil, err
+		}
+
+		action := "added"
+		if _, ok := servers[serverName]; ok {
+			action = "replaced"
+		}
+		servers[serverName] = objects[0]
+		result.Actions = append(result.Actions, fmt.Sprintf("%s the server at apps/http/servers/%s", action, serverName))
+
+	case "route":
+		name, srv, err := targetServer(cfg, serverName)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, route := range objects {
+			if route["match"] == nil && host != "" {
+				route["match"] = []any{
+					map[string]any{"host": []string{host}},
+				}
+				result.Actions = append(result.Actions, fmt.Sprintf("added a host matcher for %s to the route", host))
+			}
+
+			index := insertRoute(srv, name, route)
+			result.Actions = append(result.Actions, fmt.Sprintf("added the route at apps/http/servers/%s/routes/%d", name, index))
+		}
+
+	case "handler":
+		if host == "" {
+			return nil, fmt.Errorf("host is required to apply a handler")
+		}
+
+		name, index, route, err := findHostRoute(cfg, serverName, host)
+		if err != nil {
+			return nil, err
+		}
+
+		if route == nil {
+			name, srv, err := targetServer(cfg, serverName)
+			if err != nil {
+				return nil, err
+			}
+
+			handlers := []any{}
+			for _, handler := range objects {
+				handlers = append(handlers, handler)
+			}
+
+			route := map[string]any{
+				"match": []any{
+					map[string]any{"host": []string{host}},
+				},
+				"handle":   handlers,
+				"terminal": true,
+			}
+			index := insertRoute(srv, name, route)
+			result.Actions = append(result.Actions, fmt.Sprintf("no route matches %s; added a new route for it at apps/http/servers/%s/routes/%d", host, name, index))
+			break
+		}
+
+		path := fmt.Sprintf("apps/http/servers/%s/routes/%d", name, index)
+		for _, handler := range objects {
+			result.Actions = append(result.Actions, mergeRouteHandler(route, handler, path))
+		}
+	}
+
+	if request.GetBool("apply", false) {
+		result.Config, err = loadConfigMap(ctx, cfg)
+		if err != nil {
+			return caddyErrorResult(err)
+		}
+		result.Applied = true
+	} else {
+		result.Config, err = json.Marshal(cfg)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	out, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(out)), nil
+}
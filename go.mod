@@ -5,6 +5,7 @@ go 1.24.3
 require (
 	github.com/caddyserver/caddy/v2 v2.10.0
 	github.com/mark3labs/mcp-go v0.31.0
+	golang.org/x/crypto v0.37.0
 )
 
 require (
@@ -135,7 +136,6 @@ require (
 	go.uber.org/multierr v1.11.0 // indirect
 	go.uber.org/zap v1.27.0 // indirect
 	go.uber.org/zap/exp v0.3.0 // indirect
-	golang.org/x/crypto v0.37.0 // indirect
 	golang.org/x/crypto/x509roots/fallback v0.0.0-20250305170421-49bf5b80c810 // indirect
 	golang.org/x/exp v0.0.0-20250408133849-7e4ce0ab07d0 // indirect
 	golang.org/x/mod v0.24.0 // indirect
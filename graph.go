@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+type graphNode struct {
+	ID    string `json:"id"`
+	Type  string `json:"type"`
+	Label string `json:"label"`
+}
+
+type graphEdge struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Label string `json:"label,omitempty"`
+}
+
+type configGraph struct {
+	Nodes []graphNode `json:"nodes"`
+	Edges []graphEdge `json:"edges"`
+
+	seen map[string]bool
+}
+
+func registerGraphTools(s *server.MCPServer) {
+	configGraphTool := mcp.NewTool("config_graph",
+		mcp.WithDescription(`
+		Use the config_graph tool to get the caddy configuration as a graph of how requests flow from servers through routes to upstreams, for example to draw it or explain it.
+
+		Nodes are servers, routes (including the routes of subroutes), upstreams, file server roots and static responses. Edges go from each server to its routes in order, from routes to their subroutes and from routes to where they send requests.
+		The "json" format is a JSON document with the nodes and edges; the "dot" format is a Graphviz digraph.
+
+		Notes:
+			Node ids of servers and routes are their config paths, which can be used with the get_config_path tool. Upstreams shared by several routes are a single node.
+			Route labels show their matchers, like the resolve_route tool. Handlers that only modify requests, like headers or encode, are not nodes.
+			If json_config is not provided the graph of the current caddy server configuration is returned.
+		`),
+		mcp.WithString("format",
+			mcp.Description("The format of the graph: json or dot"),
+			mcp.Enum("json", "dot"),
+			mcp.DefaultString("json"),
+		),
+		mcp.WithString("json_config",
+			mcp.Description("The caddy JSON configuration to graph instead of the current configuration"),
+		),
+	)
+
+	// Add config graph tool handler
+	s.AddTool(configGraphTool, configGraphHandler)
+}
+
+// Add a node unless it is already in the graph
+func (g *configGraph) addNode(id, nodeType, label string) {
+	if g.seen[id] {
+		return
+	}
+	g.seen[id] = true
+	g.Nodes = append(g.Nodes, graphNode{ID: id, Type: nodeType, Label: label})
+}
+
+// Add the nodes and edges of a list of routes
+func (g *configGraph) addRoutes(parent, path string, routes []any) {
+	for i, r := range routes {
+		route, ok := r.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		routePath := joinConfigPath(path, strconv.Itoa(i))
+		label := describeMatchers(route)
+		if id, ok := route["@id"].(string); ok {
+			label = fmt.Sprintf("%s (@id %s)", label, id)
+		}
+		g.addNode(routePath, "route", label)
+		g.Edges = append(g.Edges, graphEdge{From: parent, To: routePath, Label: strconv.Itoa(i)})
+
+		handlers, _ := route["handle"].([]any)
+		for j, h := range handlers {
+			handler, ok := h.(map[string]any)
+			if !ok {
+				continue
+			}
+
+			switch handler["handler"] {
+			case "subroute":
+				subroutes, _ := handler["routes"].([]any)
+				g.addRoutes(routePath, joinConfigPath(routePath, fmt.Sprintf("handle/%d/routes", j)), subroutes)
+			case "reverse_proxy":
+				for _, dial := range proxyUpstreams(handler) {
+					id := "upstream:" + dial
+					g.addNode(id, "upstream", dial)
+					g.Edges = append(g.Edges, graphEdge{From: routePath, To: id, Label: "reverse_proxy"})
+				}
+			case "file_server":
+				root, _ := handler["root"].(string)
+				if root == "" {
+					root = "{http.vars.root}"
+				}
+				id := "files:" + root
+				g.addNode(id, "files", root)
+				g.Edges = append(g.Edges, graphEdge{From: routePath, To: id, Label: "file_server"})
+			case "static_response":
+				id := joinConfigPath(routePath, fmt.Sprintf("handle/%d", j))
+				label := "static response"
+				if status, ok := handler["status_code"]; ok {
+					label = fmt.Sprintf("static response %v", status)
+				}
+				headers, _ := handler["headers"].(map[string]any)
+				if location, ok := headers["Location"].([]any); ok && len(location) > 0 {
+					label = fmt.Sprintf("%s to %v", label, location[0])
+				}
+				g.addNode(id, "response", label)
+				g.Edges = append(g.Edges, graphEdge{From: routePath, To: id, Label: "static_response"})
+			}
+		}
+	}
+}
+
+// Render the graph in the Graphviz DOT language
+func (g *configGraph) dot() string {
+	var b strings.Builder
+	b.WriteString("digraph caddy {\n\trankdir=LR;\n")
+
+	shapes := map[string]string{
+		"server":   "box3d",
+		"route":    "box",
+		"upstream": "ellipse",
+		"files":    "folder",
+		"response": "note",
+	}
+	for _, node := range g.Nodes {
+		fmt.Fprintf(&b, "\t%s [label=%s, shape=%s];\n", strconv.Quote(node.ID), strconv.Quote(node.Label), shapes[node.Type])
+	}
+	for _, edge := range g.Edges {
+		fmt.Fprintf(&b, "\t%s -> %s [label=%s];\n", strconv.Quote(edge.From), strconv.Quote(edge.To), strconv.Quote(edge.Label))
+	}
+
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// Build the graph of servers, routes and upstreams of a configuration
+func configGraphHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	format := request.GetString("format", "json")
+	if format != "json" && format != "dot" {
+		return nil, fmt.Errorf("unsupported format: %s", format)
+	}
+
+	value, err := configArgument(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("the configuration must be a JSON object")
+	}
+
+	graph := &configGraph{
+		Nodes: []graphNode{},
+		Edges: []graphEdge{},
+		seen:  map[string]bool{},
+	}
+
+	for _, name := range httpServerNames(cfg) {
+		srv, err := httpServer(cfg, name)
+		if err != nil {
+			continue
+		}
+
+		path := "apps/http/servers/" + name
+		label := name
+		if listen, ok := srv["listen"].([]any); ok {
+			addresses := make([]string, 0, len(listen))
+			for _, l := range listen {
+				addresses = append(addresses, fmt.Sprint(l))
+			}
+			label = fmt.Sprintf("%s (%s)", name, strings.Join(addresses, ", "))
+		}
+		graph.addNode(path, "server", label)
+		graph.addRoutes(path, joinConfigPath(path, "routes"), serverRoutes(srv))
+	}
+
+	if format == "dot" {
+		return mcp.NewToolResultText(graph.dot()), nil
+	}
+
+	data, err := json.Marshal(graph)
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func registerHTTPSTools(s *server.MCPServer) {
+	setAutoHTTPSSkip := mcp.NewTool("set_auto_https_skip",
+		mcp.WithDescription(`
+		Use the set_auto_https_skip tool to exclude specific domains from automatic HTTPS on a server while keeping it enabled for all other domains.
+
+		The updated configuration is applied to the caddy server and returned in JSON format.
+
+		Notes:
+			The "skip" mode disables automatic HTTPS entirely for the domains (no certificates and no HTTP->HTTPS redirects).
+			The "skip_certificates" mode keeps the redirects and HTTPS listeners but does not manage certificates for the domains.
+			Only the selected list in the automatic_https block is replaced; all other automatic_https fields are kept.
+			Pass an empty list of domains to clear the list.
+		`),
+		mcp.WithString("server_name",
+			mcp.Required(),
+			mcp.Description("The name of the server in apps/http/servers"),
+		),
+		mcp.WithArray("domains",
+			mcp.Required(),
+			mcp.Description("The domains to exclude"),
+			mcp.Items(map[string]any{"type": "string"}),
+		),
+		mcp.WithString("mode",
+			mcp.Description("Which list to populate: skip or skip_certificates"),
+			mcp.Enum("skip", "skip_certificates"),
+			mcp.DefaultString("skip"),
+		),
+	)
+
+	// Add set auto HTTPS skip tool handler
+	s.AddTool(setAutoHTTPSSkip, setAutoHTTPSSkipHandler)
+}
+
+// Set the automatic_https skip or skip_certificates list on a server
+func setAutoHTTPSSkipHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	serverName, err := request.RequireString("server_name")
+	if err != nil {
+		return nil, err
+	}
+
+	domains, err := request.RequireStringSlice("domains")
+	if err != nil {
+		return nil, err
+	}
+
+	mode := request.GetString("mode", "skip")
+	if mode != "skip" && mode != "skip_certificates" {
+		return nil, fmt.Errorf("unsupported mode: %s", mode)
+	}
+
+	for _, domain := range domains {
+		if strings.TrimSpace(domain) == "" || strings.ContainsAny(domain, " /") {
+			return nil, fmt.Errorf("invalid domain: %q", domain)
+		}
+	}
+
+	cfg, err := fetchConfigMap(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	srv, err := httpServer(cfg, serverName)
+	if err != nil {
+		return nil, err
+	}
+
+	autoHTTPS, err := configObject(srv, true, "automatic_https")
+	if err != nil {
+		return nil, err
+	}
+
+	if len(domains) == 0 {
+		delete(autoHTTPS, mode)
+	} else {
+		autoHTTPS[mode] = domains
+	}
+
+	if len(autoHTTPS) == 0 {
+		delete(srv, "automatic_https")
+	}
+
+	return applyConfigMap(ctx, cfg)
+}
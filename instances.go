@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// The admin API URLs of the caddy instances named with -instances
+var instanceURLs = map[string]string{}
+
+type instanceKey struct{}
+
+// Parse the -instances flag, a comma separated list of name=url pairs
+func parseInstances(raw string) error {
+	for _, pair := range strings.Split(raw, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		name, rawURL, ok := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return fmt.Errorf("invalid -instances entry %q: it must be name=url, for example staging=http://10.0.0.2:2019", pair)
+		}
+
+		if _, ok := instanceURLs[name]; ok {
+			return fmt.Errorf("invalid -instances: instance %q is listed more than once", name)
+		}
+
+		u, err := normalizeAdminURL("instances", strings.TrimSpace(rawURL))
+		if err != nil {
+			return err
+		}
+		instanceURLs[name] = u
+	}
+
+	return nil
+}
+
+// The names of the instances, sorted
+func instanceNames() []string {
+	return slices.Sorted(maps.Keys(instanceURLs))
+}
+
+// Get the admin API URL of the instance a tool call targets, the -url one by default
+func adminURL(ctx context.Context) string {
+	if name, ok := ctx.Value(instanceKey{}).(string); ok && name != "" {
+		return instanceURLs[name]
+	}
+
+	return defaultURL
+}
+
+// Get the name of the instance a tool call targets, empty for the -url one
+func instanceName(ctx context.Context) string {
+	name, _ := ctx.Value(instanceKey{}).(string)
+	return name
+}
+
+// Target the admin API of another instance, for changes made outside of a tool call like an automatic revert
+func withInstance(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, instanceKey{}, name)
+}
+
+// Select the caddy instance of a tool call from its instance argument
+func instanceMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		name := request.GetString("instance", "")
+		if name == "" {
+			return next(ctx, request)
+		}
+
+		if _, ok := instanceURLs[name]; !ok {
+			if len(instanceURLs) == 0 {
+				return nil, fmt.Errorf("unknown instance %q: no instances are configured; start the MCP server with -instances to manage several caddy servers", name)
+			}
+			return nil, fmt.Errorf("unknown instance %q; known instances: %s", name, strings.Join(instanceNames(), ", "))
+		}
+
+		return next(withInstance(ctx, name), request)
+	}
+}
+
+// Add the instance argument to the schema of every tool when instances are configured
+func instanceToolFilter(ctx context.Context, tools []mcp.Tool) []mcp.Tool {
+	if len(instanceURLs) == 0 {
+		return tools
+	}
+
+	for i, tool := range tools {
+		// The properties map is shared with the registered tool, so copy it
+		properties := maps.Clone(tool.InputSchema.Properties)
+		if properties == nil {
+			properties = map[string]any{}
+		}
+		properties["instance"] = map[string]any{
+			"type":        "string",
+			"enum":        instanceNames(),
+			"description": "The caddy instance to use, by its name in -instances. Defaults to the caddy server of -url.",
+		}
+		tools[i].InputSchema.Properties = properties
+	}
+
+	return tools
+}
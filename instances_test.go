@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Start a fake caddy admin API for a named instance
+func newFakeInstance(t *testing.T, name, config string) *fakeCaddy {
+	t.Helper()
+
+	fc := &fakeCaddy{config: []byte(config)}
+
+	srv := httptest.NewServer(fc)
+	t.Cleanup(srv.Close)
+
+	instanceURLs[name] = srv.URL
+	t.Cleanup(func() { delete(instanceURLs, name) })
+
+	return fc
+}
+
+// Call a tool through the instance middleware
+func callInstanceTool(handler func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error), name string, args map[string]any) (*mcp.CallToolResult, error) {
+	return callTool(instanceMiddleware(handler), name, args)
+}
+
+func TestParseInstances(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    map[string]string
+		wantErr bool
+	}{
+		{raw: "", want: map[string]string{}},
+		{
+			raw:  "staging=http://10.0.0.2:2019/, prod = https://10.0.0.3:2019",
+			want: map[string]string{"staging": "http://10.0.0.2:2019", "prod": "https://10.0.0.3:2019"},
+		},
+		{raw: "staging", wantErr: true},
+		{raw: "=http://10.0.0.2:2019", wantErr: true},
+		{raw: "staging=10.0.0.2:2019", wantErr: true},
+		{raw: "a=http://10.0.0.2:2019,a=http://10.0.0.3:2019", wantErr: true},
+	}
+
+	oldURLs := instanceURLs
+	defer func() { instanceURLs = oldURLs }()
+
+	for _, tt := range tests {
+		instanceURLs = map[string]string{}
+
+		err := parseInstances(tt.raw)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseInstances(%q) error = %v, want error %v", tt.raw, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(instanceURLs, tt.want) {
+			t.Errorf("parseInstances(%q) = %v, want %v", tt.raw, instanceURLs, tt.want)
+		}
+	}
+}
+
+func TestInstanceArgument(t *testing.T) {
+	local := newFakeCaddy(t, `{"apps":{}}`)
+	staging := newFakeInstance(t, "staging", `{"apps":{}}`)
+
+	if _, err := callInstanceTool(updateCaddyConfigHandler, "update_caddy_config", map[string]any{"json_config": portConfig(8080), "instance": "staging"}); err != nil {
+		t.Fatalf("update_caddy_config: %v", err)
+	}
+
+	if got := staging.current(); got != portConfig(8080) {
+		t.Errorf("staging configuration = %s, want %s", got, portConfig(8080))
+	}
+	if local.loadCount() != 0 {
+		t.Errorf("the update for staging was loaded into the -url instance")
+	}
+
+	if _, err := callInstanceTool(updateCaddyConfigHandler, "update_caddy_config", map[string]any{"json_config": portConfig(8080), "instance": "prod"}); err == nil {
+		t.Errorf("update_caddy_config for an unknown instance returned no error")
+	}
+}
+
+func TestUndoPerInstance(t *testing.T) {
+	resetUndo(t)
+	local := newFakeCaddy(t, portConfig(80))
+	staging := newFakeInstance(t, "staging", portConfig(80))
+
+	if _, err := callInstanceTool(updateCaddyConfigHandler, "update_caddy_config", map[string]any{"json_config": portConfig(81)}); err != nil {
+		t.Fatalf("update_caddy_config: %v", err)
+	}
+	if _, err := callInstanceTool(updateCaddyConfigHandler, "update_caddy_config", map[string]any{"json_config": portConfig(82), "instance": "staging"}); err != nil {
+		t.Fatalf("update_caddy_config: %v", err)
+	}
+
+	// Undoing on staging leaves the change of the -url instance alone
+	if _, err := callInstanceTool(undoCaddyConfigHandler, "undo_caddy_config", map[string]any{"instance": "staging"}); err != nil {
+		t.Fatalf("undo_caddy_config: %v", err)
+	}
+	if got := staging.current(); got != portConfig(80) {
+		t.Errorf("staging configuration after undo = %s, want %s", got, portConfig(80))
+	}
+	if got := local.current(); got != portConfig(81) {
+		t.Errorf("-url configuration after undo on staging = %s, want %s", got, portConfig(81))
+	}
+
+	if got := undo(t); !got.Undone || got.Remaining != 0 {
+		t.Errorf("undo on the -url instance = %+v, want undone with 0 remaining", got)
+	}
+	if got := local.current(); got != portConfig(80) {
+		t.Errorf("-url configuration after undo = %s, want %s", got, portConfig(80))
+	}
+}
+
+func TestConfirmChangeTargetsProposedInstance(t *testing.T) {
+	useConfirmation(t)
+	local := newFakeCaddy(t, portConfig(80))
+	staging := newFakeInstance(t, "staging", portConfig(80))
+
+	result, err := callInstanceTool(updateCaddyConfigHandler, "update_caddy_config", map[string]any{"json_config": portConfig(8080), "instance": "staging"})
+	if err != nil {
+		t.Fatalf("update_caddy_config: %v", err)
+	}
+
+	var proposal confirmationRequired
+	if err := json.Unmarshal([]byte(resultText(t, result)), &proposal); err != nil {
+		t.Fatal(err)
+	}
+
+	// confirm_change is called without the instance argument
+	if _, err := callInstanceTool(confirmChangeHandler, "confirm_change", map[string]any{"token": proposal.Token}); err != nil {
+		t.Fatalf("confirm_change: %v", err)
+	}
+
+	if got := staging.current(); got != portConfig(8080) {
+		t.Errorf("staging configuration = %s, want %s", got, portConfig(8080))
+	}
+	if local.loadCount() != 0 {
+		t.Errorf("the change proposed for staging was loaded into the -url instance")
+	}
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+type lintFinding struct {
+	Rule       string `json:"rule"`
+	Severity   string `json:"severity"`
+	Path       string `json:"path"`
+	Message    string `json:"message"`
+	Suggestion string `json:"suggestion"`
+	Fixed      bool   `json:"fixed,omitempty"`
+}
+
+type lintReport struct {
+	Findings []lintFinding  `json:"findings"`
+	Config   map[string]any `json:"config,omitempty"`
+}
+
+// A lint rule reports findings on a decoded config, fixing them in place when fix is set and it is safe
+type lintRule func(cfg map[string]any, fix bool) []lintFinding
+
+var lintRules = []lintRule{
+	lintInsecureSkipVerify,
+	lintHTTPOnlyServers,
+	lintBroadMatchers,
+	lintMissingCompression,
+	lintMissingAccessLogs,
+}
+
+func registerLintTools(s *server.MCPServer) {
+	lintCaddyConfig := mcp.NewTool("lint_caddy_config",
+		mcp.WithDescription(`
+		Use the lint_caddy_config tool to find common anti-patterns in a valid caddy JSON configuration and how to fix them.
+
+		The rules are:
+			insecure_skip_verify (error): a reverse proxy does not verify the certificates of its HTTPS upstreams.
+			http_only_server (warning): a server with host names only listens on the HTTP port, so it never gets HTTPS.
+			broad_matcher (warning): a route without matchers, or with matchers matching everything, comes before other routes and answers every request, so the routes after it are never reached.
+			missing_compression (info): a route proxying or serving files does not compress responses with encode.
+			missing_access_logs (info): a server has no logs block, so its requests are not logged.
+		The result is a JSON document with each finding's rule, severity, config path, message and suggested fix.
+
+		Notes:
+			With fix set to true, the safe fixes are applied and the corrected configuration is returned in config: an encode handler with gzip and zstd is added to routes missing compression and an empty logs block to servers without access logs. Other findings need a decision and are left for you to fix. The corrected configuration is not applied.
+			If json_config is not provided the current caddy server configuration is checked.
+		`),
+		mcp.WithString("json_config",
+			mcp.Description("The caddy JSON configuration to check instead of the current configuration"),
+		),
+		mcp.WithBoolean("fix",
+			mcp.Description("Whether to return a corrected configuration with the safe fixes applied"),
+			mcp.DefaultBool(false),
+		),
+	)
+
+	// Add lint Caddy config tool handler
+	s.AddTool(lintCaddyConfig, lintCaddyConfigHandler)
+}
+
+// Call fn for every http server of a config with its config path
+func eachHTTPServer(cfg map[string]any, fn func(name, path string, srv map[string]any)) {
+	for _, name := range httpServerNames(cfg) {
+		srv, err := httpServer(cfg, name)
+		if err != nil {
+			continue
+		}
+		fn(name, "apps/http/servers/"+name, srv)
+	}
+}
+
+// Check whether a route has a handler, including handlers inside subroutes
+func routeHasHandler(route map[string]any, names ...string) bool {
+	return slices.ContainsFunc(routeHandlers(route), func(name string) bool {
+		return slices.Contains(names, name)
+	})
+}
+
+// Report reverse proxies that skip verifying upstream certificates
+func lintInsecureSkipVerify(cfg map[string]any, fix bool) []lintFinding {
+	var findings []lintFinding
+	walkJSON("", cfg, func(path string, value any) {
+		if value == true && strings.HasSuffix(path, "/transport/tls/insecure_skip_verify") {
+			findings = append(findings, lintFinding{
+				Rule:       "insecure_skip_verify",
+				Severity:   "error",
+				Path:       path,
+				Message:    "the reverse proxy does not verify the certificates of its HTTPS upstreams, so the connection can be intercepted",
+				Suggestion: "remove insecure_skip_verify and trust the upstream's CA with tls_trusted_ca_certs or set tls_server_name to the name in its certificate",
+			})
+		}
+	})
+
+	sort.Slice(findings, func(i, j int) bool {
+		return findings[i].Path < findings[j].Path
+	})
+
+	return findings
+}
+
+// Report servers with host names that only listen on the HTTP port
+func lintHTTPOnlyServers(cfg map[string]any, fix bool) []lintFinding {
+	httpPort := 80
+	if httpApp, err := configObject(cfg, false, "apps", "http"); err == nil {
+		if port, ok := httpApp["http_port"].(float64); ok {
+			httpPort = int(port)
+		}
+	}
+
+	var findings []lintFinding
+	eachHTTPServer(cfg, func(name, path string, srv map[string]any) {
+		if !listensOnlyOn(srv, httpPort) {
+			return
+		}
+
+		hasHosts := slices.ContainsFunc(serverRoutes(srv), func(r any) bool {
+			route, _ := r.(map[string]any)
+			return len(routeHosts(route)) > 0
+		})
+		if !hasHosts {
+			return
+		}
+
+		findings = append(findings, lintFinding{
+			Rule:       "http_only_server",
+			Severity:   "warning",
+			Path:       joinConfigPath(path, "listen"),
+			Message:    fmt.Sprintf("server %s has host names but only listens on the HTTP port %d, so automatic HTTPS is not applied", name, httpPort),
+			Suggestion: "listen on :443 as well, or add an automatic_https block with disable set to true if plain HTTP is intended",
+		})
+	})
+
+	return findings
+}
+
+// Check whether a route matches every request
+func matchesEverything(route map[string]any) bool {
+	matchSets, _ := route["match"].([]any)
+	if len(matchSets) == 0 {
+		return true
+	}
+
+	for _, m := range matchSets {
+		matchSet, _ := m.(map[string]any)
+		if len(matchSet) != 1 {
+			continue
+		}
+
+		for key, value := range matchSet {
+			values, _ := value.([]any)
+			if (key == "host" && slices.Contains(values, any("*"))) || (key == "path" && (slices.Contains(values, any("*")) || slices.Contains(values, any("/*")))) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Report catch-all routes that hide the routes after them
+func lintBroadMatchers(cfg map[string]any, fix bool) []lintFinding {
+	var findings []lintFinding
+	eachHTTPServer(cfg, func(name, path string, srv map[string]any) {
+		routes := serverRoutes(srv)
+		for i, r := range routes[:max(len(routes)-1, 0)] {
+			route, _ := r.(map[string]any)
+			if !matchesEverything(route) {
+				continue
+			}
+
+			terminal, _ := route["terminal"].(bool)
+			if !terminal && !routeHasHandler(route, responderHandlers...) {
+				continue
+			}
+
+			findings = append(findings, lintFinding{
+				Rule:       "broad_matcher",
+				Severity:   "warning",
+				Path:       joinConfigPath(path, "routes/"+strconv.Itoa(i)),
+				Message:    fmt.Sprintf("route %d of server %s matches every request and answers it, so the %d routes after it are never reached", i, name, len(routes)-i-1),
+				Suggestion: "add host or path matchers to the route, or move it to the end of the routes as the fallback",
+			})
+		}
+	})
+
+	return findings
+}
+
+// Report routes proxying or serving files without compression
+func lintMissingCompression(cfg map[string]any, fix bool) []lintFinding {
+	var findings []lintFinding
+	eachHTTPServer(cfg, func(name, path string, srv map[string]any) {
+		for i, r := range serverRoutes(srv) {
+			route, ok := r.(map[string]any)
+			if !ok || !routeHasHandler(route, "reverse_proxy", "file_server") || routeHasHandler(route, "encode") {
+				continue
+			}
+
+			finding := lintFinding{
+				Rule:       "missing_compression",
+				Severity:   "info",
+				Path:       joinConfigPath(path, "routes/"+strconv.Itoa(i)),
+				Message:    fmt.Sprintf("route %d of server %s does not compress its responses", i, name),
+				Suggestion: "add an encode handler with gzip and zstd before the other handlers of the route",
+			}
+
+			if fix {
+				handlers, _ := route["handle"].([]any)
+				route["handle"] = append([]any{
+					map[string]any{
+						"handler": "encode",
+						"encodings": map[string]any{
+							"gzip": map[string]any{},
+							"zstd": map[string]any{},
+						},
+						"prefer": []any{"zstd", "gzip"},
+					},
+				}, handlers...)
+				finding.Fixed = true
+			}
+
+			findings = append(findings, finding)
+		}
+	})
+
+	return findings
+}
+
+// Report servers that do not write access logs
+func lintMissingAccessLogs(cfg map[string]any, fix bool) []lintFinding {
+	var findings []lintFinding
+	eachHTTPServer(cfg, func(name, path string, srv map[string]any) {
+		if _, ok := srv["logs"]; ok {
+			return
+		}
+
+		finding := lintFinding{
+			Rule:       "missing_access_logs",
+			Severity:   "info",
+			Path:       path,
+			Message:    fmt.Sprintf("server %s has no logs block, so its requests are not logged", name),
+			Suggestion: "add an empty logs block to the server to write access logs to the default log",
+		}
+
+		if fix {
+			srv["logs"] = map[string]any{}
+			finding.Fixed = true
+		}
+
+		findings = append(findings, finding)
+	})
+
+	return findings
+}
+
+// Check a configuration for anti-patterns
+func lintCaddyConfigHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	value, err := configArgument(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("the configuration must be a JSON object")
+	}
+
+	fix := request.GetBool("fix", false)
+
+	report := lintReport{
+		Findings: []lintFinding{},
+	}
+	for _, rule := range lintRules {
+		report.Findings = append(report.Findings, rule(cfg, fix)...)
+	}
+
+	if fix {
+		report.Config = cfg
+	}
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
+}
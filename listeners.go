@@ -0,0 +1,366 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+var listenerWrapperDescriptions = map[string]string{
+	"tls":            "Marks where the TLS handshake happens. Wrappers before it see the raw connection, wrappers after it see the decrypted connection.",
+	"proxy_protocol": "Reads the PROXY protocol header sent by a load balancer so caddy sees the real client address. Must come before tls.",
+	"http_redirect":  "Redirects plain HTTP requests that arrive on an HTTPS port to HTTPS. Must come before tls.",
+}
+
+type listenerWrapperInfo struct {
+	Position    int            `json:"position"`
+	Wrapper     string         `json:"wrapper"`
+	Phase       string         `json:"phase"`
+	Implicit    bool           `json:"implicit,omitempty"`
+	Description string         `json:"description"`
+	Config      map[string]any `json:"config,omitempty"`
+}
+
+// Port ranges larger than this are not expanded by normalize_listen_address
+const maxExpandedPorts = 64
+
+type normalizedAddress struct {
+	Input     string   `json:"input"`
+	Valid     bool     `json:"valid"`
+	Address   string   `json:"address,omitempty"`
+	Network   string   `json:"network,omitempty"`
+	Host      string   `json:"host,omitempty"`
+	Ports     string   `json:"ports,omitempty"`
+	PortCount uint     `json:"port_count,omitempty"`
+	Expanded  []string `json:"expanded,omitempty"`
+	Warning   string   `json:"warning,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+type normalizedListen struct {
+	Valid     bool                `json:"valid"`
+	Listen    []string            `json:"listen,omitempty"`
+	Addresses []normalizedAddress `json:"addresses"`
+}
+
+func registerListenerTools(s *server.MCPServer) {
+	enableProxyProtocol := mcp.NewTool("enable_proxy_protocol",
+		mcp.WithDescription(`
+		Use the enable_proxy_protocol tool to make a caddy server accept the PROXY protocol from a load balancer in front of it, so caddy sees the real client addresses.
+
+		The proxy_protocol listener wrapper is placed first in the server's listener_wrappers, before the tls wrapper, which is where it must go for HTTPS servers. Existing wrappers are kept in their order.
+		The updated configuration is applied to the caddy server and returned in JSON format.
+
+		Notes:
+			Restrict allow to the addresses of your load balancers; PROXY headers from anyone else can spoof client addresses.
+		`),
+		mcp.WithString("server_name",
+			mcp.Required(),
+			mcp.Description("The name of the server in apps/http/servers"),
+		),
+		mcp.WithArray("allow",
+			mcp.Description("The CIDR ranges allowed to send PROXY headers"),
+			mcp.Items(map[string]any{"type": "string"}),
+		),
+		mcp.WithString("timeout",
+			mcp.Description("The maximum time to wait for the PROXY header, for example 5s"),
+		),
+		mcp.WithString("fallback_policy",
+			mcp.Description("The policy for connections from addresses that are not allowed: IGNORE, USE, REJECT, REQUIRE or SKIP"),
+			mcp.Enum("IGNORE", "USE", "REJECT", "REQUIRE", "SKIP"),
+		),
+	)
+
+	// Add enable proxy protocol tool handler
+	s.AddTool(enableProxyProtocol, enableProxyProtocolHandler)
+
+	listListenerWrappers := mcp.NewTool("list_listener_wrappers",
+		mcp.WithDescription(`
+		Use the list_listener_wrappers tool to list the listener wrappers of a caddy server in the order they are applied, with a short description of each.
+
+		The result is a JSON document. Each wrapper has a phase telling whether it runs before or after the TLS handshake.
+
+		Notes:
+			When no tls wrapper is configured caddy implicitly runs the TLS handshake first, so every configured wrapper runs after it. The implicit tls wrapper is included in the list.
+		`),
+		mcp.WithString("server_name",
+			mcp.Required(),
+			mcp.Description("The name of the server in apps/http/servers"),
+		),
+	)
+
+	// Add list listener wrappers tool handler
+	s.AddTool(listListenerWrappers, listListenerWrappersHandler)
+
+	normalizeListenAddress := mcp.NewTool("normalize_listen_address",
+		mcp.WithDescription(`
+		Use the normalize_listen_address tool to check caddy listen addresses and get their canonical form before putting them in a server's listen array.
+
+		The input can hold several addresses separated by spaces, for example "127.0.0.1:443 192.168.1.1:443". The result is a JSON document with, for each address, its canonical form, network, host and ports or an error explaining what is wrong, and the listen array to use when every address is valid.
+
+		Notes:
+			Addresses have the form [network/]host:port. The host may be empty to listen on all interfaces, and the port may be a range like 8000-8010, which is expanded when it is small.
+			Unix socket addresses look like unix//run/caddy.sock and have no port.
+		`),
+		mcp.WithString("address",
+			mcp.Required(),
+			mcp.Description("One or more listen addresses separated by spaces, for example :443"),
+		),
+	)
+
+	// Add normalize listen address tool handler
+	s.AddTool(normalizeListenAddress, normalizeListenAddressHandler)
+}
+
+// Get the listener wrappers of a server
+func listenerWrappers(srv map[string]any) []any {
+	wrappers, _ := srv["listener_wrappers"].([]any)
+	return wrappers
+}
+
+// Get the name of a listener wrapper
+func wrapperName(wrapper any) string {
+	w, _ := wrapper.(map[string]any)
+	name, _ := w["wrapper"].(string)
+	return name
+}
+
+// Enable the PROXY protocol listener wrapper on a server
+func enableProxyProtocolHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if _, err := caddy.GetModule("caddy.listeners.proxy_protocol"); err != nil {
+		return nil, fmt.Errorf("the proxy_protocol listener wrapper is not available in this build: %v", err)
+	}
+
+	serverName, err := request.RequireString("server_name")
+	if err != nil {
+		return nil, err
+	}
+
+	allow := request.GetStringSlice("allow", nil)
+	for _, cidr := range allow {
+		if _, err := netip.ParsePrefix(cidr); err != nil {
+			return nil, fmt.Errorf("invalid CIDR: %q", cidr)
+		}
+	}
+
+	wrapper := map[string]any{
+		"wrapper": "proxy_protocol",
+	}
+	if len(allow) > 0 {
+		wrapper["allow"] = allow
+	}
+	if timeout := request.GetString("timeout", ""); timeout != "" {
+		if _, err := time.ParseDuration(timeout); err != nil {
+			return nil, fmt.Errorf("invalid timeout %q: %v", timeout, err)
+		}
+		wrapper["timeout"] = timeout
+	}
+	if policy := request.GetString("fallback_policy", ""); policy != "" {
+		wrapper["fallback_policy"] = policy
+	}
+
+	cfg, err := fetchConfigMap(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	srv, err := httpServer(cfg, serverName)
+	if err != nil {
+		return nil, err
+	}
+
+	hasTLS := false
+	var others []any
+	for _, w := range listenerWrappers(srv) {
+		switch wrapperName(w) {
+		case "proxy_protocol":
+			continue
+		case "tls":
+			hasTLS = true
+		}
+		others = append(others, w)
+	}
+
+	// Without an explicit tls wrapper every wrapper runs after the TLS handshake
+	wrappers := []any{wrapper}
+	if !hasTLS {
+		wrappers = append(wrappers, map[string]any{"wrapper": "tls"})
+	}
+	srv["listener_wrappers"] = append(wrappers, others...)
+
+	return applyConfigMap(ctx, cfg)
+}
+
+// List the listener wrappers of a server in order
+func listListenerWrappersHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	serverName, err := request.RequireString("server_name")
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := fetchConfigMap(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	srv, err := httpServer(cfg, serverName)
+	if err != nil {
+		return nil, err
+	}
+
+	wrappers := listenerWrappers(srv)
+
+	hasTLS := false
+	for _, w := range wrappers {
+		if wrapperName(w) == "tls" {
+			hasTLS = true
+		}
+	}
+
+	result := []listenerWrapperInfo{}
+	if !hasTLS && len(wrappers) > 0 {
+		result = append(result, listenerWrapperInfo{
+			Wrapper:     "tls",
+			Phase:       "tls",
+			Implicit:    true,
+			Description: listenerWrapperDescriptions["tls"],
+		})
+	}
+
+	phase := "before_tls"
+	if !hasTLS {
+		phase = "after_tls"
+	}
+
+	for _, w := range wrappers {
+		name := wrapperName(w)
+
+		info := listenerWrapperInfo{
+			Wrapper:     name,
+			Phase:       phase,
+			Description: listenerWrapperDescriptions[name],
+		}
+		if info.Description == "" {
+			info.Description = "A listener wrapper provided by a third-party module."
+		}
+
+		if name == "tls" {
+			info.Phase = "tls"
+			phase = "after_tls"
+		}
+
+		if config, ok := w.(map[string]any); ok && len(config) > 1 {
+			info.Config = map[string]any{}
+			for key, value := range config {
+				if key != "wrapper" {
+					info.Config[key] = value
+				}
+			}
+		}
+
+		result = append(result, info)
+	}
+
+	for i := range result {
+		result[i].Position = i
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+// Parse a listen address into its canonical form
+func normalizeAddress(input string) normalizedAddress {
+	result := normalizedAddress{Input: input}
+
+	addr, err := caddy.ParseNetworkAddress(input)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Network = addr.Network
+	result.Host = addr.Host
+
+	switch {
+	case addr.IsUnixNetwork():
+		if addr.Host == "" {
+			result.Error = "missing socket path, for example unix//run/caddy.sock"
+			return result
+		}
+	case addr.IsFdNetwork():
+		result.Warning = "file descriptor addresses only work when caddy inherits the descriptor from its parent process"
+	default:
+		if addr.StartPort == 0 {
+			result.Error = "missing port; listen addresses need a port, for example :443 or localhost:8080"
+			return result
+		}
+
+		if strings.HasPrefix(addr.Network, "udp") {
+			result.Warning = "udp addresses cannot serve HTTP/1 or HTTP/2; use tcp and let caddy open the HTTP/3 socket"
+		}
+
+		result.PortCount = addr.PortRangeSize()
+		result.Ports = fmt.Sprintf("%d", addr.StartPort)
+		if addr.EndPort != addr.StartPort {
+			result.Ports = fmt.Sprintf("%d-%d", addr.StartPort, addr.EndPort)
+			if result.PortCount <= maxExpandedPorts {
+				for _, a := range addr.Expand() {
+					result.Expanded = append(result.Expanded, a.String())
+				}
+			}
+		}
+	}
+
+	result.Valid = true
+	result.Address = addr.String()
+
+	return result
+}
+
+// Validate and normalize space-separated listen addresses
+func normalizeListenAddressHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	input, err := request.RequireString("address")
+	if err != nil {
+		return nil, err
+	}
+
+	fields := strings.Fields(input)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("address must not be empty")
+	}
+
+	result := normalizedListen{
+		Valid:     true,
+		Addresses: []normalizedAddress{},
+	}
+	for _, field := range fields {
+		addr := normalizeAddress(field)
+		result.Valid = result.Valid && addr.Valid
+		result.Addresses = append(result.Addresses, addr)
+	}
+
+	if result.Valid {
+		for _, addr := range result.Addresses {
+			result.Listen = append(result.Listen, addr.Address)
+		}
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
+}
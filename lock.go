@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Serializes the tools that read the caddy configuration, change it and load it back, so two
+// clients of this MCP server cannot overwrite each other's changes with a stale read.
+// It does not protect against changes made to caddy outside of this process; those are caught by
+// loading the changes with If-Match on the Etag of the configuration that was read.
+var configMu sync.Mutex
+
+type configVersionKey struct{}
+
+// The version of the configuration a tool call read, used to load its changes conditionally
+type configVersion struct {
+	etag        string
+	loaded      bool
+	conditional bool
+	locked      bool
+}
+
+// Release configMu if the tool call still holds it
+func (v *configVersion) unlock() {
+	if v.locked {
+		v.locked = false
+		configMu.Unlock()
+	}
+}
+
+// Let other tools change the configuration while a tool call that is done changing it waits, for example for certificates
+func releaseConfigLock(ctx context.Context) {
+	if version, ok := ctx.Value(configVersionKey{}).(*configVersion); ok {
+		version.unlock()
+	}
+}
+
+// The tools that change the caddy configuration and hold configMu while they run
+var configChangeTools = map[string]bool{
+	"add_file_server":            true,
+	"add_reverse_proxy":          true,
+	"apply_and_report_certs":     true,
+	"clone_route":                true,
+	"commit_config_upload":       true,
+	"conditional_update":         true,
+	"configure_ondemand_ask":     true,
+	"confirm_change":             true,
+	"delete_config_path":         true,
+	"disable_caddy_metrics":      true,
+	"drain_upstream":             true,
+	"enable_caddy_metrics":       true,
+	"enable_cors":                true,
+	"enable_proxy_protocol":      true,
+	"load_environment":           true,
+	"manage_certificates":        true,
+	"protect_server_basicauth":   true,
+	"reset_caddy_config":         true,
+	"restore_caddy_config":       true,
+	"restore_upstream":           true,
+	"restrict_route_by_ip":       true,
+	"set_auto_https_skip":        true,
+	"set_config_path":            true,
+	"set_fallback_handler":       true,
+	"set_log_sampling":           true,
+	"set_proxy_response_timeout": true,
+	"set_route_access_log":       true,
+	"set_route_enabled":          true,
+	"set_tls_connection_policy":  true,
+	"setup_canary":               true,
+	"setup_simple_proxy":         true,
+	"setup_spa":                  true,
+	"smart_apply_fragment":       true,
+	"stop_caddy":                 true,
+	"try_config_with_autorevert": true,
+	"undo_caddy_config":          true,
+	"update_caddy_config":        true,
+}
+
+// Run the tools that change the configuration one at a time, and tell in their result whether
+// the configuration they loaded was conditional on the version they read
+func configLockMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if !configChangeTools[request.Params.Name] {
+			return next(ctx, request)
+		}
+
+		configMu.Lock()
+		version := &configVersion{locked: true}
+		defer version.unlock()
+
+		result, err := next(context.WithValue(ctx, configVersionKey{}, version), request)
+		if err != nil || result == nil || !version.loaded {
+			return result, err
+		}
+
+		if version.conditional {
+			result.Content = append(result.Content, mcp.NewTextContent("The update was conditional: caddy only loaded it because the configuration had not changed since this tool read it."))
+		} else {
+			result.Content = append(result.Content, mcp.NewTextContent("The update was not conditional: the configuration was loaded without checking whether it changed since it was last read."))
+		}
+
+		return result, nil
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestConfigLockSerializesChanges(t *testing.T) {
+	fc := newFakeCaddy(t, `{"apps":{"http":{"servers":{"srv0":{"listen":[":443"]}}}}}`)
+	handler := configLockMiddleware(setupSPAHandler)
+
+	const calls = 10
+
+	var wg sync.WaitGroup
+	errs := make(chan error, calls)
+	for i := range calls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			request := mcp.CallToolRequest{}
+			request.Params.Name = "setup_spa"
+			request.Params.Arguments = map[string]any{"host": fmt.Sprintf("app%d.example.com", i), "root": "/srv/app"}
+			if _, err := handler(context.Background(), request); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatalf("setup_spa: %v", err)
+	}
+
+	var cfg map[string]any
+	if err := json.Unmarshal([]byte(fc.current()), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	srv, err := httpServer(cfg, "srv0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every read-modify-write saw the changes of the calls before it
+	if routes := serverRoutes(srv); len(routes) != calls {
+		t.Errorf("server has %d routes after %d concurrent setup_spa calls, want %d", len(routes), calls, calls)
+	}
+}
+
+func TestConfigLockSkipsReadOnlyTools(t *testing.T) {
+	newFakeCaddy(t, `{"apps":{}}`)
+	handler := configLockMiddleware(getConfigHashHandler)
+
+	configMu.Lock()
+	defer configMu.Unlock()
+
+	done := make(chan error, 1)
+	go func() {
+		request := mcp.CallToolRequest{}
+		request.Params.Name = "get_config_hash"
+		_, err := handler(context.Background(), request)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("get_config_hash: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("get_config_hash waited for the configuration lock")
+	}
+}
+
+// Get the text of the last content of a tool result
+func lastResultText(t *testing.T, result *mcp.CallToolResult) string {
+	t.Helper()
+
+	if result == nil || len(result.Content) == 0 {
+		t.Fatalf("tool returned no content")
+	}
+
+	text, ok := result.Content[len(result.Content)-1].(mcp.TextContent)
+	if !ok {
+		t.Fatalf("tool returned %T, want text", result.Content[len(result.Content)-1])
+	}
+	return text.Text
+}
+
+func TestConditionalLoad(t *testing.T) {
+	fc := newFakeCaddy(t, `{"apps":{"http":{"servers":{"srv0":{"listen":[":443"]}}}}}`)
+	handler := configLockMiddleware(setupSPAHandler)
+
+	request := mcp.CallToolRequest{}
+	request.Params.Name = "setup_spa"
+	request.Params.Arguments = map[string]any{"host": "app.example.com", "root": "/srv/app"}
+
+	result, err := handler(context.Background(), request)
+	if err != nil {
+		t.Fatalf("setup_spa: %v", err)
+	}
+	if text := lastResultText(t, result); !strings.Contains(text, "The update was conditional") {
+		t.Errorf("setup_spa result ends with %q, want the conditional note", text)
+	}
+	if fc.loadCount() != 1 {
+		t.Errorf("setup_spa loaded %d configurations, want 1", fc.loadCount())
+	}
+}
+
+func TestConditionalLoadRefusesExternalChange(t *testing.T) {
+	fc := newFakeCaddy(t, `{"apps":{"http":{"http_port":80}}}`)
+
+	// A tool whose read and write are separated by a change made outside of this MCP server
+	handler := configLockMiddleware(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		cfg, err := fetchConfigMap(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		fc.set(`{"apps":{"http":{"http_port":9090}}}`)
+
+		httpApp, err := configObject(cfg, false, "apps", "http")
+		if err != nil {
+			return nil, err
+		}
+		httpApp["http_port"] = 8080
+
+		return applyConfigMap(ctx, cfg)
+	})
+
+	request := mcp.CallToolRequest{}
+	request.Params.Name = "update_caddy_config"
+
+	if _, err := handler(context.Background(), request); !errors.Is(err, errConfigChanged) {
+		t.Fatalf("tool error = %v, want errConfigChanged", err)
+	}
+	if got := fc.current(); got != `{"apps":{"http":{"http_port":9090}}}` {
+		t.Errorf("configuration = %s, want the external change kept", got)
+	}
+}
+
+func TestUnconditionalLoad(t *testing.T) {
+	newFakeCaddy(t, `{"apps":{}}`)
+	handler := configLockMiddleware(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if _, err := loadConfig(ctx, []byte(`{"apps":{"http":{}}}`)); err != nil {
+			return nil, err
+		}
+		return mcp.NewToolResultText("loaded"), nil
+	})
+
+	request := mcp.CallToolRequest{}
+	request.Params.Name = "update_caddy_config"
+
+	result, err := handler(context.Background(), request)
+	if err != nil {
+		t.Fatalf("tool error = %v", err)
+	}
+	if text := lastResultText(t, result); !strings.Contains(text, "The update was not conditional") {
+		t.Errorf("tool result ends with %q, want the unconditional note", text)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+type adminStatusKey struct{}
+
+// Configure the default slog logger from the -log-level and -log-format flags.
+// Logs go to stderr so they never mix with the stdio transport.
+func setupLogger(level, format string) error {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("invalid -log-level %q, must be debug, info, warn or error", level)
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+
+	var handler slog.Handler
+	switch format {
+	case "text":
+		handler = slog.NewTextHandler(os.Stderr, opts)
+	case "json":
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	default:
+		return fmt.Errorf("invalid -log-format %q, must be text or json", format)
+	}
+
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+// Log an error and exit
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// Remember the status of the last admin API response of a tool call so it can be logged
+func recordAdminStatus(req *http.Request, status int) {
+	if last, ok := req.Context().Value(adminStatusKey{}).(*int); ok {
+		*last = status
+	}
+}
+
+// Log every tool call with its arguments size, the last caddy status and its latency
+func loggingMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var status int
+		ctx = context.WithValue(ctx, adminStatusKey{}, &status)
+
+		args, _ := json.Marshal(request.Params.Arguments)
+
+		start := time.Now()
+		result, err := next(ctx, request)
+
+		attrs := []any{
+			"tool", request.Params.Name,
+			"args_bytes", len(args),
+			"latency", time.Since(start),
+		}
+		if status != 0 {
+			attrs = append(attrs, "caddy_status", status)
+		}
+
+		switch {
+		case err != nil:
+			slog.Warn("tool call failed", append(attrs, "error", err)...)
+		case result != nil && result.IsError:
+			slog.Warn("tool call returned an error", attrs...)
+		default:
+			slog.Info("tool call", attrs...)
+		}
+
+		return result, err
+	}
+}
@@ -0,0 +1,450 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Names of custom logs created for routes
+var logNameRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// The most lines get_recent_logs returns
+const maxLogLines = 1000
+
+type recentLogs struct {
+	Path     string      `json:"path"`
+	Lines    []string    `json:"lines"`
+	Statuses map[int]int `json:"statuses,omitempty"`
+	Unparsed int         `json:"unparsed,omitempty"`
+	Note     string      `json:"note,omitempty"`
+}
+
+func registerLogTools(s *server.MCPServer) {
+	setRouteAccessLog := mcp.NewTool("set_route_access_log",
+		mcp.WithDescription(`
+		Use the set_route_access_log tool to send the access logs of one route of a caddy server to their own log, with its own format and output.
+
+		A vars handler setting access_logger_names is prepended to the route, which is what the log_name Caddyfile directive does, and a custom log including only that access logger is added to logging/logs. The default log excludes it so the entries are not written twice.
+		The updated configuration is applied to the caddy server and returned in JSON format.
+
+		Notes:
+			Access logs are only written for servers with a logs block. If the server has none an empty one is created, which also enables access logging of the server's other routes to the default log.
+			The logger_names and skip_hosts settings of the server's logs block are per host; a route's access_logger_names takes precedence over logger_names, but skip_hosts still applies to the route.
+			Caddy cannot keep only some fields of a log entry; use exclude_fields to delete fields instead, for example request>headers>Authorization.
+		`),
+		mcp.WithString("server_name",
+			mcp.Required(),
+			mcp.Description("The name of the server in apps/http/servers"),
+		),
+		mcp.WithString("route",
+			mcp.Required(),
+			mcp.Description("The route index or a host matched by the route"),
+		),
+		mcp.WithString("log_name",
+			mcp.Description("The name of the log in logging/logs (default: the route's @id, or the server name and route index)"),
+		),
+		mcp.WithString("format",
+			mcp.Description("The log encoding: json or console"),
+			mcp.Enum("json", "console"),
+			mcp.DefaultString("json"),
+		),
+		mcp.WithString("output",
+			mcp.Description("Where to write the log: stderr, stdout, discard or the path of a file (default stderr)"),
+			mcp.DefaultString("stderr"),
+		),
+		mcp.WithArray("exclude_fields",
+			mcp.Description("Fields to delete from each entry, using > to separate nested keys"),
+			mcp.Items(map[string]any{"type": "string"}),
+		),
+	)
+
+	// Add set route access log tool handler
+	s.AddTool(setRouteAccessLog, setRouteAccessLogHandler)
+
+	setLogSampling := mcp.NewTool("set_log_sampling",
+		mcp.WithDescription(`
+		Use the set_log_sampling tool to limit how many access log entries a busy caddy server writes.
+
+		Within each interval the first entries with the same message are written, then only one of every thereafter entries. The access logs of the server are written to their own log in logging/logs, named after the server's default_logger_name, and sampling is configured on that log.
+		The updated configuration is applied to the caddy server and returned in JSON format.
+
+		Notes:
+			Sampling is done per log, not per route. When the server has no default_logger_name it is set to the server name, and a log including only the server's access logs is created that writes JSON to stderr.
+			Requests whose host is mapped by logger_names or routes using the log_name directive go to other logs and are not sampled.
+			Access logs are only written for servers with a logs block; an empty one is created when missing.
+			Set enabled to false to remove sampling from the log.
+		`),
+		mcp.WithString("server_name",
+			mcp.Required(),
+			mcp.Description("The name of the server in apps/http/servers"),
+		),
+		mcp.WithString("interval",
+			mcp.Description("The window over which entries are sampled, for example 1s"),
+			mcp.DefaultString("1s"),
+		),
+		mcp.WithNumber("first",
+			mcp.Description("The number of entries written in each interval before sampling starts"),
+			mcp.DefaultNumber(100),
+		),
+		mcp.WithNumber("thereafter",
+			mcp.Description("After the first entries, write one of every thereafter entries"),
+			mcp.DefaultNumber(100),
+		),
+		mcp.WithBoolean("enabled",
+			mcp.Description("Whether to sample the access logs; false removes sampling"),
+			mcp.DefaultBool(true),
+		),
+	)
+
+	// Add set log sampling tool handler
+	s.AddTool(setLogSampling, setLogSamplingHandler)
+
+	getRecentLogs := mcp.NewTool("get_recent_logs",
+		mcp.WithDescription(`
+		Use the get_recent_logs tool to see the most recent entries of the caddy access log, for example to debug live traffic after a change.
+
+		The result is a JSON document with the last lines of the access log file given with -access-log, oldest first.
+		With summarize set to true, the lines are parsed as caddy's JSON access log entries and the number of requests for each status code is returned as well.
+
+		Notes:
+			Only the file given with -access-log can be read; the tool is disabled without it. The file is read on the host of this MCP server, so it must be the log of a caddy server running there, written with a file output.
+			At most 1000 lines are returned.
+			Lines that are not JSON access log entries, like the console format, are counted as unparsed in the summary.
+		`),
+		mcp.WithNumber("lines",
+			mcp.Description("The number of lines to return, up to 1000"),
+			mcp.DefaultNumber(50),
+		),
+		mcp.WithBoolean("summarize",
+			mcp.Description("Whether to count the requests by status code"),
+			mcp.DefaultBool(false),
+		),
+	)
+
+	// Add get recent logs tool handler
+	s.AddTool(getRecentLogs, getRecentLogsHandler)
+}
+
+// Build the writer of a custom log from an output name or file path
+func logWriter(output string) map[string]any {
+	switch output {
+	case "stderr", "stdout", "discard":
+		return map[string]any{"output": output}
+	default:
+		return map[string]any{"output": "file", "filename": output}
+	}
+}
+
+// Check whether a handler only sets the access logger names of a route
+func isAccessLoggerVars(handler any) bool {
+	h, ok := handler.(map[string]any)
+	if !ok || h["handler"] != "vars" {
+		return false
+	}
+
+	_, ok = h["access_logger_names"]
+	return ok && len(h) == 2
+}
+
+// Keep the entries of a logger out of the default log so they are not written twice
+func excludeFromDefaultLog(logs map[string]any, loggerName string) error {
+	defaultLog, err := configObject(logs, true, "default")
+	if err != nil {
+		return err
+	}
+
+	// The default log may only exclude loggers when it does not restrict what it includes
+	if _, ok := defaultLog["include"]; ok {
+		return nil
+	}
+
+	var exclude []string
+	excluded, _ := defaultLog["exclude"].([]any)
+	for _, e := range excluded {
+		if name, ok := e.(string); ok {
+			exclude = append(exclude, name)
+		}
+	}
+	if !slices.Contains(exclude, loggerName) {
+		exclude = append(exclude, loggerName)
+		sort.Strings(exclude)
+	}
+	defaultLog["exclude"] = exclude
+
+	return nil
+}
+
+// Log the requests of a route to their own custom log
+func setRouteAccessLogHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	serverName, err := request.RequireString("server_name")
+	if err != nil {
+		return nil, err
+	}
+
+	selector, err := request.RequireString("route")
+	if err != nil {
+		return nil, err
+	}
+
+	format := request.GetString("format", "json")
+	if format != "json" && format != "console" {
+		return nil, fmt.Errorf("unsupported format: %s", format)
+	}
+
+	output := request.GetString("output", "stderr")
+	if output == "" {
+		return nil, fmt.Errorf("output is required")
+	}
+
+	cfg, err := fetchConfigMap(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	srv, err := httpServer(cfg, serverName)
+	if err != nil {
+		return nil, err
+	}
+
+	index, route, err := resolveRoute(srv, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	logName := request.GetString("log_name", "")
+	if logName == "" {
+		logName, _ = route["@id"].(string)
+	}
+	if logName == "" {
+		logName = fmt.Sprintf("%s_route_%d", serverName, index)
+	}
+	if !logNameRegexp.MatchString(logName) || logName == "default" {
+		return nil, fmt.Errorf("invalid log name %q: use letters, digits, '-' and '_' and not \"default\"", logName)
+	}
+
+	loggerName := "http.log.access." + logName
+
+	encoder := map[string]any{"format": format}
+	if fields := request.GetStringSlice("exclude_fields", nil); len(fields) > 0 {
+		filters := map[string]any{}
+		for _, field := range fields {
+			filters[field] = map[string]any{"filter": "delete"}
+		}
+		encoder = map[string]any{
+			"format": "filter",
+			"wrap":   encoder,
+			"fields": filters,
+		}
+	}
+
+	logs, err := configObject(cfg, true, "logging", "logs")
+	if err != nil {
+		return nil, err
+	}
+	logs[logName] = map[string]any{
+		"writer":  logWriter(output),
+		"encoder": encoder,
+		"include": []string{loggerName},
+	}
+
+	if err := excludeFromDefaultLog(logs, loggerName); err != nil {
+		return nil, err
+	}
+
+	if _, err := configObject(srv, true, "logs"); err != nil {
+		return nil, err
+	}
+
+	handlers, _ := route["handle"].([]any)
+	handlers = slices.DeleteFunc(handlers, isAccessLoggerVars)
+	route["handle"] = append([]any{
+		map[string]any{
+			"handler":             "vars",
+			"access_logger_names": []string{logName},
+		},
+	}, handlers...)
+
+	return applyConfigMap(ctx, cfg)
+}
+
+// Sample the access logs of a server
+func setLogSamplingHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	serverName, err := request.RequireString("server_name")
+	if err != nil {
+		return nil, err
+	}
+
+	enabled := request.GetBool("enabled", true)
+
+	interval, err := caddy.ParseDuration(request.GetString("interval", "1s"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid interval: %v", err)
+	}
+
+	first := request.GetInt("first", 100)
+	thereafter := request.GetInt("thereafter", 100)
+	if interval <= 0 || first < 1 || thereafter < 1 {
+		return nil, fmt.Errorf("interval, first and thereafter must be positive")
+	}
+
+	cfg, err := fetchConfigMap(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	srv, err := httpServer(cfg, serverName)
+	if err != nil {
+		return nil, err
+	}
+
+	serverLogs, err := configObject(srv, true, "logs")
+	if err != nil {
+		return nil, err
+	}
+
+	logName, _ := serverLogs["default_logger_name"].(string)
+	if logName == "" {
+		if !logNameRegexp.MatchString(serverName) || serverName == "default" {
+			return nil, fmt.Errorf("server %q has no default_logger_name and its name cannot be used as a log name", serverName)
+		}
+		logName = serverName
+		serverLogs["default_logger_name"] = logName
+	}
+
+	loggerName := "http.log.access." + logName
+
+	logs, err := configObject(cfg, true, "logging", "logs")
+	if err != nil {
+		return nil, err
+	}
+
+	log, ok := logs[logName].(map[string]any)
+	if !ok {
+		log = map[string]any{
+			"writer":  logWriter("stderr"),
+			"encoder": map[string]any{"format": "json"},
+			"include": []string{loggerName},
+		}
+		logs[logName] = log
+
+		if err := excludeFromDefaultLog(logs, loggerName); err != nil {
+			return nil, err
+		}
+	}
+
+	if enabled {
+		// LogSampling.Interval is a plain time.Duration, so it is given in nanoseconds
+		log["sampling"] = map[string]any{
+			"interval":   int64(interval),
+			"first":      first,
+			"thereafter": thereafter,
+		}
+	} else {
+		delete(log, "sampling")
+	}
+
+	return applyConfigMap(ctx, cfg)
+}
+
+// Read the last n lines of a file, reading it backwards so large logs are not read whole
+func tailLines(path string, n int) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	const chunkSize = 64 * 1024
+	offset := info.Size()
+	var data []byte
+	for offset > 0 && bytes.Count(data, []byte("\n")) <= n {
+		size := min(chunkSize, offset)
+		offset -= size
+
+		chunk := make([]byte, size)
+		if _, err := f.ReadAt(chunk, offset); err != nil {
+			return nil, err
+		}
+		data = append(chunk, data...)
+	}
+
+	text := strings.TrimRight(string(data), "\n")
+	if text == "" {
+		return []string{}, nil
+	}
+
+	lines := strings.Split(text, "\n")
+	if offset > 0 {
+		// The first line read may start in the middle of a line
+		lines = lines[1:]
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+
+	return lines, nil
+}
+
+// Return the last lines of the access log
+func getRecentLogsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if accessLog == "" {
+		return nil, fmt.Errorf("reading access logs is disabled; start the MCP server with -access-log")
+	}
+
+	n := request.GetInt("lines", 50)
+	if n < 1 {
+		return nil, fmt.Errorf("lines must be at least 1")
+	}
+	n = min(n, maxLogLines)
+
+	lines, err := tailLines(accessLog, n)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the access log: %v", err)
+	}
+
+	result := recentLogs{
+		Path:  accessLog,
+		Lines: lines,
+	}
+
+	if request.GetBool("summarize", false) {
+		result.Statuses = map[int]int{}
+		for _, line := range lines {
+			var entry struct {
+				Status *int `json:"status"`
+			}
+			if err := json.Unmarshal([]byte(line), &entry); err != nil || entry.Status == nil {
+				result.Unparsed++
+				continue
+			}
+			result.Statuses[*entry.Status]++
+		}
+
+		if len(lines) > 0 && result.Unparsed == len(lines) {
+			result.Note = "no line is a JSON access log entry; configure the log with the json encoder to summarize it"
+		}
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
+}
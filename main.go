@@ -1,16 +1,22 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
-	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
+	"unicode/utf8"
 
 	"github.com/caddyserver/caddy/v2/caddyconfig"
 	"github.com/mark3labs/mcp-go/mcp"
@@ -19,6 +25,9 @@ import (
 	_ "github.com/caddyserver/caddy/v2/modules/standard"
 )
 
+// The version of caddy-mcp reported to MCP clients and by the caddy_version tool
+const caddyMCPVersion = "1.0.0"
+
 const toolInstructions = `This server is a tool for managing a caddy server instance. It should be used to get the current caddy configuration in JSON format and describe the configuration in a human readable format.
 It can also be used to update the caddy configuration in JSON format using the update_caddy_config tool.
 
@@ -31,9 +40,44 @@ var (
 	client     http.Client
 	defaultURL = "http://127.0.0.1:2019"
 	transport  = "stdio"
+	host       = "127.0.0.1"
 	port       = 7000
+	envDir     = ""
+	backupDir  = ""
+	instances  = ""
+
+	requireConfirmation = false
+	allowReset          = false
+	allowStop           = false
+	requireTicket       = ""
+	adminToken          = ""
+	adminCert           = ""
+	adminKey            = ""
+	adminCA             = ""
+	adminInsecure       = false
+	timeout             = 10 * time.Second
+	caddyfileDir        = ""
+	accessLog           = ""
+	maxResponseBytes    = 100000
+	retries             = 2
+	undoDepth           = 5
+	logLevel            = "info"
+	logFormat           = "text"
+	logRedact           = false
 )
 
+type validationResult struct {
+	Valid bool   `json:"valid"`
+	Error string `json:"error,omitempty"`
+}
+
+type dryRunResult struct {
+	Valid   bool   `json:"valid"`
+	Applied bool   `json:"applied"`
+	Message string `json:"message"`
+	Error   string `json:"error,omitempty"`
+}
+
 type caddyError struct {
 	StatusCode int    `json:"status_code"`
 	Message    string `json:"message"`
@@ -41,25 +85,107 @@ type caddyError struct {
 
 func main() {
 	flag.StringVar(&defaultURL, "url", defaultURL, "The URL of the caddy server")
+	flag.StringVar(&instances, "instances", instances, "Other caddy servers tools can target with their instance argument, as comma separated name=url pairs")
 	flag.StringVar(&transport, "transport", transport, "The transport to use for the MCP server (stdio, sse, httpstream)")
+	flag.StringVar(&host, "host", host, "Address to run the MCP server on, 0.0.0.0 to listen on all interfaces")
 	flag.IntVar(&port, "port", port, "Port to run the MCP server on")
+	flag.StringVar(&envDir, "env-dir", envDir, "Directory to store named environment configurations in")
+	flag.StringVar(&backupDir, "backup-dir", backupDir, "Directory to store configuration backups in")
+	flag.StringVar(&accessLog, "access-log", accessLog, "Caddy access log file the get_recent_logs tool can read")
+	flag.StringVar(&caddyfileDir, "caddyfile-dir", caddyfileDir, "Directory the convert_caddyfile_file_to_json tool can read Caddyfiles from")
+	flag.BoolVar(&allowReset, "allow-reset", allowReset, "Allow the reset_caddy_config tool to clear the caddy configuration")
+	flag.BoolVar(&allowStop, "allow-stop", allowStop, "Allow the stop_caddy tool to stop the caddy server")
+	flag.BoolVar(&requireConfirmation, "require-confirmation", requireConfirmation, "Return proposed configuration changes for review and only apply them through confirm_change")
+	flag.StringVar(&requireTicket, "require-ticket", requireTicket, "Require changes to carry a ticket argument matching this regular expression, for example JIRA-\\d+")
+	flag.StringVar(&adminToken, "admin-token", adminToken, "Bearer token sent in the Authorization header of every admin API request (default $CADDY_ADMIN_TOKEN)")
+	flag.StringVar(&adminCert, "admin-cert", adminCert, "Client certificate file to authenticate to an admin API served over HTTPS")
+	flag.StringVar(&adminKey, "admin-key", adminKey, "Private key file of the -admin-cert client certificate")
+	flag.StringVar(&adminCA, "admin-ca", adminCA, "CA certificate file to verify the admin API's certificate instead of the system pool")
+	flag.BoolVar(&adminInsecure, "admin-insecure", adminInsecure, "Skip verifying the admin API's certificate, for local testing only")
+	flag.IntVar(&undoDepth, "undo-depth", undoDepth, "Number of configurations replaced by update_caddy_config to keep for undo_caddy_config")
+	flag.IntVar(&retries, "retries", retries, "Number of times to retry admin API requests failing with a connection error, or with a 5xx status when they are safe to repeat")
+	flag.DurationVar(&timeout, "timeout", timeout, "Timeout of requests to the caddy admin API, for example 30s for slow certificate provisioning")
+	flag.StringVar(&logLevel, "log-level", logLevel, "The level of the logs written to stderr (debug, info, warn, error)")
+	flag.StringVar(&logFormat, "log-format", logFormat, "The format of the logs written to stderr (text, json)")
+	flag.BoolVar(&logRedact, "log-redact", logRedact, "Leave the request and response bodies out of the debug logs of admin API requests")
+	flag.IntVar(&maxResponseBytes, "max-response-bytes", maxResponseBytes, "Default maximum size of the configuration returned by get_caddy_config, 0 for no limit")
 	flag.Parse()
 
+	if err := applyEnvFlags(); err != nil {
+		fatal(err.Error())
+	}
+
+	if err := setupLogger(logLevel, logFormat); err != nil {
+		fatal(err.Error())
+	}
+
+	// Read the token from the environment after parsing so it never shows up in the usage output
+	if adminToken == "" {
+		adminToken = os.Getenv("CADDY_ADMIN_TOKEN")
+	}
+
+	primaryURL, err := normalizeAdminURL("url", defaultURL)
+	if err != nil {
+		fatal(err.Error())
+	}
+	defaultURL = primaryURL
+
+	if err := parseInstances(instances); err != nil {
+		fatal(err.Error())
+	}
+
 	if port <= 0 || port > 65535 {
-		log.Fatal("Invalid port number.")
+		fatal("invalid port number", "port", port)
+	}
+
+	if timeout <= 0 {
+		fatal("invalid timeout, it must be positive", "timeout", timeout)
+	}
+
+	if maxResponseBytes < 0 {
+		fatal("invalid max response bytes, it must not be negative", "max_response_bytes", maxResponseBytes)
+	}
+
+	if retries < 0 {
+		fatal("invalid retries, it must not be negative", "retries", retries)
+	}
+
+	if undoDepth < 0 {
+		fatal("invalid undo depth, it must not be negative", "undo_depth", undoDepth)
+	}
+
+	if err := compileTicketPattern(requireTicket); err != nil {
+		fatal(err.Error())
 	}
 
 	// Create MCP server
 	s := server.NewMCPServer(
 		"caddy-mcp",
-		"1.0.0",
+		caddyMCPVersion,
 		server.WithToolCapabilities(true),
 		server.WithInstructions(toolInstructions),
+		server.WithToolHandlerMiddleware(loggingMiddleware),
+		server.WithToolHandlerMiddleware(statsMiddleware),
+		server.WithToolHandlerMiddleware(ticketMiddleware),
+		server.WithToolHandlerMiddleware(instanceMiddleware),
+		server.WithToolHandlerMiddleware(configLockMiddleware),
+		server.WithToolFilter(ticketToolFilter),
+		server.WithToolFilter(instanceToolFilter),
 	)
 
 	// Create http client
 	client = http.Client{
-		Timeout: 10 * time.Second,
+		Timeout: timeout,
+	}
+
+	tlsConfig, err := adminTLSConfig()
+	if err != nil {
+		fatal(err.Error())
+	}
+	if tlsConfig != nil {
+		adminTransport := http.DefaultTransport.(*http.Transport).Clone()
+		adminTransport.TLSClientConfig = tlsConfig
+		client.Transport = adminTransport
 	}
 
 	getCaddyConfig := mcp.NewTool("get_caddy_config",
@@ -67,12 +193,27 @@ func main() {
 		Use the get_caddy_config tool to get the current caddy server configuration in JSON format.
 
 		The caddy server will always return a JSON configuration unless there is no configuration currently loaded.
+
+		Notes:
+			Configurations larger than max_bytes are truncated, and the result ends with a note telling how many bytes were left out. Use the get_config_path tool to read specific sections of a large configuration instead.
+			max_bytes defaults to the -max-response-bytes setting of the MCP server; 0 returns the whole configuration.
 		`),
+		mcp.WithNumber("max_bytes",
+			mcp.Description("The maximum number of bytes of configuration to return"),
+		),
 	)
 
 	// Add get Caddy config tool handler
 	s.AddTool(getCaddyConfig, getCaddyConfigHandler)
 
+	caddyConfigResource := mcp.NewResource("caddy://config", "Caddy configuration",
+		mcp.WithResourceDescription("The current caddy server configuration in JSON format"),
+		mcp.WithMIMEType("application/json"),
+	)
+
+	// Add Caddy config resource handler
+	s.AddResource(caddyConfigResource, caddyConfigResourceHandler)
+
 	updateCaddyConfig := mcp.NewTool("update_caddy_config",
 		mcp.WithDescription(`
 		Use the update_caddy_config tool to update the caddy server configuration in JSON format.
@@ -80,26 +221,55 @@ func main() {
 		Notes:
 			You must provide a valid JSON configuration to update the caddy server configuration.
 			You must provide the full JSON configuration and not just a partial configuration.
+			Invalid JSON is rejected before it is sent to caddy, with the byte offset, line and column of the syntax error.
 			You can use the get_caddy_config tool to get the current caddy server configuration in JSON format.
 			If the user provides a YAML configuration, you must convert it to JSON first using the convert_yaml_to_json tool.
 			If the user provides a Nginx configuration, you must convert it to JSON first using the convert_nginx_to_json tool.
 			If the user provides a Caddyfile configuration, you must convert it to JSON first using the convert_caddyfile_to_json tool.
+			On success the result is a JSON document with a warnings array. Caddy only returns warnings in its response when it adapted the configuration; warnings logged while provisioning modules only appear in caddy's logs.
+			The configuration is loaded on the condition that it was not changed since this tool read it to keep for undo. If it was, the tool fails with "config changed underneath you, re-read and retry"; get the current configuration and make the change again.
+			With dry_run set to true, the configuration is only validated like the validate_caddy_config tool and is not sent to caddy. The result tells whether it is valid and, if not, the error, so you can show the user the change before applying it.
 		`),
 		mcp.WithString("json_config",
 			mcp.Required(),
 			mcp.Description("The caddy server JSON configuration to update the caddy server with"),
 		),
+		mcp.WithBoolean("dry_run",
+			mcp.Description("Whether to only validate the configuration without applying it"),
+			mcp.DefaultBool(false),
+		),
 	)
 
 	// Add update Caddy config tool handler
 	s.AddTool(updateCaddyConfig, updateCaddyConfigHandler)
 
+	validateCaddyConfig := mcp.NewTool("validate_caddy_config",
+		mcp.WithDescription(`
+		Use the validate_caddy_config tool to check that a caddy JSON configuration is valid before applying it with the update_caddy_config tool.
+
+		The configuration is decoded strictly and every module is provisioned and validated without starting any server, like the caddy validate command. Nothing is sent to the caddy server.
+		The result is a JSON document telling whether the configuration is valid and, if not, the error.
+
+		Notes:
+			Validation uses the modules compiled into this MCP server. A configuration using plugins that only the running caddy server has is reported as using unknown modules.
+			Problems only found at runtime, like a port already in use, are not detected.
+		`),
+		mcp.WithString("json_config",
+			mcp.Required(),
+			mcp.Description("The caddy server JSON configuration to validate"),
+		),
+	)
+
+	// Add validate Caddy config tool handler
+	s.AddTool(validateCaddyConfig, validateCaddyConfigHandler)
+
 	convertCaddyfileToJSON := mcp.NewTool("convert_caddyfile_to_json",
 		mcp.WithDescription(`
 		Use the convert_caddyfile_to_json tool to convert a caddy server Caddyfile to JSON configuration.
 
 		Notes:
 			You must provide a valid Caddyfile configuration to convert to JSON.
+			Warnings from the adapter, like deprecated or ambiguous directives, are listed after the JSON in a Warnings section with their file and line; they are not part of the JSON configuration.
 		`),
 		mcp.WithString("caddyfile_config",
 			mcp.Required(),
@@ -116,6 +286,7 @@ func main() {
 
 		Notes:
 			You must provide a valid Nginx configuration to convert to JSON.
+			Warnings from the adapter, like deprecated or ambiguous directives, are listed after the JSON in a Warnings section with their file and line; they are not part of the JSON configuration.
 		`),
 		mcp.WithString("nginx_config",
 			mcp.Required(),
@@ -132,6 +303,7 @@ func main() {
 
 		Notes:
 			You must provide a valid YAML configuration to convert to JSON.
+			Warnings from the adapter, like deprecated or ambiguous directives, are listed after the JSON in a Warnings section with their file and line; they are not part of the JSON configuration.
 		`),
 		mcp.WithString("yaml_config",
 			mcp.Required(),
@@ -142,6 +314,30 @@ func main() {
 	// Add convert YAML to JSON tool handler
 	s.AddTool(convertYamlToJSON, yamlToJSON)
 
+	remoteAdaptConfig := mcp.NewTool("remote_adapt_config",
+		mcp.WithDescription(`
+		Use the remote_adapt_config tool to convert a configuration to caddy JSON with the adapters of the running caddy server instead of the ones built into this MCP server.
+
+		The configuration is sent to caddy's /adapt endpoint, so the JSON matches the modules and version of that server. Nothing is loaded.
+		The result is a JSON document with the adapted configuration in result and the adapter's warnings.
+
+		Notes:
+			The type is the name of a config adapter compiled into the caddy server, like caddyfile. Adapters like nginx or yaml are only available if caddy was built with them.
+			Prefer this tool over convert_caddyfile_to_json when the caddy server uses plugins or a different caddy version.
+		`),
+		mcp.WithString("config",
+			mcp.Required(),
+			mcp.Description("The configuration to adapt, for example a Caddyfile"),
+		),
+		mcp.WithString("type",
+			mcp.Description("The name of the config adapter to use"),
+			mcp.DefaultString("caddyfile"),
+		),
+	)
+
+	// Add remote adapt config tool handler
+	s.AddTool(remoteAdaptConfig, remoteAdaptConfigHandler)
+
 	// Add upstream proxy statuses tool handler
 	upstreamProxyStatuses := mcp.NewTool("upstream_proxy_statuses",
 		mcp.WithDescription("Get the current status of the configured reverse proxy upstreams (backends) as a JSON document. This can be used to confirm that the backend proxy servers are running and responding to requests."),
@@ -150,6 +346,45 @@ func main() {
 	// Add upstream proxy statuses tool handler
 	s.AddTool(upstreamProxyStatuses, upstreamProxyStatusesHandler)
 
+	registerDiagnosticTools(s)
+	registerEnvironmentTools(s)
+	registerChangeTools(s)
+	registerHTTPSTools(s)
+	registerRouteTools(s)
+	registerCORSTools(s)
+	registerUploadTools(s)
+	registerBackupTools(s)
+	registerFallbackTools(s)
+	registerMetricsTools(s)
+	registerCertificateTools(s)
+	registerAccessTools(s)
+	registerDocsTools(s)
+	registerCheckTools(s)
+	registerListenerTools(s)
+	registerAutoRevertTools(s)
+	registerUpstreamTools(s)
+	registerTLSTools(s)
+	registerOnDemandTools(s)
+	registerProxyTools(s)
+	registerLogTools(s)
+	registerResourceTools(s)
+	registerCaddyfileTools(s)
+	registerStaticTools(s)
+	registerConfirmTools(s)
+	registerStatsTools(s)
+	registerResetTools(s)
+	registerFragmentTools(s)
+	registerPathTools(s)
+	registerGraphTools(s)
+	registerOverviewTools(s)
+	registerLintTools(s)
+	registerStopTools(s)
+	registerUndoTools(s)
+	registerServerTools(s)
+	registerFormatTools(s)
+	registerModuleTools(s)
+	registerPrompts(s)
+
 	// Check if SSE is enabled then start the server
 	if transport == "sse" {
 		sseServer := server.NewSSEServer(
@@ -157,60 +392,124 @@ func main() {
 			server.WithKeepAlive(true),
 		)
 
-		log.Printf("Starting MCP SSE server on: %d\n", port)
-		if err := sseServer.Start(fmt.Sprintf("0.0.0.0:%d", port)); err != nil {
-			log.Fatalf("Server error: %v\n", err)
-		}
+		slog.Info("starting MCP SSE server", "host", host, "port", port)
+		serveHTTP(sseServer, net.JoinHostPort(host, strconv.Itoa(port)))
 	} else if transport == "httpstream" {
 		streamable := server.NewStreamableHTTPServer(s, server.WithHeartbeatInterval(10*time.Second))
-		log.Printf("Starting MCP Streamable HTTP server on: %d\n", port)
-		if err := streamable.Start(fmt.Sprintf("0.0.0.0:%d", port)); err != nil {
-			log.Fatalf("Server error: %v\n", err)
-		}
+		slog.Info("starting MCP Streamable HTTP server", "host", host, "port", port)
+		serveHTTP(streamable, net.JoinHostPort(host, strconv.Itoa(port)))
 	} else {
 		// Start the MCP server using stdio
 		if err := server.ServeStdio(s); err != nil {
-			log.Fatalf("Server error: %v\n", err)
+			fatal("server error", "error", err)
 		}
 	}
 }
 
+// The SSE and Streamable HTTP servers of mcp-go
+type httpTransport interface {
+	Start(addr string) error
+	Shutdown(ctx context.Context) error
+}
+
+// Run an HTTP transport until it fails or the process gets SIGINT or SIGTERM, then close its sessions
+func serveHTTP(srv httpTransport, addr string) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.Start(addr)
+	}()
+
+	select {
+	case err := <-errc:
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fatal("server error", "error", err)
+		}
+	case <-ctx.Done():
+		slog.Info("shutting down")
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			slog.Warn("failed to shut down cleanly", "error", err)
+		}
+	}
+}
+
+// Set the flags that were not given on the command line from CADDY_MCP_<FLAG> environment variables,
+// for example CADDY_MCP_URL for -url or CADDY_MCP_ADMIN_TOKEN for -admin-token
+func applyEnvFlags() error {
+	set := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	var err error
+	flag.VisitAll(func(f *flag.Flag) {
+		if set[f.Name] || err != nil {
+			return
+		}
+
+		name := "CADDY_MCP_" + strings.ToUpper(strings.ReplaceAll(f.Name, "-", "_"))
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return
+		}
+
+		if e := f.Value.Set(value); e != nil {
+			err = fmt.Errorf("invalid value %q for %s: %v", value, name, e)
+		}
+	})
+
+	return err
+}
+
 // Get the current Caddy JSON configuration
 func getCaddyConfigHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	reqURL, err := url.Parse(fmt.Sprintf("%s/config/", defaultURL))
+	body, err := fetchConfig(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	req := &http.Request{
-		Method: http.MethodGet,
-		URL:    reqURL,
-		Header: make(http.Header),
+	maxBytes := request.GetInt("max_bytes", maxResponseBytes)
+	if maxBytes < 0 {
+		return nil, fmt.Errorf("max_bytes must not be negative")
 	}
 
-	req.Header.Set("Accept", "application/json")
+	return mcp.NewToolResultText(truncateText(string(body), maxBytes)), nil
+}
 
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
+// Cut text to at most maxBytes without splitting a character, noting how much was left out
+func truncateText(text string, maxBytes int) string {
+	if maxBytes == 0 || len(text) <= maxBytes {
+		return text
 	}
 
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to get Caddy configuration: %s", resp.Status)
+	cut := maxBytes
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	return fmt.Sprintf("%s\n\n[truncated: %d of %d bytes omitted; use the get_config_path tool to read specific sections of the configuration]", text[:cut], len(text)-cut, len(text))
+}
+
+// Read the current Caddy JSON configuration as a resource
+func caddyConfigResourceHandler(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	body, err := fetchConfig(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(body) == 0 {
-		return nil, fmt.Errorf("no configuration currently loaded")
-	}
-
-	return mcp.NewToolResultText(fmt.Sprintf("%s", string(body))), nil
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{
+			URI:      request.Params.URI,
+			MIMEType: "application/json",
+			Text:     string(body),
+		},
+	}, nil
 }
 
 // Update the Caddy JSON configuration
@@ -220,53 +519,74 @@ func updateCaddyConfigHandler(ctx context.Context, request mcp.CallToolRequest)
 		return nil, err
 	}
 
-	reqURL, err := url.Parse(fmt.Sprintf("%s/load", defaultURL))
-	if err != nil {
+	if err := checkConfigJSON([]byte(config)); err != nil {
 		return nil, err
 	}
 
-	req := &http.Request{
-		Method: http.MethodPost,
-		URL:    reqURL,
-		Header: make(http.Header),
-		Body:   io.NopCloser(bytes.NewBuffer([]byte(config))),
+	if request.GetBool("dry_run", false) {
+		result := dryRunResult{Valid: true, Message: "valid, not applied"}
+		if err := validateConfig([]byte(config)); err != nil {
+			result = dryRunResult{Message: "invalid, not applied", Error: err.Error()}
+		}
+
+		data, err := json.Marshal(result)
+		if err != nil {
+			return nil, err
+		}
+
+		return mcp.NewToolResultText(string(data)), nil
+	}
+
+	// Snapshot the configuration being replaced so the change can be undone
+	previous, err := fetchConfig(ctx)
+	if err != nil && !errors.Is(err, errNoConfig) {
+		return nil, err
+	}
+
+	body, err := loadConfig(ctx, []byte(config))
+	if err != nil {
+		return caddyErrorResult(err)
 	}
 
-	req.Header.Set("Content-Type", "application/json")
+	if previous != nil {
+		pushUndo(ctx, previous)
+	}
 
-	resp, err := client.Do(req)
+	data, err := json.Marshal(parseLoadResponse(body))
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+// Validate a Caddy JSON configuration without applying it
+func validateCaddyConfigHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	config, err := request.RequireString("json_config")
 	if err != nil {
 		return nil, err
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		caddyerr := &caddyError{
-			StatusCode: resp.StatusCode,
-			Message:    string(body),
-		}
-		data, err := json.Marshal(caddyerr)
-		if err != nil {
-			return nil, err
-		}
-		return mcp.NewToolResultText(fmt.Sprintf("%s", string(data))), nil
+	result := validationResult{Valid: true}
+	if err := validateConfig([]byte(config)); err != nil {
+		result = validationResult{Error: err.Error()}
 	}
 
-	return mcp.NewToolResultText(fmt.Sprintf("%s", body)), nil
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
 }
 
 // Convert configuration to JSON configuration
-func adaptToJSON(format string, input []byte) ([]byte, error) {
+func adaptToJSON(format string, input []byte) ([]byte, []caddyconfig.Warning, error) {
 	var (
-		adapter caddyconfig.Adapter
-		//warnings []caddyconfig.Warning
-		err    error
-		output []byte
+		adapter  caddyconfig.Adapter
+		warnings []caddyconfig.Warning
+		err      error
+		output   []byte
 	)
 
 	switch format {
@@ -277,15 +597,30 @@ func adaptToJSON(format string, input []byte) ([]byte, error) {
 	case "nginx":
 		adapter = caddyconfig.GetAdapter("nginx")
 	default:
-		return nil, fmt.Errorf("unsupported format: %s", format)
+		return nil, nil, fmt.Errorf("unsupported format: %s", format)
 	}
 
-	output, _, err = adapter.Adapt(input, nil)
+	output, warnings, err = adapter.Adapt(input, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to adapt %s: %v", format, err)
+		return nil, nil, fmt.Errorf("failed to adapt %s: %v", format, err)
 	}
 
-	return output, nil
+	return output, warnings, nil
+}
+
+// Format adapter warnings as a section to append to the converted configuration
+func formatWarnings(warnings []caddyconfig.Warning) string {
+	if len(warnings) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("\n\nWarnings:\n")
+	for _, w := range warnings {
+		fmt.Fprintf(&b, "- %s\n", w)
+	}
+
+	return b.String()
 }
 
 // Convert caddy Caddyfile to JSON configuration
@@ -295,12 +630,12 @@ func caddyfileToJSON(ctx context.Context, request mcp.CallToolRequest) (*mcp.Cal
 		return nil, err
 	}
 
-	json, err := adaptToJSON("caddyfile", []byte(config))
+	json, warnings, err := adaptToJSON("caddyfile", []byte(config))
 	if err != nil {
 		return nil, err
 	}
 
-	return mcp.NewToolResultText(fmt.Sprintf("%s", json)), nil
+	return mcp.NewToolResultText(fmt.Sprintf("%s%s", json, formatWarnings(warnings))), nil
 }
 
 // Convert caddy Nginx configuration to JSON configuration
@@ -310,12 +645,12 @@ func nginxToJSON(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToo
 		return nil, err
 	}
 
-	json, err := adaptToJSON("nginx", []byte(config))
+	json, warnings, err := adaptToJSON("nginx", []byte(config))
 	if err != nil {
 		return nil, err
 	}
 
-	return mcp.NewToolResultText(fmt.Sprintf("%s", json)), nil
+	return mcp.NewToolResultText(fmt.Sprintf("%s%s", json, formatWarnings(warnings))), nil
 }
 
 // Convert caddy YAML configuration to JSON configuration
@@ -325,19 +660,70 @@ func yamlToJSON(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallTool
 		return nil, err
 	}
 
-	json, err := adaptToJSON("yaml", []byte(config))
+	json, warnings, err := adaptToJSON("yaml", []byte(config))
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(fmt.Sprintf("%s%s", json, formatWarnings(warnings))), nil
+}
+
+// The /adapt response of the caddy server
+type remoteAdaptResult struct {
+	Result   json.RawMessage       `json:"result"`
+	Warnings []caddyconfig.Warning `json:"warnings"`
+}
+
+// Adapt a configuration to JSON with the running caddy server's /adapt endpoint
+func remoteAdaptConfigHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	config, err := request.RequireString("config")
+	if err != nil {
+		return nil, err
+	}
+
+	adapter := request.GetString("type", "caddyfile")
+	if adapter == "" || strings.ContainsAny(adapter, "/;, ") {
+		return nil, fmt.Errorf("invalid adapter type: %q", adapter)
+	}
+
+	status, body, err := adminRequestHeader(ctx, http.MethodPost, "/adapt", []byte(config), http.Header{
+		"Content-Type": {"text/" + adapter},
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return mcp.NewToolResultText(fmt.Sprintf("%s", json)), nil
+	if status != http.StatusOK {
+		return caddyErrorResult(&caddyError{
+			StatusCode: status,
+			Message:    string(body),
+		})
+	}
+
+	result := remoteAdaptResult{
+		Warnings: []caddyconfig.Warning{},
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse adapt response: %v", err)
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
 }
 
 // Get the current status of the configured reverse proxy upstreams (backends) as a JSON document.
 func upstreamProxyStatusesHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	url := fmt.Sprintf("%s/reverse_proxy/upstreams", defaultURL)
+	url := fmt.Sprintf("%s/reverse_proxy/upstreams", adminURL(ctx))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
 
-	resp, err := client.Get(url)
+	resp, err := adminDo(req)
 	if err != nil {
 		return nil, err
 	}
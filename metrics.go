@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// The metrics reported by caddy_metrics, summed over their label sets
+var keyMetrics = []string{
+	"go_goroutines",
+	"go_memstats_heap_alloc_bytes",
+	"go_memstats_heap_inuse_bytes",
+	"go_memstats_sys_bytes",
+	"process_resident_memory_bytes",
+	"process_cpu_seconds_total",
+	"process_open_fds",
+	"caddy_config_last_reload_successful",
+	"caddy_config_last_reload_success_timestamp_seconds",
+	"caddy_admin_http_requests_total",
+	"caddy_http_requests_total",
+	"caddy_http_requests_in_flight",
+	"caddy_http_request_errors_total",
+	"caddy_reverse_proxy_upstreams_healthy",
+}
+
+type metricValue struct {
+	Name   string  `json:"name"`
+	Value  float64 `json:"value"`
+	Series int     `json:"series"`
+}
+
+func registerMetricsTools(s *server.MCPServer) {
+	caddyMetrics := mcp.NewTool("caddy_metrics",
+		mcp.WithDescription(`
+		Use the caddy_metrics tool to get a quick health snapshot of the caddy process from its Prometheus /metrics endpoint: goroutines, memory, CPU, open files, config reloads and request counts.
+
+		The result is a JSON document with a curated list of metrics. Metrics with labels, like requests per server or handler, are summed over all their label sets, and series tells how many were summed.
+
+		Notes:
+			caddy_http_* metrics are only reported when metrics are enabled in the http app; use the enable_caddy_metrics tool.
+			caddy_reverse_proxy_upstreams_healthy is the number of healthy upstreams when summed.
+			Metrics caddy does not report are left out.
+		`),
+	)
+
+	// Add Caddy metrics tool handler
+	s.AddTool(caddyMetrics, caddyMetricsHandler)
+
+	enableCaddyMetrics := mcp.NewTool("enable_caddy_metrics",
+		mcp.WithDescription(`
+		Use the enable_caddy_metrics tool to turn on HTTP metrics collection in the caddy http app so the /metrics endpoint reports per-handler request metrics.
+
+		The updated configuration is applied to the caddy server and returned in JSON format.
+
+		Notes:
+			Metrics are configured in apps/http/metrics and cover every server. Caddy cannot collect metrics for only some servers: the per-server apps/http/servers/<name>/metrics block is deprecated, and setting it on one server turns on the app-level metrics for all of them. The deprecated blocks are removed and merged into apps/http/metrics.
+			Per-host metrics may use a lot of memory when caddy manages many hosts.
+		`),
+		mcp.WithBoolean("per_host",
+			mcp.Description("Whether to label metrics with the request host"),
+			mcp.DefaultBool(false),
+		),
+	)
+
+	// Add enable Caddy metrics tool handler
+	s.AddTool(enableCaddyMetrics, enableCaddyMetricsHandler)
+
+	disableCaddyMetrics := mcp.NewTool("disable_caddy_metrics",
+		mcp.WithDescription(`
+		Use the disable_caddy_metrics tool to turn off HTTP metrics collection in the caddy http app, for every server.
+
+		The updated configuration is applied to the caddy server and returned in JSON format.
+		`),
+	)
+
+	// Add disable Caddy metrics tool handler
+	s.AddTool(disableCaddyMetrics, disableCaddyMetricsHandler)
+}
+
+// Remove the deprecated per-server metrics blocks, reporting whether any asked for per-host metrics
+func removeServerMetrics(cfg map[string]any) bool {
+	perHost := false
+	for _, name := range httpServerNames(cfg) {
+		srv, err := httpServer(cfg, name)
+		if err != nil {
+			continue
+		}
+
+		if metrics, ok := srv["metrics"].(map[string]any); ok && metrics["per_host"] == true {
+			perHost = true
+		}
+		delete(srv, "metrics")
+	}
+
+	return perHost
+}
+
+// Enable metrics collection in the http app
+func enableCaddyMetricsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	perHost := request.GetBool("per_host", false)
+
+	cfg, err := fetchConfigMap(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	httpApp, err := configObject(cfg, true, "apps", "http")
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := map[string]any{}
+	if removeServerMetrics(cfg) || perHost {
+		metrics["per_host"] = true
+	}
+	httpApp["metrics"] = metrics
+
+	return applyConfigMap(ctx, cfg)
+}
+
+// Disable metrics collection in the http app
+func disableCaddyMetricsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	cfg, err := fetchConfigMap(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	removeServerMetrics(cfg)
+
+	if httpApp, err := configObject(cfg, false, "apps", "http"); err == nil {
+		delete(httpApp, "metrics")
+	}
+
+	return applyConfigMap(ctx, cfg)
+}
+
+// Sum the samples of the wanted metrics in the Prometheus text exposition format
+func parseMetrics(text string, names []string) []metricValue {
+	wanted := map[string]*metricValue{}
+	for _, name := range names {
+		wanted[name] = &metricValue{Name: name}
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// Samples are name{labels} value [timestamp]; label values may contain spaces
+		var name, rest string
+		if open := strings.IndexByte(line, '{'); open >= 0 {
+			end := strings.LastIndexByte(line, '}')
+			if end < open {
+				continue
+			}
+			name, rest = line[:open], line[end+1:]
+		} else {
+			name, rest, _ = strings.Cut(line, " ")
+		}
+
+		metric, ok := wanted[name]
+		if !ok {
+			continue
+		}
+
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			continue
+		}
+		value, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			continue
+		}
+
+		metric.Value += value
+		metric.Series++
+	}
+
+	metrics := []metricValue{}
+	for _, name := range names {
+		if metric := wanted[name]; metric.Series > 0 {
+			metrics = append(metrics, *metric)
+		}
+	}
+
+	return metrics
+}
+
+// Report the key metrics of the caddy process
+func caddyMetricsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	status, body, err := adminRequest(ctx, http.MethodGet, "/metrics", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("failed to get caddy metrics: %d %s", status, http.StatusText(status))
+	}
+
+	data, err := json.Marshal(parseMetrics(string(body), keyMetrics))
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+type moduleNamespace struct {
+	Namespace string   `json:"namespace"`
+	Modules   []string `json:"modules"`
+}
+
+type moduleList struct {
+	Count      int               `json:"count"`
+	Namespaces []moduleNamespace `json:"namespaces"`
+}
+
+func registerModuleTools(s *server.MCPServer) {
+	listCaddyModules := mcp.NewTool("list_caddy_modules",
+		mcp.WithDescription(`
+		Use the list_caddy_modules tool to find out which caddy modules are available, before suggesting configuration that uses a handler, matcher, DNS provider or other module.
+
+		The result is a JSON document with the modules grouped by namespace, for example the http.handlers namespace with file_server and reverse_proxy. The namespace of the top-level apps, like http and tls, is empty.
+		A module is used in the configuration by its name in the field its namespace expects, for example "handler": "file_server" for http.handlers.file_server.
+
+		Notes:
+			The modules listed are the ones compiled into caddy-mcp, which validates and adapts configurations with them. They match the running caddy server when caddy-mcp is built with the same modules; see the Adding Additional Caddy Modules section of the README.
+			With namespace set, only that namespace and the namespaces inside it are listed, so http lists http.handlers, http.matchers and the others.
+		`),
+		mcp.WithString("namespace",
+			mcp.Description("The namespace to list the modules of, for example http.handlers or dns.providers"),
+		),
+	)
+
+	// Add list Caddy modules tool handler
+	s.AddTool(listCaddyModules, listCaddyModulesHandler)
+}
+
+// List the registered caddy modules grouped by namespace
+func listCaddyModulesHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	filter := strings.Trim(request.GetString("namespace", ""), ".")
+
+	grouped := map[string][]string{}
+	count := 0
+	for _, name := range caddy.Modules() {
+		id := caddy.ModuleID(name)
+		namespace := id.Namespace()
+		if filter != "" && namespace != filter && !strings.HasPrefix(namespace, filter+".") {
+			continue
+		}
+
+		grouped[namespace] = append(grouped[namespace], id.Name())
+		count++
+	}
+
+	result := moduleList{
+		Count:      count,
+		Namespaces: []moduleNamespace{},
+	}
+	for _, namespace := range slices.Sorted(maps.Keys(grouped)) {
+		result.Namespaces = append(result.Namespaces, moduleNamespace{
+			Namespace: namespace,
+			Modules:   grouped[namespace],
+		})
+	}
+
+	if filter != "" && result.Count == 0 {
+		return nil, fmt.Errorf("no modules in namespace %q; call list_caddy_modules without a namespace to see all namespaces", filter)
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
+}
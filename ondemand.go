@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+type askTestResult struct {
+	Endpoint   string `json:"endpoint"`
+	Domain     string `json:"domain"`
+	URL        string `json:"url"`
+	Allowed    bool   `json:"allowed"`
+	StatusCode int    `json:"status_code,omitempty"`
+	Latency    string `json:"latency"`
+	Error      string `json:"error,omitempty"`
+	Note       string `json:"note,omitempty"`
+}
+
+func registerOnDemandTools(s *server.MCPServer) {
+	configureOnDemandAsk := mcp.NewTool("configure_ondemand_ask",
+		mcp.WithDescription(`
+		Use the configure_ondemand_ask tool to set the endpoint caddy asks before obtaining a certificate with on-demand TLS.
+
+		Caddy requests the endpoint with the domain in the domain query parameter, for example https://auth.internal/check?domain=example.com, and only obtains the certificate when it answers with a 2xx status.
+		The endpoint is set as the http permission module in apps/tls/automation/on_demand, replacing the deprecated ask field. The updated configuration is applied to the caddy server and returned in JSON format.
+
+		Notes:
+			Without a working endpoint, anyone pointing a domain at the server can make caddy obtain certificates for it. Use the test_ondemand_ask tool to check that the endpoint allows your domains and denies others.
+			This tool only sets the endpoint; on-demand TLS is enabled by the on_demand setting of an automation policy in apps/tls/automation/policies.
+			Caddy does not follow redirects of the endpoint.
+		`),
+		mcp.WithString("endpoint",
+			mcp.Required(),
+			mcp.Description("The http or https URL of the endpoint approving certificates, for example http://localhost:5555/check"),
+		),
+	)
+
+	// Add configure on-demand ask tool handler
+	s.AddTool(configureOnDemandAsk, configureOnDemandAskHandler)
+
+	testOnDemandAsk := mcp.NewTool("test_ondemand_ask",
+		mcp.WithDescription(`
+		Use the test_ondemand_ask tool to check that the on-demand TLS ask endpoint answers as expected for a domain, like caddy would ask it.
+
+		The endpoint is requested with the domain in the domain query parameter. The result is a JSON document with the URL requested, the status code, the latency and whether caddy would allow a certificate, which it does for 2xx statuses only.
+
+		Notes:
+			If endpoint is not provided the endpoint configured in apps/tls/automation/on_demand is tested.
+			Test both a domain that must be allowed and one that must not, like an unknown subdomain.
+			The request is made from the host of this MCP server, not from caddy's, so the result can differ when the endpoint is only reachable from the caddy host. Placeholders like {env.ASK_URL} in the configured endpoint are not replaced.
+		`),
+		mcp.WithString("domain",
+			mcp.Required(),
+			mcp.Description("The domain to ask about, for example example.com"),
+		),
+		mcp.WithString("endpoint",
+			mcp.Description("The endpoint to test instead of the configured one"),
+		),
+	)
+
+	// Add test on-demand ask tool handler
+	s.AddTool(testOnDemandAsk, testOnDemandAskHandler)
+}
+
+// Check that an ask endpoint is an absolute http or https URL
+func validateAskEndpoint(endpoint string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid endpoint %q: it must be an http or https URL, for example http://localhost:5555/check", endpoint)
+	}
+
+	return nil
+}
+
+// Get the ask endpoint of the on-demand TLS configuration, from the http permission module or the deprecated ask field
+func configuredAskEndpoint(cfg map[string]any) (string, error) {
+	onDemand, err := configObject(cfg, false, "apps", "tls", "automation", "on_demand")
+	if err != nil {
+		return "", fmt.Errorf("no on-demand TLS ask endpoint is configured; set one with the configure_ondemand_ask tool or pass endpoint")
+	}
+
+	if permission, ok := onDemand["permission"].(map[string]any); ok {
+		if permission["module"] != "http" {
+			return "", fmt.Errorf("the on-demand TLS permission module is %v, not http, so there is no endpoint to test", permission["module"])
+		}
+		if endpoint, ok := permission["endpoint"].(string); ok && endpoint != "" {
+			return endpoint, nil
+		}
+	}
+
+	if ask, ok := onDemand["ask"].(string); ok && ask != "" {
+		return ask, nil
+	}
+
+	return "", fmt.Errorf("no on-demand TLS ask endpoint is configured; set one with the configure_ondemand_ask tool or pass endpoint")
+}
+
+// Set the endpoint approving on-demand certificates
+func configureOnDemandAskHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	endpoint, err := request.RequireString("endpoint")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateAskEndpoint(endpoint); err != nil {
+		return nil, err
+	}
+
+	cfg, err := fetchConfigMap(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	onDemand, err := configObject(cfg, true, "apps", "tls", "automation", "on_demand")
+	if err != nil {
+		return nil, err
+	}
+
+	delete(onDemand, "ask")
+	onDemand["permission"] = map[string]any{
+		"module":   "http",
+		"endpoint": endpoint,
+	}
+
+	return applyConfigMap(ctx, cfg)
+}
+
+// Ask the on-demand TLS endpoint about a domain like caddy does
+func testOnDemandAskHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	domain, err := request.RequireString("domain")
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := request.GetString("endpoint", "")
+	if endpoint == "" {
+		cfg, err := fetchConfigMap(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		endpoint, err = configuredAskEndpoint(cfg)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result := askTestResult{
+		Endpoint: endpoint,
+		Domain:   domain,
+	}
+
+	if strings.Contains(endpoint, "{") {
+		result.Note = "the endpoint contains placeholders that caddy replaces but this tool does not"
+	}
+
+	if err := validateAskEndpoint(endpoint); err != nil {
+		return nil, err
+	}
+
+	askURL, _ := url.Parse(endpoint)
+	query := askURL.Query()
+	query.Set("domain", domain)
+	askURL.RawQuery = query.Encode()
+	result.URL = askURL.String()
+
+	// Caddy asks without credentials and does not follow redirects, so neither does the test
+	askClient := http.Client{
+		Timeout: client.Timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return fmt.Errorf("following http redirects is not allowed")
+		},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, result.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	resp, err := askClient.Do(req)
+	result.Latency = time.Since(start).Round(time.Millisecond).String()
+	if err != nil {
+		result.Error = err.Error()
+	} else {
+		resp.Body.Close()
+		result.StatusCode = resp.StatusCode
+		result.Allowed = resp.StatusCode >= 200 && resp.StatusCode <= 299
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"sort"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+type serverOverview struct {
+	Name   string   `json:"name"`
+	Listen []string `json:"listen"`
+	Routes int      `json:"routes"`
+}
+
+type caddyOverview struct {
+	Reachable         bool             `json:"reachable"`
+	ConfigLoaded      bool             `json:"config_loaded"`
+	Error             string           `json:"error,omitempty"`
+	Apps              []string         `json:"apps"`
+	Servers           []serverOverview `json:"servers"`
+	TotalRoutes       int              `json:"total_routes"`
+	TLSDomains        int              `json:"tls_domains"`
+	Upstreams         int              `json:"upstreams"`
+	FailingUpstreams  []upstreamStatus `json:"failing_upstreams"`
+	UpstreamStatusErr string           `json:"upstream_status_error,omitempty"`
+}
+
+func registerOverviewTools(s *server.MCPServer) {
+	caddyOverviewTool := mcp.NewTool("caddy_overview",
+		mcp.WithDescription(`
+		Use the caddy_overview tool to get a high-level status of the caddy server in one call, for example at the start of a troubleshooting session.
+
+		The result is a JSON document with whether the admin API is reachable and a configuration is loaded, the loaded apps, each http server with its listen addresses and number of routes, the number of domains with automatic TLS, and the reverse proxy upstreams that are currently failing.
+
+		Notes:
+			Routes count the top-level routes of each server; routes inside subroutes are not counted.
+			TLS domains are the host matchers of the http servers plus the names in apps/tls/certificates/automate.
+			An upstream is failing when caddy counts failed requests to it from passive health checks; use the health_sweep tool to probe upstreams directly.
+		`),
+	)
+
+	// Add Caddy overview tool handler
+	s.AddTool(caddyOverviewTool, caddyOverviewHandler)
+}
+
+// Summarize the state of the caddy server
+func caddyOverviewHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	overview := caddyOverview{
+		Apps:             []string{},
+		Servers:          []serverOverview{},
+		FailingUpstreams: []upstreamStatus{},
+	}
+
+	cfg, err := fetchConfigMap(ctx)
+	switch {
+	case err == nil:
+		overview.Reachable = true
+		overview.ConfigLoaded = true
+	case errors.Is(err, errNoConfig):
+		overview.Reachable = true
+	default:
+		// Errors other than failed requests mean caddy answered
+		var urlErr *url.Error
+		overview.Reachable = !errors.As(err, &urlErr)
+		overview.Error = err.Error()
+	}
+
+	apps, _ := cfg["apps"].(map[string]any)
+	for name := range apps {
+		overview.Apps = append(overview.Apps, name)
+	}
+	sort.Strings(overview.Apps)
+
+	for _, name := range httpServerNames(cfg) {
+		srv, err := httpServer(cfg, name)
+		if err != nil {
+			continue
+		}
+
+		summary := serverOverview{
+			Name:   name,
+			Listen: []string{},
+			Routes: len(serverRoutes(srv)),
+		}
+		listen, _ := srv["listen"].([]any)
+		for _, l := range listen {
+			if address, ok := l.(string); ok {
+				summary.Listen = append(summary.Listen, address)
+			}
+		}
+
+		overview.Servers = append(overview.Servers, summary)
+		overview.TotalRoutes += summary.Routes
+	}
+
+	domains := configDomains(cfg)
+	if certificates, err := configObject(cfg, false, "apps", "tls", "certificates"); err == nil {
+		names, _ := certificates["automate"].([]any)
+		for _, n := range names {
+			if name, ok := n.(string); ok {
+				domains[name] = true
+			}
+		}
+	}
+	overview.TLSDomains = len(domains)
+
+	if overview.Reachable {
+		statuses, err := fetchUpstreamStatuses(ctx)
+		if err != nil {
+			overview.UpstreamStatusErr = err.Error()
+		}
+		overview.Upstreams = len(statuses)
+		for _, status := range statuses {
+			if status.Fails > 0 {
+				overview.FailingUpstreams = append(overview.FailingUpstreams, status)
+			}
+		}
+	}
+
+	data, err := json.Marshal(overview)
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
+}
@@ -0,0 +1,478 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+type pathChangeResult struct {
+	Status int    `json:"status"`
+	Method string `json:"method"`
+	Path   string `json:"path"`
+}
+
+type conditionalUpdateResult struct {
+	ConditionHeld bool        `json:"condition_held"`
+	Path          string      `json:"path"`
+	Expected      any         `json:"expected"`
+	Actual        any         `json:"actual"`
+	Applied       bool        `json:"applied"`
+	Load          *loadResult `json:"load,omitempty"`
+}
+
+func registerPathTools(s *server.MCPServer) {
+	getConfigPath := mcp.NewTool("get_config_path",
+		mcp.WithDescription(`
+		Use the get_config_path tool to get one part of the current caddy server configuration in JSON format, instead of the whole configuration.
+
+		The path is appended to the /config/ endpoint of the admin API, for example apps/http/servers/srv0 returns only that server. Array elements are addressed by index, for example apps/http/servers/srv0/routes/0.
+
+		Notes:
+			Prefer this tool over get_caddy_config when only one app, server or route is needed; full configurations can be large.
+			An error is returned when nothing is configured at the path.
+		`),
+		mcp.WithString("path",
+			mcp.Required(),
+			mcp.Description("The config path to get, for example apps/http/servers/srv0"),
+		),
+	)
+
+	// Add get config path tool handler
+	s.AddTool(getConfigPath, getConfigPathHandler)
+
+	setConfigPath := mcp.NewTool("set_config_path",
+		mcp.WithDescription(`
+		Use the set_config_path tool to change one part of the caddy server configuration without sending the full configuration.
+
+		json_value is POSTed to the /config/ endpoint of the admin API at path. This modifies only the targeted subtree: the value at path is created or replaced, and when path is an array and json_value is not, json_value is appended to it. An array replacing an array is sent with PATCH, since a POST would append it as one element. The rest of the configuration is left untouched.
+		The result is a JSON document with the status returned by caddy.
+
+		Notes:
+			For example, set apps/http/servers/srv0/listen to [":8443"] to change the listen addresses of one server, or POST a route to apps/http/servers/srv0/routes to append it.
+			Use the get_config_path tool first to see the current value at the path.
+			Caddy validates the resulting configuration and rejects the change if it is invalid; the error is returned in the result.
+			path must not be empty; use the update_caddy_config tool to replace the whole configuration.
+		`),
+		mcp.WithString("path",
+			mcp.Required(),
+			mcp.Description("The config path to set, for example apps/http/servers/srv0/listen"),
+		),
+		mcp.WithString("json_value",
+			mcp.Required(),
+			mcp.Description("The JSON value to set at the path"),
+		),
+	)
+
+	// Add set config path tool handler
+	s.AddTool(setConfigPath, setConfigPathHandler)
+
+	deleteConfigPath := mcp.NewTool("delete_config_path",
+		mcp.WithDescription(`
+		Use the delete_config_path tool to remove one part of the caddy server configuration, like a route or a server, without sending the full configuration.
+
+		A DELETE request is sent to the /config/ endpoint of the admin API at path. The result is a JSON document with the status returned by caddy.
+
+		Notes:
+			For example, delete apps/http/servers/srv0/routes/2 to remove the third route of srv0. Later elements of an array move down by one, so get the array again before deleting another element by index.
+			Use the get_config_path tool first to check that the path holds what should be removed.
+			Caddy rejects the change if the remaining configuration is invalid; the error is returned in the result.
+		`),
+		mcp.WithString("path",
+			mcp.Required(),
+			mcp.Description("The config path to delete, for example apps/http/servers/srv0/routes/2"),
+		),
+	)
+
+	// Add delete config path tool handler
+	s.AddTool(deleteConfigPath, deleteConfigPathHandler)
+
+	conditionalUpdate := mcp.NewTool("conditional_update",
+		mcp.WithDescription(`
+		Use the conditional_update tool to update the caddy server configuration only if the current configuration still holds an expected value, like a compare-and-swap.
+
+		The precondition is a JSONPath query against the current configuration and the JSON value expected there. When the value found equals expected, json_config is loaded like the update_caddy_config tool does; otherwise nothing is changed.
+		The result is a JSON document telling whether the condition held, the value found, and whether the configuration was applied with its warnings.
+
+		Notes:
+			The query supports dot and bracket child access and array indexes, for example $.apps.http.servers.srv0.routes[0]['@id'] or $.admin.listen. Wildcards and filters are not supported.
+			A path that does not exist has the value null, so expected null means "only if it does not exist yet", for example to add a route only once.
+			You must provide the full JSON configuration and not just a partial configuration.
+		`),
+		mcp.WithString("json_config",
+			mcp.Required(),
+			mcp.Description("The caddy server JSON configuration to load when the precondition holds"),
+		),
+		mcp.WithString("precondition",
+			mcp.Required(),
+			mcp.Description("The JSONPath query of the value to check, for example $.apps.http.servers.srv0.listen[0]"),
+		),
+		mcp.WithString("expected",
+			mcp.Required(),
+			mcp.Description("The JSON value the query must return, for example \":443\" or null"),
+		),
+	)
+
+	// Add conditional update tool handler
+	s.AddTool(conditionalUpdate, conditionalUpdateHandler)
+}
+
+// Validate a config path and return it without surrounding slashes
+func cleanConfigPath(path string) (string, error) {
+	path = strings.Trim(strings.TrimSpace(path), "/")
+	path = strings.TrimPrefix(path, "config/")
+
+	if strings.Contains(path, "://") || strings.HasPrefix(path, "//") {
+		return "", fmt.Errorf("path must be a config path like apps/http/servers/srv0, not a URL")
+	}
+
+	for _, segment := range strings.Split(path, "/") {
+		if segment == ".." || segment == "." {
+			return "", fmt.Errorf("path must not contain %q segments", segment)
+		}
+	}
+
+	if strings.ContainsAny(path, "?#") {
+		return "", fmt.Errorf("path must not contain a query or fragment")
+	}
+
+	return path, nil
+}
+
+// Get the JSON configuration at a config path
+func fetchConfigPath(ctx context.Context, path string) ([]byte, error) {
+	status, body, err := adminRequest(ctx, http.MethodGet, "/config/"+path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if status != http.StatusOK {
+		return nil, &caddyError{
+			StatusCode: status,
+			Message:    string(body),
+		}
+	}
+
+	if trimmed := bytes.TrimSpace(body); len(trimmed) == 0 || string(trimmed) == "null" {
+		return nil, fmt.Errorf("path not found or empty: %s", path)
+	}
+
+	return body, nil
+}
+
+// Return one subtree of the current configuration
+func getConfigPathHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	path, err := request.RequireString("path")
+	if err != nil {
+		return nil, err
+	}
+
+	path, err = cleanConfigPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := fetchConfigPath(ctx, path)
+	if err != nil {
+		return caddyErrorResult(err)
+	}
+
+	return mcp.NewToolResultText(string(body)), nil
+}
+
+// List the changes made by replacing the value at a config path
+func pathChanges(path string, oldValue, newValue any) []configChange {
+	switch {
+	case oldValue == nil && newValue == nil:
+		return nil
+	case oldValue == nil:
+		return []configChange{{Path: path, Op: "added", New: newValue}}
+	case newValue == nil:
+		return []configChange{{Path: path, Op: "removed", Old: oldValue}}
+	default:
+		return diffJSON(path, oldValue, newValue)
+	}
+}
+
+// Send a change of a config path to Caddy, returning a *caddyError if Caddy rejects it
+func applyConfigPathChange(ctx context.Context, method, path string, body []byte) ([]byte, error) {
+	status, respBody, err := adminRequest(ctx, method, "/config/"+path, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if status != http.StatusOK {
+		return nil, &caddyError{
+			StatusCode: status,
+			Message:    string(respBody),
+		}
+	}
+
+	// Changes made through config paths are writes of this server too
+	if config, err := fetchConfig(ctx); err == nil {
+		recordConfigWrite(ctx, config)
+	}
+
+	return respBody, nil
+}
+
+// Change a config path in Caddy, or propose the change when changes require confirmation
+func changeConfigPath(ctx context.Context, method, path string, body []byte) ([]byte, error) {
+	if !requireConfirmation {
+		return applyConfigPathChange(ctx, method, path, body)
+	}
+
+	var current any
+	if data, err := fetchConfigPath(ctx, path); err == nil {
+		if err := json.Unmarshal(data, &current); err != nil {
+			return nil, err
+		}
+	}
+
+	var proposed any
+	if body != nil {
+		if err := json.Unmarshal(body, &proposed); err != nil {
+			return nil, err
+		}
+	}
+
+	// Appending to an array adds an element instead of replacing the array
+	if elements, ok := current.([]any); ok && method == http.MethodPost {
+		if _, isArray := proposed.([]any); !isArray {
+			proposed = append(slices.Clone(elements), proposed)
+		}
+	}
+
+	return nil, proposeChange(ctx, method, "/config/"+path, pathChanges(path, current, proposed), func(ctx context.Context) ([]byte, error) {
+		return applyConfigPathChange(ctx, method, path, body)
+	})
+}
+
+// Return the result of a config path change
+func pathChangeResultText(method, path string) (*mcp.CallToolResult, error) {
+	data, err := json.Marshal(pathChangeResult{
+		Status: http.StatusOK,
+		Method: method,
+		Path:   path,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+// Set the JSON value at a config path
+func setConfigPathHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	path, err := request.RequireString("path")
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := request.RequireString("json_value")
+	if err != nil {
+		return nil, err
+	}
+
+	path, err = cleanConfigPath(path)
+	if err != nil {
+		return nil, err
+	}
+	if path == "" {
+		return nil, fmt.Errorf("path must not be empty; use the update_caddy_config tool to replace the whole configuration")
+	}
+
+	if !json.Valid([]byte(value)) {
+		return nil, fmt.Errorf("json_value is not valid JSON")
+	}
+
+	method := http.MethodPost
+	if strings.HasPrefix(strings.TrimSpace(value), "[") {
+		if current, err := fetchConfigPath(ctx, path); err == nil && bytes.HasPrefix(bytes.TrimSpace(current), []byte("[")) {
+			method = http.MethodPatch
+		}
+	}
+
+	if _, err := changeConfigPath(ctx, method, path, []byte(value)); err != nil {
+		return caddyErrorResult(err)
+	}
+
+	return pathChangeResultText(method, path)
+}
+
+// Remove the value at a config path
+func deleteConfigPathHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	path, err := request.RequireString("path")
+	if err != nil {
+		return nil, err
+	}
+
+	path, err = cleanConfigPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if path == "" {
+		return nil, fmt.Errorf("path must not be empty; deleting the whole configuration is not allowed")
+	}
+
+	if _, err := changeConfigPath(ctx, http.MethodDelete, path, nil); err != nil {
+		return caddyErrorResult(err)
+	}
+
+	return pathChangeResultText(http.MethodDelete, path)
+}
+
+// Split a JSONPath query into the keys and indexes it selects
+func parseJSONPath(query string) ([]any, error) {
+	query = strings.TrimSpace(query)
+	if !strings.HasPrefix(query, "$") {
+		return nil, fmt.Errorf("JSONPath query must start with $: %s", query)
+	}
+
+	var steps []any
+	rest := query[1:]
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			return nil, fmt.Errorf("recursive descent is not supported: %s", query)
+		case rest[0] == '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			key := rest[:end]
+			if key == "" || key == "*" {
+				return nil, fmt.Errorf("invalid or unsupported key in JSONPath query: %s", query)
+			}
+			steps = append(steps, key)
+			rest = rest[end:]
+		case rest[0] == '[':
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed bracket in JSONPath query: %s", query)
+			}
+			selector := strings.TrimSpace(rest[1:end])
+			rest = rest[end+1:]
+
+			if len(selector) >= 2 && (selector[0] == '\'' || selector[0] == '"') && selector[len(selector)-1] == selector[0] {
+				steps = append(steps, selector[1:len(selector)-1])
+				continue
+			}
+
+			index, err := strconv.Atoi(selector)
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("unsupported selector [%s] in JSONPath query: %s", selector, query)
+			}
+			steps = append(steps, index)
+		default:
+			return nil, fmt.Errorf("invalid JSONPath query: %s", query)
+		}
+	}
+
+	return steps, nil
+}
+
+// Get the value selected by JSONPath steps, or nil when it does not exist
+func selectJSONPath(value any, steps []any) any {
+	for _, step := range steps {
+		switch key := step.(type) {
+		case string:
+			obj, ok := value.(map[string]any)
+			if !ok {
+				return nil
+			}
+			value = obj[key]
+		case int:
+			arr, ok := value.([]any)
+			if !ok || key >= len(arr) {
+				return nil
+			}
+			value = arr[key]
+		}
+	}
+
+	return value
+}
+
+// Select the value of a JSONPath query and check that it equals the expected value
+func checkCondition(current any, steps []any, expected any) (any, bool) {
+	actual := selectJSONPath(current, steps)
+	return actual, reflect.DeepEqual(actual, expected)
+}
+
+// Load a configuration only when a value of the current configuration is as expected
+func conditionalUpdateHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	config, err := request.RequireString("json_config")
+	if err != nil {
+		return nil, err
+	}
+
+	query, err := request.RequireString("precondition")
+	if err != nil {
+		return nil, err
+	}
+
+	expectedJSON, err := request.RequireString("expected")
+	if err != nil {
+		return nil, err
+	}
+
+	steps, err := parseJSONPath(query)
+	if err != nil {
+		return nil, err
+	}
+
+	var expected any
+	if err := json.Unmarshal([]byte(expectedJSON), &expected); err != nil {
+		return nil, fmt.Errorf("expected is not valid JSON: %v", err)
+	}
+
+	// Only a server without configuration is compared as empty; any other failure must not pass for a held condition
+	var current any
+	data, err := fetchConfig(ctx)
+	switch {
+	case errors.Is(err, errNoConfig):
+	case err != nil:
+		return caddyErrorResult(err)
+	default:
+		if err := json.Unmarshal(data, &current); err != nil {
+			return nil, fmt.Errorf("failed to parse Caddy configuration: %v", err)
+		}
+	}
+
+	result := conditionalUpdateResult{
+		Path:     query,
+		Expected: expected,
+	}
+	result.Actual, result.ConditionHeld = checkCondition(current, steps, expected)
+
+	if result.ConditionHeld {
+		body, err := loadConfig(ctx, []byte(config))
+		if err != nil {
+			return caddyErrorResult(err)
+		}
+
+		load := parseLoadResponse(body)
+		result.Applied = true
+		result.Load = &load
+	}
+
+	data, err = json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCleanConfigPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{path: "apps/http/servers/srv0", want: "apps/http/servers/srv0"},
+		{path: "/apps/http/", want: "apps/http"},
+		{path: "  apps/tls  ", want: "apps/tls"},
+		{path: "/config/apps/http", want: "apps/http"},
+		{path: "", want: ""},
+		{path: "http://127.0.0.1:2019/config/apps", wantErr: true},
+		{path: "apps/../admin", wantErr: true},
+		{path: "apps/./http", wantErr: true},
+		{path: "apps/http?pretty=1", wantErr: true},
+		{path: "apps/http#servers", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := cleanConfigPath(tt.path)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("cleanConfigPath(%q) error = %v, want error %v", tt.path, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("cleanConfigPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestParseJSONPath(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    []any
+		wantErr bool
+	}{
+		{query: "$", want: nil},
+		{query: "$.apps.http", want: []any{"apps", "http"}},
+		{query: "$.apps.http.servers.srv0.listen[0]", want: []any{"apps", "http", "servers", "srv0", "listen", 0}},
+		{query: "$['apps'][\"tls\"]", want: []any{"apps", "tls"}},
+		{query: "apps.http", wantErr: true},
+		{query: "$..listen", wantErr: true},
+		{query: "$.apps.*", wantErr: true},
+		{query: "$.routes[-1]", wantErr: true},
+		{query: "$.routes[0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseJSONPath(tt.query)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseJSONPath(%q) error = %v, want error %v", tt.query, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+
+		gotJSON, _ := json.Marshal(got)
+		wantJSON, _ := json.Marshal(tt.want)
+		if string(gotJSON) != string(wantJSON) {
+			t.Errorf("parseJSONPath(%q) = %s, want %s", tt.query, gotJSON, wantJSON)
+		}
+	}
+}
+
+func TestConditionalUpdate(t *testing.T) {
+	fc := newFakeCaddy(t, `{"apps":{"http":{"http_port":8080}}}`)
+
+	update := func(expected string) conditionalUpdateResult {
+		result, err := callTool(conditionalUpdateHandler, "conditional_update", map[string]any{
+			"json_config":  `{"apps":{"http":{"http_port":9090}}}`,
+			"precondition": "$.apps.http.http_port",
+			"expected":     expected,
+		})
+		if err != nil {
+			t.Fatalf("conditional_update: %v", err)
+		}
+
+		var got conditionalUpdateResult
+		if err := json.Unmarshal([]byte(resultText(t, result)), &got); err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	if got := update(`80`); got.ConditionHeld || got.Applied {
+		t.Errorf("conditional_update with a failing precondition = %+v, want it not applied", got)
+	}
+	if fc.loadCount() != 0 {
+		t.Fatalf("conditional_update loaded a configuration although its precondition failed")
+	}
+
+	if got := update(`8080`); !got.ConditionHeld || !got.Applied {
+		t.Errorf("conditional_update with a holding precondition = %+v, want it applied", got)
+	}
+	if got := fc.current(); got != `{"apps":{"http":{"http_port":9090}}}` {
+		t.Errorf("configuration after conditional_update = %s, want the new one", got)
+	}
+}
+
+func TestCheckCondition(t *testing.T) {
+	var current any
+	if err := json.Unmarshal([]byte(`{
+		"apps": {
+			"http": {
+				"servers": {
+					"srv0": {
+						"listen": [":443"],
+						"routes": [{"@id": "api"}]
+					}
+				},
+				"http_port": 8080
+			}
+		}
+	}`), &current); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		current  any
+		query    string
+		expected string
+		wantHeld bool
+	}{
+		{name: "string matches", current: current, query: "$.apps.http.servers.srv0.listen[0]", expected: `":443"`, wantHeld: true},
+		{name: "string differs", current: current, query: "$.apps.http.servers.srv0.listen[0]", expected: `":80"`, wantHeld: false},
+		{name: "number matches", current: current, query: "$.apps.http.http_port", expected: `8080`, wantHeld: true},
+		{name: "object matches", current: current, query: "$.apps.http.servers.srv0.routes[0]", expected: `{"@id": "api"}`, wantHeld: true},
+		{name: "array differs", current: current, query: "$.apps.http.servers.srv0.listen", expected: `[":443", ":80"]`, wantHeld: false},
+		{name: "missing value is null", current: current, query: "$.apps.tls", expected: `null`, wantHeld: true},
+		{name: "missing value is not an object", current: current, query: "$.apps.tls", expected: `{}`, wantHeld: false},
+		{name: "index out of range", current: current, query: "$.apps.http.servers.srv0.listen[3]", expected: `":443"`, wantHeld: false},
+		{name: "no configuration is null", current: nil, query: "$.apps", expected: `null`, wantHeld: true},
+		{name: "no configuration has no value", current: nil, query: "$.apps.http.http_port", expected: `8080`, wantHeld: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			steps, err := parseJSONPath(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var expected any
+			if err := json.Unmarshal([]byte(tt.expected), &expected); err != nil {
+				t.Fatal(err)
+			}
+
+			_, held := checkCondition(tt.current, steps, expected)
+			if held != tt.wantHeld {
+				t.Errorf("checkCondition(%s, %s) held = %v, want %v", tt.query, tt.expected, held, tt.wantHeld)
+			}
+		})
+	}
+}
+
+func TestConditionalUpdateUnreadableConfig(t *testing.T) {
+	var loads int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		loads++
+	}))
+	defer srv.Close()
+
+	oldURL, oldRetries := defaultURL, retries
+	defaultURL, retries = srv.URL, 0
+	defer func() { defaultURL, retries = oldURL, oldRetries }()
+
+	// A missing value compares equal to null, so an unreadable configuration must not pass for an empty one
+	_, err := callTool(conditionalUpdateHandler, "conditional_update", map[string]any{
+		"json_config":  `{"apps":{}}`,
+		"precondition": "$.apps.tls",
+		"expected":     `null`,
+	})
+	if err == nil {
+		t.Errorf("conditional_update with an unreadable configuration returned no error")
+	}
+	if loads != 0 {
+		t.Errorf("conditional_update loaded a configuration although the current one could not be read")
+	}
+}
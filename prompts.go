@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const safeAddReverseProxyPrompt = `Add a reverse proxy for %[1]s to the backend %[2]s on the caddy server, following these steps and stopping to report to me if any step fails:
+
+1. Get the current configuration with the get_caddy_config tool. Keep it, it is the configuration to go back to.
+2. Check with the list_routes tool that no route already matches %[1]s. If one does, show it to me and ask whether to replace it instead of adding a second route.
+3. Add a route to the configuration with a host matcher for %[1]s and a reverse_proxy handler with the upstream %[2]s, marked terminal, before any catch-all route of the server. Do not change anything else in the configuration.
+4. Validate the full updated configuration with the validate_caddy_config tool and fix any error it reports.
+5. Show me the changes with the diff_caddy_config tool.
+6. Apply the full updated configuration with the update_caddy_config tool.
+7. Check that the backend answers with the get_upstream_status tool for %[2]s and that %[1]s will get a certificate with the check_https_readiness tool.
+
+If the new route does not work, restore the configuration from step 1 with the update_caddy_config tool.`
+
+func registerPrompts(s *server.MCPServer) {
+	safeAddReverseProxy := mcp.NewPrompt("safe_add_reverse_proxy",
+		mcp.WithPromptDescription("Safely add a reverse proxy for a domain to a backend: get the configuration, add a route, validate, review and update"),
+		mcp.WithArgument("domain",
+			mcp.ArgumentDescription("The domain to proxy, for example example.com"),
+			mcp.RequiredArgument(),
+		),
+		mcp.WithArgument("backend",
+			mcp.ArgumentDescription("The backend to proxy to as host:port, for example localhost:8080"),
+			mcp.RequiredArgument(),
+		),
+	)
+
+	// Add safe add reverse proxy prompt handler
+	s.AddPrompt(safeAddReverseProxy, safeAddReverseProxyHandler)
+}
+
+// Build the step-by-step instructions for adding a reverse proxy safely
+func safeAddReverseProxyHandler(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	domain := request.Params.Arguments["domain"]
+	if domain == "" {
+		return nil, fmt.Errorf("domain is required")
+	}
+
+	backend := request.Params.Arguments["backend"]
+	if err := validateUpstream(backend); err != nil {
+		return nil, err
+	}
+
+	return mcp.NewGetPromptResult(
+		fmt.Sprintf("Safely add a reverse proxy for %s to %s", domain, backend),
+		[]mcp.PromptMessage{
+			mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(fmt.Sprintf(safeAddReverseProxyPrompt, domain, backend))),
+		},
+	), nil
+}
@@ -0,0 +1,432 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Top-level config sections kept when a generated config replaces the current one
+var preservedConfigSections = []string{"admin", "logging", "storage"}
+
+func registerProxyTools(s *server.MCPServer) {
+	setupSimpleProxy := mcp.NewTool("setup_simple_proxy",
+		mcp.WithDescription(`
+		Use the setup_simple_proxy tool to generate a configuration that proxies every request to a single upstream while preserving the original Host header.
+
+		The generated configuration has a single server with one catch-all reverse_proxy route and is returned in JSON format.
+
+		Notes:
+			The configuration is only applied to the caddy server when apply is true. Applying it replaces all existing servers; the admin, logging and storage settings of the current configuration are kept.
+		`),
+		mcp.WithString("upstream",
+			mcp.Required(),
+			mcp.Description("The upstream to proxy to as host:port, for example localhost:8080"),
+		),
+		mcp.WithString("listen",
+			mcp.Description("The address to listen on (default :80)"),
+			mcp.DefaultString(":80"),
+		),
+		mcp.WithBoolean("apply",
+			mcp.Description("Whether to apply the generated configuration to the caddy server"),
+			mcp.DefaultBool(false),
+		),
+	)
+
+	// Add setup simple proxy tool handler
+	s.AddTool(setupSimpleProxy, setupSimpleProxyHandler)
+
+	setProxyResponseTimeout := mcp.NewTool("set_proxy_response_timeout",
+		mcp.WithDescription(`
+		Use the set_proxy_response_timeout tool to set how long the reverse_proxy handlers of a route wait for a slow upstream to respond before giving up.
+
+		The timeout is set as response_header_timeout on the http transport of every reverse_proxy handler in the route, including handlers nested in subroutes. It starts once the request has been sent and ends when the upstream begins its response; when it expires the client gets a 504 Gateway Timeout.
+		The updated configuration is applied to the caddy server and returned in JSON format.
+
+		Notes:
+			This is not dial_timeout (connecting to the upstream), read_timeout or write_timeout (a single read or write on the connection). Use this one when a backend is slow to produce its response.
+			Durations use Go syntax with an optional "d" unit, for example 30s, 2m or 1d.
+			Only reverse_proxy handlers using the http transport are supported; the fastcgi transport has no response timeout.
+		`),
+		mcp.WithString("server_name",
+			mcp.Required(),
+			mcp.Description("The name of the server in apps/http/servers"),
+		),
+		mcp.WithString("route",
+			mcp.Required(),
+			mcp.Description("The route index or a host matched by the route"),
+		),
+		mcp.WithString("timeout",
+			mcp.Required(),
+			mcp.Description("How long to wait for the upstream's response, for example 60s"),
+		),
+	)
+
+	// Add set proxy response timeout tool handler
+	s.AddTool(setProxyResponseTimeout, setProxyResponseTimeoutHandler)
+
+	setupCanary := mcp.NewTool("setup_canary",
+		mcp.WithDescription(`
+		Use the setup_canary tool to split the traffic of a host between a stable and a canary upstream, for canary deployments or A/B tests.
+
+		Provide either header_name and header_value, or percent:
+			With a header, a route sends requests carrying the header value to the canary upstream and every other request to the stable upstream.
+			With a percentage, one reverse proxy balances between both upstreams with the weighted_round_robin selection policy so the canary gets that share of requests.
+		A route for the host is added, replacing the one from a previous call for the same host and server, before the fallback route of the server.
+		The updated configuration is applied to the caddy server and returned in JSON format.
+
+		Notes:
+			Percentage splits are per request: the same client can reach both versions. Use a header, for example a cookie set by the application or a header set by a client, when users must stick to one version.
+			An unavailable upstream is skipped by the percentage split, so the other upstream takes all traffic.
+			Routes added earlier for the same host come first and still take precedence; remove them once the canary route replaces them.
+		`),
+		mcp.WithString("server_name",
+			mcp.Required(),
+			mcp.Description("The name of the server in apps/http/servers"),
+		),
+		mcp.WithString("host",
+			mcp.Required(),
+			mcp.Description("The host whose traffic is split, for example app.example.com"),
+		),
+		mcp.WithString("stable_upstream",
+			mcp.Required(),
+			mcp.Description("The upstream of the stable version as host:port"),
+		),
+		mcp.WithString("canary_upstream",
+			mcp.Required(),
+			mcp.Description("The upstream of the canary version as host:port"),
+		),
+		mcp.WithString("header_name",
+			mcp.Description("The request header selecting the canary, for example X-Canary"),
+		),
+		mcp.WithString("header_value",
+			mcp.Description("The value of header_name selecting the canary; * matches any value"),
+		),
+		mcp.WithNumber("percent",
+			mcp.Description("The percentage of requests sent to the canary, from 1 to 99"),
+		),
+	)
+
+	// Add setup canary tool handler
+	s.AddTool(setupCanary, setupCanaryHandler)
+
+	addReverseProxy := mcp.NewTool("add_reverse_proxy",
+		mcp.WithDescription(`
+		Use the add_reverse_proxy tool to proxy the requests for a host to an upstream, for example "proxy example.com to localhost:8080", without writing the route JSON.
+
+		A route with a host matcher and a reverse_proxy handler is added to the server, replacing the one from a previous call for the same host and server, before the fallback route of the server.
+		The updated configuration is applied to the caddy server and returned in JSON format.
+
+		Notes:
+			server can be omitted when the configuration has a single server.
+			Routes added earlier for the same host come first and still take precedence; use the list_routes tool to check.
+		`),
+		mcp.WithString("host",
+			mcp.Required(),
+			mcp.Description("The host to proxy, for example example.com"),
+		),
+		mcp.WithString("upstream",
+			mcp.Required(),
+			mcp.Description("The upstream to proxy to as host:port, for example localhost:8080"),
+		),
+		mcp.WithString("server",
+			mcp.Description("The name of the server in apps/http/servers"),
+		),
+	)
+
+	// Add add reverse proxy tool handler
+	s.AddTool(addReverseProxy, addReverseProxyHandler)
+}
+
+// Build a reverse_proxy handler for a single upstream
+func reverseProxyHandler(upstream string) map[string]any {
+	return map[string]any{
+		"handler": "reverse_proxy",
+		"upstreams": []any{
+			map[string]any{"dial": upstream},
+		},
+	}
+}
+
+// Generate a config proxying everything to one upstream
+func setupSimpleProxyHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	upstream, err := request.RequireString("upstream")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateUpstream(upstream); err != nil {
+		return nil, err
+	}
+
+	listen := request.GetString("listen", ":80")
+
+	proxy := reverseProxyHandler(upstream)
+	proxy["headers"] = map[string]any{
+		"request": map[string]any{
+			"set": map[string]any{
+				"Host": []string{"{http.request.hostport}"},
+			},
+		},
+	}
+
+	cfg := map[string]any{
+		"apps": map[string]any{
+			"http": map[string]any{
+				"servers": map[string]any{
+					"srv0": map[string]any{
+						"listen": []string{listen},
+						"routes": []any{
+							map[string]any{
+								"handle":   []any{proxy},
+								"terminal": true,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	if !request.GetBool("apply", false) {
+		data, err := json.Marshal(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+
+	// Only a server without configuration starts from scratch; a failed read must not replace the live configuration
+	current, err := fetchConfigMap(ctx)
+	if err != nil && !errors.Is(err, errNoConfig) {
+		return nil, err
+	}
+	for _, section := range preservedConfigSections {
+		if value, ok := current[section]; ok {
+			cfg[section] = value
+		}
+	}
+
+	return applyConfigMap(ctx, cfg)
+}
+
+// Set the response timeout of the reverse_proxy handlers of a route
+func setProxyResponseTimeoutHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	serverName, err := request.RequireString("server_name")
+	if err != nil {
+		return nil, err
+	}
+
+	selector, err := request.RequireString("route")
+	if err != nil {
+		return nil, err
+	}
+
+	timeout, err := request.RequireString("timeout")
+	if err != nil {
+		return nil, err
+	}
+
+	duration, err := caddy.ParseDuration(timeout)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timeout %q: %v", timeout, err)
+	}
+	if duration <= 0 {
+		return nil, fmt.Errorf("timeout must be positive")
+	}
+
+	cfg, err := fetchConfigMap(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	srv, err := httpServer(cfg, serverName)
+	if err != nil {
+		return nil, err
+	}
+
+	index, route, err := resolveRoute(srv, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	var proxies []map[string]any
+	walkHandlers([]any{route}, func(handler map[string]any) {
+		if handler["handler"] == "reverse_proxy" {
+			proxies = append(proxies, handler)
+		}
+	})
+	if len(proxies) == 0 {
+		return nil, fmt.Errorf("route %d has no reverse_proxy handler", index)
+	}
+
+	for _, proxy := range proxies {
+		transport, ok := proxy["transport"].(map[string]any)
+		if !ok {
+			transport = map[string]any{"protocol": "http"}
+		}
+
+		if protocol, _ := transport["protocol"].(string); protocol != "http" {
+			return nil, fmt.Errorf("route %d has a reverse_proxy using the %q transport, which has no response timeout", index, protocol)
+		}
+
+		transport["response_header_timeout"] = timeout
+		proxy["transport"] = transport
+	}
+
+	return applyConfigMap(ctx, cfg)
+}
+
+// Split the traffic of a host between a stable and a canary upstream
+func setupCanaryHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	serverName, err := request.RequireString("server_name")
+	if err != nil {
+		return nil, err
+	}
+
+	host, err := request.RequireString("host")
+	if err != nil {
+		return nil, err
+	}
+
+	stable, err := request.RequireString("stable_upstream")
+	if err != nil {
+		return nil, err
+	}
+
+	canary, err := request.RequireString("canary_upstream")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, upstream := range []string{stable, canary} {
+		if err := validateUpstream(upstream); err != nil {
+			return nil, err
+		}
+	}
+
+	headerName := request.GetString("header_name", "")
+	headerValue := request.GetString("header_value", "")
+	percent := request.GetInt("percent", 0)
+
+	var handler map[string]any
+	switch {
+	case headerName != "" && percent != 0:
+		return nil, fmt.Errorf("provide either header_name or percent, not both")
+	case headerName != "":
+		if headerValue == "" {
+			return nil, fmt.Errorf("header_value is required with header_name")
+		}
+
+		handler = map[string]any{
+			"handler": "subroute",
+			"routes": []any{
+				map[string]any{
+					"match": []any{
+						map[string]any{
+							"header": map[string]any{headerName: []string{headerValue}},
+						},
+					},
+					"handle":   []any{reverseProxyHandler(canary)},
+					"terminal": true,
+				},
+				map[string]any{
+					"handle": []any{reverseProxyHandler(stable)},
+				},
+			},
+		}
+	case percent != 0:
+		if percent < 1 || percent > 99 {
+			return nil, fmt.Errorf("percent must be between 1 and 99")
+		}
+
+		// Reduce the weights so the round robin cycle is as short as possible
+		divisor := 100
+		for percent%divisor != 0 || (100-percent)%divisor != 0 {
+			divisor--
+		}
+
+		handler = map[string]any{
+			"handler": "reverse_proxy",
+			"upstreams": []any{
+				map[string]any{"dial": stable},
+				map[string]any{"dial": canary},
+			},
+			"load_balancing": map[string]any{
+				"selection_policy": map[string]any{
+					"policy":  "weighted_round_robin",
+					"weights": []int{(100 - percent) / divisor, percent / divisor},
+				},
+			},
+		}
+	default:
+		return nil, fmt.Errorf("provide header_name and header_value, or percent")
+	}
+
+	cfg, err := fetchConfigMap(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	srv, err := httpServer(cfg, serverName)
+	if err != nil {
+		return nil, err
+	}
+
+	insertRoute(srv, serverName, map[string]any{
+		"@id": generatedRouteID("canary", serverName, host),
+		"match": []any{
+			map[string]any{"host": []string{host}},
+		},
+		"handle":   []any{handler},
+		"terminal": true,
+	})
+
+	return applyConfigMap(ctx, cfg)
+}
+
+// Add a route proxying a host to an upstream
+func addReverseProxyHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	host, err := request.RequireString("host")
+	if err != nil {
+		return nil, err
+	}
+
+	upstream, err := request.RequireString("upstream")
+	if err != nil {
+		return nil, err
+	}
+
+	if host == "" {
+		return nil, fmt.Errorf("host must not be empty")
+	}
+	if err := validateUpstream(upstream); err != nil {
+		return nil, err
+	}
+
+	cfg, err := fetchConfigMap(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	serverName, srv, err := targetServer(cfg, request.GetString("server", ""))
+	if err != nil {
+		return nil, err
+	}
+
+	insertRoute(srv, serverName, map[string]any{
+		"@id": generatedRouteID("proxy", serverName, host),
+		"match": []any{
+			map[string]any{"host": []string{host}},
+		},
+		"handle":   []any{reverseProxyHandler(upstream)},
+		"terminal": true,
+	})
+
+	return applyConfigMap(ctx, cfg)
+}
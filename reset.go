@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+type resetResult struct {
+	Reset          bool            `json:"reset"`
+	PreviousConfig json.RawMessage `json:"previous_config"`
+	Config         json.RawMessage `json:"config"`
+}
+
+func registerResetTools(s *server.MCPServer) {
+	resetCaddyConfig := mcp.NewTool("reset_caddy_config",
+		mcp.WithDescription(`
+		Use the reset_caddy_config tool to clear the caddy server configuration, removing all apps and servers, for example to tear down a test setup.
+
+		The admin, logging and storage settings are kept so caddy stays reachable. The previous configuration is returned in the result so it can be restored with the update_caddy_config tool.
+
+		Notes:
+			This is destructive and only available when the MCP server runs with -allow-reset.
+			confirm must be true; ask the user before resetting.
+		`),
+		mcp.WithBoolean("confirm",
+			mcp.Required(),
+			mcp.Description("Must be true to confirm that every app and server should be removed"),
+		),
+	)
+
+	// Add reset Caddy config tool handler
+	s.AddTool(resetCaddyConfig, resetCaddyConfigHandler)
+}
+
+// Replace the configuration with one without apps, keeping a copy of the previous one
+func resetCaddyConfigHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if !allowReset {
+		return nil, fmt.Errorf("resetting the configuration is disabled; start the MCP server with -allow-reset")
+	}
+
+	if !request.GetBool("confirm", false) {
+		return nil, fmt.Errorf("confirm must be true to reset the configuration")
+	}
+
+	previous, err := fetchConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var current map[string]any
+	if err := json.Unmarshal(previous, &current); err != nil {
+		return nil, err
+	}
+
+	cfg := map[string]any{
+		"apps": map[string]any{},
+	}
+	for _, section := range preservedConfigSections {
+		if value, ok := current[section]; ok {
+			cfg[section] = value
+		}
+	}
+
+	result := resetResult{
+		Reset:          true,
+		PreviousConfig: previous,
+	}
+
+	result.Config, err = loadConfigMap(ctx, cfg)
+	if err != nil {
+		return caddyErrorResult(err)
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Descriptors caddy uses besides listeners and logs: the admin endpoint, storage, DNS and so on
+const baseDescriptors = 32
+
+// Number of descriptors below which a host is considered low on room for connections
+const minConnectionDescriptors = 1024
+
+type listenerEstimate struct {
+	Server  string `json:"server"`
+	Address string `json:"address"`
+	Sockets int    `json:"sockets"`
+	HTTP3   bool   `json:"http3,omitempty"`
+}
+
+type resourceEstimate struct {
+	Listeners             []listenerEstimate `json:"listeners"`
+	ListenerSockets       int                `json:"listener_sockets"`
+	LogFiles              int                `json:"log_files"`
+	ReservedDescriptors   int                `json:"reserved_descriptors"`
+	SoftLimit             uint64             `json:"soft_limit,omitempty"`
+	HardLimit             uint64             `json:"hard_limit,omitempty"`
+	MaxClientConnections  int                `json:"max_client_connections,omitempty"`
+	MaxProxiedConnections int                `json:"max_proxied_connections,omitempty"`
+	Warnings              []string           `json:"warnings"`
+}
+
+func registerResourceTools(s *server.MCPServer) {
+	estimateResourceUsage := mcp.NewTool("estimate_resource_usage",
+		mcp.WithDescription(`
+		Use the estimate_resource_usage tool to check whether a caddy JSON configuration is likely to run out of file descriptors before applying it.
+
+		The listeners of every server are counted, including port ranges, HTTP/3 sockets and the HTTP to HTTPS redirect listener, along with log files. The result is a JSON document comparing the descriptors the configuration needs with the soft file descriptor limit, the number of connections left, and warnings.
+
+		Notes:
+			The limit is read from the process running this MCP server, which is only the same as caddy's when both run on the same host with the same limits (for example the LimitNOFILE of a systemd unit).
+			Each client connection uses one descriptor and each proxied request uses a second one for the upstream, so the connection counts are upper bounds.
+			If json_config is not provided the current caddy server configuration is checked.
+		`),
+		mcp.WithString("json_config",
+			mcp.Description("The caddy JSON configuration to check instead of the current configuration"),
+		),
+	)
+
+	// Add estimate resource usage tool handler
+	s.AddTool(estimateResourceUsage, estimateResourceUsageHandler)
+}
+
+// Check whether a server serves HTTPS, either explicitly or on the default HTTPS port
+func serverUsesTLS(srv map[string]any, httpsPort int) bool {
+	if _, ok := srv["tls_connection_policies"]; ok {
+		return true
+	}
+
+	listen, _ := srv["listen"].([]any)
+	for _, l := range listen {
+		address, _ := l.(string)
+		addr, err := caddy.ParseNetworkAddress(address)
+		if err == nil && int(addr.StartPort) <= httpsPort && httpsPort <= int(addr.EndPort) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Check whether a server enables HTTP/3
+func serverUsesHTTP3(srv map[string]any) bool {
+	protocols, ok := srv["protocols"].([]any)
+	if !ok {
+		return true
+	}
+
+	return slices.Contains(protocols, any("h3"))
+}
+
+// Count the distinct files written by custom logs
+func logFiles(cfg map[string]any) int {
+	logs, err := configObject(cfg, false, "logging", "logs")
+	if err != nil {
+		return 0
+	}
+
+	files := map[string]bool{}
+	for _, l := range logs {
+		log, _ := l.(map[string]any)
+		writer, _ := log["writer"].(map[string]any)
+		if filename, ok := writer["filename"].(string); ok && writer["output"] == "file" {
+			files[filename] = true
+		}
+	}
+
+	return len(files)
+}
+
+// Estimate the file descriptors a config needs and compare them with the limit
+func estimateResourceUsageHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	value, err := configArgument(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("the configuration must be a JSON object")
+	}
+
+	httpApp, _ := configObject(cfg, false, "apps", "http")
+	httpsPort, httpPort := 443, 80
+	if port, ok := httpApp["https_port"].(float64); ok {
+		httpsPort = int(port)
+	}
+	if port, ok := httpApp["http_port"].(float64); ok {
+		httpPort = int(port)
+	}
+
+	result := resourceEstimate{
+		Listeners: []listenerEstimate{},
+		Warnings:  []string{},
+	}
+
+	needsRedirect := false
+	listensOnHTTP := false
+
+	for _, name := range httpServerNames(cfg) {
+		srv, err := httpServer(cfg, name)
+		if err != nil {
+			continue
+		}
+
+		usesTLS := serverUsesTLS(srv, httpsPort)
+		http3 := usesTLS && serverUsesHTTP3(srv)
+
+		if autoHTTPS, _ := srv["automatic_https"].(map[string]any); usesTLS && autoHTTPS["disable"] != true && autoHTTPS["disable_redirects"] != true {
+			needsRedirect = true
+		}
+
+		listen, _ := srv["listen"].([]any)
+		for _, l := range listen {
+			address, _ := l.(string)
+			addr, err := caddy.ParseNetworkAddress(address)
+			if err != nil {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("server %s: invalid listen address %q: %v", name, address, err))
+				continue
+			}
+
+			if int(addr.StartPort) <= httpPort && httpPort <= int(addr.EndPort) {
+				listensOnHTTP = true
+			}
+
+			sockets := int(addr.PortRangeSize())
+			if http3 && !addr.IsUnixNetwork() {
+				sockets *= 2
+			}
+
+			result.Listeners = append(result.Listeners, listenerEstimate{
+				Server:  name,
+				Address: address,
+				Sockets: sockets,
+				HTTP3:   http3,
+			})
+			result.ListenerSockets += sockets
+		}
+	}
+
+	if needsRedirect && !listensOnHTTP {
+		result.Listeners = append(result.Listeners, listenerEstimate{
+			Server:  "automatic HTTPS redirects",
+			Address: ":" + strconv.Itoa(httpPort),
+			Sockets: 1,
+		})
+		result.ListenerSockets++
+	}
+
+	result.LogFiles = logFiles(cfg)
+	result.ReservedDescriptors = result.ListenerSockets + result.LogFiles + baseDescriptors
+
+	soft, hard, err := fileDescriptorLimit()
+	if err != nil {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("could not read the file descriptor limit: %v", err))
+	} else {
+		result.SoftLimit = soft
+		result.HardLimit = hard
+
+		available := int(min(soft, 1<<30)) - result.ReservedDescriptors
+		switch {
+		case available <= 0:
+			result.Warnings = append(result.Warnings, fmt.Sprintf("the configuration needs about %d descriptors but the soft limit is %d; caddy will fail to open its listeners", result.ReservedDescriptors, soft))
+		case available < minConnectionDescriptors:
+			result.Warnings = append(result.Warnings, fmt.Sprintf("only %d descriptors are left for connections; raise the soft limit (ulimit -n or LimitNOFILE) before serving real traffic", available))
+		}
+
+		if available > 0 {
+			result.MaxClientConnections = available
+			result.MaxProxiedConnections = available / 2
+		}
+
+		if available < minConnectionDescriptors && soft < hard {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("the soft limit %d is below the hard limit %d and can be raised without privileges", soft, hard))
+		}
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
+}
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// Get the soft and hard limits on open file descriptors of this process
+func fileDescriptorLimit() (uint64, uint64, error) {
+	return 0, 0, fmt.Errorf("file descriptor limits are not available on %s", runtime.GOOS)
+}
@@ -0,0 +1,15 @@
+//go:build unix
+
+package main
+
+import "syscall"
+
+// Get the soft and hard limits on open file descriptors of this process
+func fileDescriptorLimit() (uint64, uint64, error) {
+	var limit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &limit); err != nil {
+		return 0, 0, err
+	}
+
+	return uint64(limit.Cur), uint64(limit.Max), nil
+}
@@ -0,0 +1,959 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+type servedDomains struct {
+	Server  string   `json:"server"`
+	Domains []string `json:"domains"`
+}
+
+type routeSummary struct {
+	Index    int      `json:"index"`
+	ID       string   `json:"@id,omitempty"`
+	Hosts    []string `json:"hosts,omitempty"`
+	Paths    []string `json:"paths,omitempty"`
+	Handlers []string `json:"handlers"`
+	Terminal bool     `json:"terminal,omitempty"`
+	Disabled bool     `json:"disabled,omitempty"`
+}
+
+type routeEnabledResult struct {
+	Server  string       `json:"server"`
+	Enabled bool         `json:"enabled"`
+	Changed bool         `json:"changed"`
+	Route   routeSummary `json:"route"`
+}
+
+type serverRouteList struct {
+	Server string         `json:"server"`
+	Routes []routeSummary `json:"routes"`
+}
+
+type clonedRoute struct {
+	Source routeSummary `json:"source"`
+	Clone  routeSummary `json:"clone"`
+}
+
+type routeChange struct {
+	Before  routeSummary   `json:"before"`
+	After   routeSummary   `json:"after"`
+	Changes []configChange `json:"changes"`
+}
+
+type serverRouteDiff struct {
+	Server  string         `json:"server"`
+	Added   []routeSummary `json:"added,omitempty"`
+	Removed []routeSummary `json:"removed,omitempty"`
+	Changed []routeChange  `json:"changed,omitempty"`
+}
+
+type routeDiff struct {
+	Added   int               `json:"added"`
+	Removed int               `json:"removed"`
+	Changed int               `json:"changed"`
+	Servers []serverRouteDiff `json:"servers"`
+}
+
+// The most bytes of a response body test_route returns
+const maxTestBodyBytes = 2048
+
+// The response headers test_route reports
+var testRouteHeaders = []string{"Content-Type", "Content-Length", "Content-Encoding", "Location", "Server", "Via", "Cache-Control", "Alt-Svc", "Strict-Transport-Security"}
+
+type routeTestResult struct {
+	URL        string            `json:"url"`
+	Host       string            `json:"host,omitempty"`
+	StatusCode int               `json:"status_code"`
+	Status     string            `json:"status"`
+	Protocol   string            `json:"protocol"`
+	Latency    string            `json:"latency"`
+	Headers    map[string]string `json:"headers"`
+	Body       string            `json:"body"`
+	Truncated  bool              `json:"truncated,omitempty"`
+}
+
+type keyedRoute struct {
+	key   string
+	index int
+	route map[string]any
+}
+
+func registerRouteTools(s *server.MCPServer) {
+	listServedDomains := mcp.NewTool("list_served_domains",
+		mcp.WithDescription(`
+		Use the list_served_domains tool to list every domain the caddy server is configured to answer for.
+
+		The result is a JSON document with the deduplicated host matcher values of each server, including hosts matched inside subroutes.
+		`),
+	)
+
+	// Add list served domains tool handler
+	s.AddTool(listServedDomains, listServedDomainsHandler)
+
+	listRoutes := mcp.NewTool("list_routes",
+		mcp.WithDescription(`
+		Use the list_routes tool to get an overview of the routes of the caddy servers, for example to debug which route handles a request.
+
+		The result is a JSON document with, for each server, its routes in order with their index, @id, host and path matchers and handler types, including the handlers inside subroutes.
+
+		Notes:
+			Only the routes of server are listed when it is provided, otherwise the routes of every server.
+			Route indexes can be used as route selectors with tools like resolve_route.
+		`),
+		mcp.WithString("server",
+			mcp.Description("The name of the server in apps/http/servers to list the routes of"),
+		),
+	)
+
+	// Add list routes tool handler
+	s.AddTool(listRoutes, listRoutesHandler)
+
+	resolveRouteTool := mcp.NewTool("resolve_route",
+		mcp.WithDescription(`
+		Use the resolve_route tool to check which route of a server a route selector refers to before changing it.
+
+		The result is a JSON document with the route index, its host and path matchers and its handlers, or an error when the selector matches no route or more than one route.
+
+		Notes:
+			A route selector is either the index of the route in the server's routes or a host matched by the route.
+			Tools that take a route selector resolve it the same way.
+		`),
+		mcp.WithString("server",
+			mcp.Required(),
+			mcp.Description("The name of the server in apps/http/servers"),
+		),
+		mcp.WithString("route",
+			mcp.Required(),
+			mcp.Description("The route index or a host matched by the route"),
+		),
+	)
+
+	// Add resolve route tool handler
+	s.AddTool(resolveRouteTool, resolveRouteHandler)
+
+	cloneRoute := mcp.NewTool("clone_route",
+		mcp.WithDescription(`
+		Use the clone_route tool to copy a route from one caddy server and append it to the routes of another server.
+
+		The result is a JSON document summarizing the source route and the new copy.
+
+		Notes:
+			@id values are removed from the copy because ids must be unique across the configuration.
+			The copy is appended as the last route of the target server.
+		`),
+		mcp.WithString("server",
+			mcp.Required(),
+			mcp.Description("The name of the server to copy the route from"),
+		),
+		mcp.WithString("route",
+			mcp.Required(),
+			mcp.Description("The route index or a host matched by the route"),
+		),
+		mcp.WithString("target_server",
+			mcp.Required(),
+			mcp.Description("The name of the server to append the copy to"),
+		),
+	)
+
+	// Add clone route tool handler
+	s.AddTool(cloneRoute, cloneRouteHandler)
+
+	diffRoutes := mcp.NewTool("diff_routes",
+		mcp.WithDescription(`
+		Use the diff_routes tool to preview which routes a new configuration adds, removes or changes compared to the current caddy server configuration.
+
+		The result is a JSON document with, for each server that differs, the added, removed and changed routes. Each route is summarized by its hosts, paths and handlers, and changed routes list the paths that differ inside the route.
+
+		Notes:
+			Routes are matched across the two configurations by their @id, or by their matchers when they have no @id, not by their position. A route whose matchers change is reported as removed and added.
+			Routes that only moved to another position are not reported.
+			Nothing is applied to the caddy server.
+		`),
+		mcp.WithString("json_config",
+			mcp.Required(),
+			mcp.Description("The caddy JSON configuration to compare with the current configuration"),
+		),
+	)
+
+	// Add diff routes tool handler
+	s.AddTool(diffRoutes, diffRoutesHandler)
+
+	setRouteEnabled := mcp.NewTool("set_route_enabled",
+		mcp.WithDescription(`
+		Use the set_route_enabled tool to temporarily disable a route without deleting it, or to enable it again, for example while debugging.
+
+		A disabled route is wrapped unchanged in a subroute behind a matcher that never matches, so it keeps its position and its configuration; enabling it unwraps it again. The result is a JSON document with the route summary and whether it is now enabled.
+
+		Notes:
+			Disabled routes are listed with disabled set to true by the list_routes tool.
+			A disabled route no longer matches its hosts, so select it by index to enable it again.
+			Enabling an enabled route or disabling a disabled one changes nothing.
+		`),
+		mcp.WithString("server",
+			mcp.Required(),
+			mcp.Description("The name of the server in apps/http/servers"),
+		),
+		mcp.WithString("route",
+			mcp.Required(),
+			mcp.Description("The route index or a host matched by the route"),
+		),
+		mcp.WithBoolean("enabled",
+			mcp.Required(),
+			mcp.Description("Whether the route should handle requests"),
+		),
+	)
+
+	// Add set route enabled tool handler
+	s.AddTool(setRouteEnabled, setRouteEnabledHandler)
+
+	testRoute := mcp.NewTool("test_route",
+		mcp.WithDescription(`
+		Use the test_route tool to check end to end that a route works, for example that example.com returns 200 from its backend after adding a reverse proxy.
+
+		A GET request is sent to the url through the caddy server itself, not the admin API. The result is a JSON document with the status code, the protocol, the latency, the main response headers and the first 2048 bytes of the body.
+
+		Notes:
+			Set host to send the request to another address than the domain of the url, for example url https://127.0.0.1/ with host example.com before DNS points to the server. The Host header and the TLS server name are set to host, so the certificate is still verified for it.
+			Redirects are not followed; the Location header tells where the response redirects to.
+			The request uses the -timeout of admin API requests. Certificate errors are returned as errors, which usually means caddy has not obtained a certificate for the domain yet.
+		`),
+		mcp.WithString("url",
+			mcp.Required(),
+			mcp.Description("The http or https URL to request, for example https://example.com/health"),
+		),
+		mcp.WithString("host",
+			mcp.Description("The host name to send in the Host header and as the TLS server name instead of the url's"),
+		),
+	)
+
+	// Add test route tool handler
+	s.AddTool(testRoute, testRouteHandler)
+}
+
+// Get the original route of a route disabled by set_route_enabled, which is wrapped in a
+// subroute behind an expression matcher that never matches
+func disabledRoute(route map[string]any) (map[string]any, bool) {
+	matchSets, _ := route["match"].([]any)
+	handlers, _ := route["handle"].([]any)
+	if len(matchSets) != 1 || len(handlers) != 1 || len(route) != 2 {
+		return nil, false
+	}
+
+	if matchSet, _ := matchSets[0].(map[string]any); len(matchSet) != 1 || matchSet["expression"] != "false" {
+		return nil, false
+	}
+
+	handler, _ := handlers[0].(map[string]any)
+	subroutes, _ := handler["routes"].([]any)
+	if handler["handler"] != "subroute" || len(handler) != 2 || len(subroutes) != 1 {
+		return nil, false
+	}
+
+	inner, ok := subroutes[0].(map[string]any)
+	return inner, ok
+}
+
+// Get the routes of a server in the http app
+func serverRoutes(srv map[string]any) []any {
+	routes, _ := srv["routes"].([]any)
+	return routes
+}
+
+// Resolve a route selector, either an index or a host matcher value, to a top-level route of a server
+func resolveRoute(srv map[string]any, selector string) (int, map[string]any, error) {
+	routes := serverRoutes(srv)
+
+	if index, err := strconv.Atoi(selector); err == nil {
+		if index < 0 || index >= len(routes) {
+			return 0, nil, fmt.Errorf("route index %d out of range: the server has %d routes", index, len(routes))
+		}
+
+		route, ok := routes[index].(map[string]any)
+		if !ok {
+			return 0, nil, fmt.Errorf("route %d is not an object", index)
+		}
+
+		return index, route, nil
+	}
+
+	var matches []int
+	for i, r := range routes {
+		route, ok := r.(map[string]any)
+		if ok && slices.Contains(routeHosts(route), selector) {
+			matches = append(matches, i)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return 0, nil, fmt.Errorf("no route matches host %q", selector)
+	case 1:
+		return matches[0], routes[matches[0]].(map[string]any), nil
+	default:
+		return 0, nil, fmt.Errorf("host %q is ambiguous: it matches routes %v; select the route by index instead", selector, matches)
+	}
+}
+
+// Summarize the matchers and handlers of a route
+func summarizeRoute(index int, route map[string]any) routeSummary {
+	if inner, ok := disabledRoute(route); ok {
+		summary := summarizeRoute(index, inner)
+		summary.Disabled = true
+		return summary
+	}
+
+	summary := routeSummary{
+		Index:    index,
+		Hosts:    routeHosts(route),
+		Handlers: routeHandlers(route),
+	}
+
+	summary.ID, _ = route["@id"].(string)
+	summary.Terminal, _ = route["terminal"].(bool)
+
+	matchSets, _ := route["match"].([]any)
+	for _, m := range matchSets {
+		matchSet, ok := m.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		paths, _ := matchSet["path"].([]any)
+		for _, p := range paths {
+			if path, ok := p.(string); ok {
+				summary.Paths = append(summary.Paths, path)
+			}
+		}
+	}
+
+	return summary
+}
+
+// Get the handler names of a route, including handlers inside subroutes
+func routeHandlers(route map[string]any) []string {
+	names := []string{}
+
+	handlers, _ := route["handle"].([]any)
+	for _, h := range handlers {
+		handler, ok := h.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		name, _ := handler["handler"].(string)
+		names = append(names, name)
+
+		if name == "subroute" {
+			subroutes, _ := handler["routes"].([]any)
+			for _, r := range subroutes {
+				if subroute, ok := r.(map[string]any); ok {
+					names = append(names, routeHandlers(subroute)...)
+				}
+			}
+		}
+	}
+
+	return names
+}
+
+// Call fn for every route, descending into subroute handlers
+func walkRoutes(routes []any, fn func(route map[string]any)) {
+	for _, r := range routes {
+		route, ok := r.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		fn(route)
+
+		handlers, _ := route["handle"].([]any)
+		for _, h := range handlers {
+			handler, ok := h.(map[string]any)
+			if !ok || handler["handler"] != "subroute" {
+				continue
+			}
+
+			subroutes, _ := handler["routes"].([]any)
+			walkRoutes(subroutes, fn)
+		}
+	}
+}
+
+// Call fn for every handler of the routes, descending into subroute handlers
+func walkHandlers(routes []any, fn func(handler map[string]any)) {
+	walkRoutes(routes, func(route map[string]any) {
+		handlers, _ := route["handle"].([]any)
+		for _, h := range handlers {
+			if handler, ok := h.(map[string]any); ok {
+				fn(handler)
+			}
+		}
+	})
+}
+
+// Describe the matcher sets of a route, for example "host=example.com path=/api/*"
+func describeMatchers(route map[string]any) string {
+	matchSets, _ := route["match"].([]any)
+	if len(matchSets) == 0 {
+		return "*"
+	}
+
+	var sets []string
+	for _, m := range matchSets {
+		matchSet, ok := m.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		keys := make([]string, 0, len(matchSet))
+		for key := range matchSet {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		var parts []string
+		for _, key := range keys {
+			switch value := matchSet[key].(type) {
+			case []any:
+				values := make([]string, 0, len(value))
+				for _, v := range value {
+					values = append(values, fmt.Sprint(v))
+				}
+				parts = append(parts, fmt.Sprintf("%s=%s", key, strings.Join(values, ",")))
+			default:
+				parts = append(parts, key)
+			}
+		}
+		sets = append(sets, strings.Join(parts, " "))
+	}
+
+	return strings.Join(sets, " OR ")
+}
+
+// Get the host matcher values of a route
+func routeHosts(route map[string]any) []string {
+	var hosts []string
+
+	matchSets, _ := route["match"].([]any)
+	for _, m := range matchSets {
+		matchSet, ok := m.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		values, _ := matchSet["host"].([]any)
+		for _, v := range values {
+			if host, ok := v.(string); ok {
+				hosts = append(hosts, host)
+			}
+		}
+	}
+
+	return hosts
+}
+
+// Get the names of the http servers in a decoded config, sorted
+func httpServerNames(cfg map[string]any) []string {
+	servers, err := configObject(cfg, false, "apps", "http", "servers")
+	if err != nil {
+		return nil
+	}
+
+	names := make([]string, 0, len(servers))
+	for name := range servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// List the domains each server answers for
+func listServedDomainsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	cfg, err := fetchConfigMap(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []servedDomains{}
+	for _, name := range httpServerNames(cfg) {
+		srv, err := httpServer(cfg, name)
+		if err != nil {
+			return nil, err
+		}
+
+		seen := map[string]bool{}
+		domains := []string{}
+		walkRoutes(serverRoutes(srv), func(route map[string]any) {
+			for _, host := range routeHosts(route) {
+				if !seen[host] {
+					seen[host] = true
+					domains = append(domains, host)
+				}
+			}
+		})
+		sort.Strings(domains)
+
+		result = append(result, servedDomains{
+			Server:  name,
+			Domains: domains,
+		})
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+// Summarize the routes of one or every server
+func listRoutesHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	cfg, err := fetchConfigMap(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	names := httpServerNames(cfg)
+	if name := request.GetString("server", ""); name != "" {
+		names = []string{name}
+	}
+
+	result := []serverRouteList{}
+	for _, name := range names {
+		srv, err := httpServer(cfg, name)
+		if err != nil {
+			return nil, err
+		}
+
+		list := serverRouteList{
+			Server: name,
+			Routes: []routeSummary{},
+		}
+		for i, r := range serverRoutes(srv) {
+			if route, ok := r.(map[string]any); ok {
+				list.Routes = append(list.Routes, summarizeRoute(i, route))
+			}
+		}
+
+		result = append(result, list)
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+// Disable a route by wrapping it behind a matcher that never matches, or enable it by unwrapping it
+func setRouteEnabledHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	serverName, err := request.RequireString("server")
+	if err != nil {
+		return nil, err
+	}
+
+	selector, err := request.RequireString("route")
+	if err != nil {
+		return nil, err
+	}
+
+	enabled, err := request.RequireBool("enabled")
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := fetchConfigMap(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	srv, err := httpServer(cfg, serverName)
+	if err != nil {
+		return nil, err
+	}
+
+	index, route, err := resolveRoute(srv, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	result := routeEnabledResult{
+		Server:  serverName,
+		Enabled: enabled,
+	}
+
+	inner, disabled := disabledRoute(route)
+	routes := serverRoutes(srv)
+	switch {
+	case enabled && disabled:
+		routes[index] = inner
+		result.Changed = true
+	case !enabled && !disabled:
+		routes[index] = map[string]any{
+			"match": []any{
+				map[string]any{"expression": "false"},
+			},
+			"handle": []any{
+				map[string]any{
+					"handler": "subroute",
+					"routes":  []any{route},
+				},
+			},
+		}
+		result.Changed = true
+	}
+
+	if result.Changed {
+		if _, err := loadConfigMap(ctx, cfg); err != nil {
+			return caddyErrorResult(err)
+		}
+	}
+
+	result.Route = summarizeRoute(index, routes[index].(map[string]any))
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+// Resolve a route selector and summarize the route it refers to
+func resolveRouteHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	serverName, err := request.RequireString("server")
+	if err != nil {
+		return nil, err
+	}
+
+	selector, err := request.RequireString("route")
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := fetchConfigMap(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	srv, err := httpServer(cfg, serverName)
+	if err != nil {
+		return nil, err
+	}
+
+	index, route, err := resolveRoute(srv, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(summarizeRoute(index, route))
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+// Deep copy a decoded JSON value, dropping @id keys
+func copyWithoutIDs(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		copied := make(map[string]any, len(v))
+		for key, child := range v {
+			if key != "@id" {
+				copied[key] = copyWithoutIDs(child)
+			}
+		}
+		return copied
+	case []any:
+		copied := make([]any, len(v))
+		for i, child := range v {
+			copied[i] = copyWithoutIDs(child)
+		}
+		return copied
+	default:
+		return v
+	}
+}
+
+// Copy a route from one server to another
+func cloneRouteHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	serverName, err := request.RequireString("server")
+	if err != nil {
+		return nil, err
+	}
+
+	selector, err := request.RequireString("route")
+	if err != nil {
+		return nil, err
+	}
+
+	targetName, err := request.RequireString("target_server")
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := fetchConfigMap(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	srv, err := httpServer(cfg, serverName)
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := httpServer(cfg, targetName)
+	if err != nil {
+		return nil, err
+	}
+
+	index, route, err := resolveRoute(srv, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	clone := copyWithoutIDs(route).(map[string]any)
+	target["routes"] = append(serverRoutes(target), clone)
+
+	if _, err := loadConfigMap(ctx, cfg); err != nil {
+		return caddyErrorResult(err)
+	}
+
+	data, err := json.Marshal(clonedRoute{
+		Source: summarizeRoute(index, route),
+		Clone:  summarizeRoute(len(serverRoutes(target))-1, clone),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+// Key the routes of a server by @id or matchers, numbering routes that share a key
+func keyRoutes(routes []any) []keyedRoute {
+	seen := map[string]int{}
+	var keyed []keyedRoute
+
+	for i, r := range routes {
+		route, ok := r.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		key := "match " + describeMatchers(route)
+		if id, ok := route["@id"].(string); ok && id != "" {
+			key = "@id " + id
+		}
+
+		seen[key]++
+		if seen[key] > 1 {
+			key = fmt.Sprintf("%s #%d", key, seen[key])
+		}
+
+		keyed = append(keyed, keyedRoute{key: key, index: i, route: route})
+	}
+
+	return keyed
+}
+
+// Compare the routes of one server in two configs
+func diffServerRoutes(name string, oldRoutes, newRoutes []any) serverRouteDiff {
+	diff := serverRouteDiff{Server: name}
+
+	oldKeyed := keyRoutes(oldRoutes)
+	oldByKey := map[string]keyedRoute{}
+	for _, r := range oldKeyed {
+		oldByKey[r.key] = r
+	}
+
+	newKeys := map[string]bool{}
+	for _, r := range keyRoutes(newRoutes) {
+		newKeys[r.key] = true
+
+		old, ok := oldByKey[r.key]
+		if !ok {
+			diff.Added = append(diff.Added, summarizeRoute(r.index, r.route))
+			continue
+		}
+
+		if changes := diffJSON("", old.route, r.route); len(changes) > 0 {
+			diff.Changed = append(diff.Changed, routeChange{
+				Before:  summarizeRoute(old.index, old.route),
+				After:   summarizeRoute(r.index, r.route),
+				Changes: changes,
+			})
+		}
+	}
+
+	for _, r := range oldKeyed {
+		if !newKeys[r.key] {
+			diff.Removed = append(diff.Removed, summarizeRoute(r.index, r.route))
+		}
+	}
+
+	return diff
+}
+
+// Compare the routes of a config with the current config
+func diffRoutesHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	config, err := request.RequireString("json_config")
+	if err != nil {
+		return nil, err
+	}
+
+	var proposed map[string]any
+	if err := json.Unmarshal([]byte(config), &proposed); err != nil {
+		return nil, fmt.Errorf("invalid JSON configuration: %v", err)
+	}
+
+	current, err := fetchConfigMap(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	names := httpServerNames(current)
+	for _, name := range httpServerNames(proposed) {
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	result := routeDiff{Servers: []serverRouteDiff{}}
+	for _, name := range names {
+		var oldRoutes, newRoutes []any
+		if srv, err := httpServer(current, name); err == nil {
+			oldRoutes = serverRoutes(srv)
+		}
+		if srv, err := httpServer(proposed, name); err == nil {
+			newRoutes = serverRoutes(srv)
+		}
+
+		diff := diffServerRoutes(name, oldRoutes, newRoutes)
+		if len(diff.Added)+len(diff.Removed)+len(diff.Changed) == 0 {
+			continue
+		}
+
+		result.Added += len(diff.Added)
+		result.Removed += len(diff.Removed)
+		result.Changed += len(diff.Changed)
+		result.Servers = append(result.Servers, diff)
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+// Request a URL through the caddy server and report the response
+func testRouteHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	rawURL, err := request.RequireString("url")
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid url %q: it must be an http or https URL with a host", rawURL)
+	}
+
+	host := request.GetString("host", "")
+
+	// A client of its own, since requests to the sites must not carry the admin token or admin TLS settings
+	siteTransport := http.DefaultTransport.(*http.Transport).Clone()
+	if host != "" {
+		siteTransport.TLSClientConfig = &tls.Config{ServerName: hostWithoutPort(host)}
+	}
+	siteClient := http.Client{
+		Timeout:   timeout,
+		Transport: siteTransport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	defer siteTransport.CloseIdleConnections()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if host != "" {
+		req.Host = host
+	}
+
+	start := time.Now()
+	resp, err := siteClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request to %s failed: %v", u, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxTestBodyBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the response body: %v", err)
+	}
+	latency := time.Since(start)
+
+	result := routeTestResult{
+		URL:        u.String(),
+		Host:       host,
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Protocol:   resp.Proto,
+		Latency:    latency.Round(time.Millisecond).String(),
+		Headers:    map[string]string{},
+	}
+
+	if len(body) > maxTestBodyBytes {
+		body = body[:maxTestBodyBytes]
+		result.Truncated = true
+	}
+	result.Body = strings.ToValidUTF8(string(body), "")
+
+	for _, name := range testRouteHeaders {
+		if value := resp.Header.Get(name); value != "" {
+			result.Headers[name] = value
+		}
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+// Strip the port of a host header value, keeping IPv6 addresses without brackets
+func hostWithoutPort(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+
+	return strings.Trim(host, "[]")
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+const routesTestConfig = `{"apps":{"http":{"servers":{"srv0":{"listen":[":443"],"routes":[
+	{"@id":"api","match":[{"host":["api.example.com"],"path":["/v1/*"]}],"handle":[{"handler":"reverse_proxy"}]},
+	{"match":[{"host":["www.example.com","example.com"]}],"handle":[{"handler":"file_server"}],"terminal":true},
+	{"match":[{"host":["shared.example.com"]}],"handle":[{"handler":"static_response"}]},
+	{"match":[{"host":["shared.example.com"]},{"path":["/old/*"]}],"handle":[{"handler":"static_response"}]}
+]}}}}}`
+
+// Decode the routes test configuration and return its server
+func routesTestServer(t *testing.T) map[string]any {
+	t.Helper()
+
+	var cfg map[string]any
+	if err := json.Unmarshal([]byte(routesTestConfig), &cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	srv, err := httpServer(cfg, "srv0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv
+}
+
+func TestResolveRoute(t *testing.T) {
+	srv := routesTestServer(t)
+
+	tests := []struct {
+		selector  string
+		wantIndex int
+		wantErr   bool
+	}{
+		{selector: "0", wantIndex: 0},
+		{selector: "3", wantIndex: 3},
+		{selector: "4", wantErr: true},
+		{selector: "-1", wantErr: true},
+		{selector: "api.example.com", wantIndex: 0},
+		{selector: "example.com", wantIndex: 1},
+		{selector: "missing.example.com", wantErr: true},
+		{selector: "shared.example.com", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		index, route, err := resolveRoute(srv, tt.selector)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("resolveRoute(%q) error = %v, want error %v", tt.selector, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+
+		if index != tt.wantIndex {
+			t.Errorf("resolveRoute(%q) index = %d, want %d", tt.selector, index, tt.wantIndex)
+		}
+		if !reflect.DeepEqual(route, serverRoutes(srv)[tt.wantIndex]) {
+			t.Errorf("resolveRoute(%q) returned a different route than index %d", tt.selector, tt.wantIndex)
+		}
+	}
+}
+
+func TestRouteHosts(t *testing.T) {
+	srv := routesTestServer(t)
+
+	tests := []struct {
+		index int
+		want  []string
+	}{
+		{index: 0, want: []string{"api.example.com"}},
+		{index: 1, want: []string{"www.example.com", "example.com"}},
+		{index: 3, want: []string{"shared.example.com"}},
+	}
+
+	for _, tt := range tests {
+		route := serverRoutes(srv)[tt.index].(map[string]any)
+		if got := routeHosts(route); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("routeHosts(route %d) = %v, want %v", tt.index, got, tt.want)
+		}
+	}
+}
+
+func TestResolveRouteTool(t *testing.T) {
+	newFakeCaddy(t, routesTestConfig)
+
+	result, err := callTool(resolveRouteHandler, "resolve_route", map[string]any{"server": "srv0", "route": "www.example.com"})
+	if err != nil {
+		t.Fatalf("resolve_route: %v", err)
+	}
+
+	var got routeSummary
+	if err := json.Unmarshal([]byte(resultText(t, result)), &got); err != nil {
+		t.Fatal(err)
+	}
+
+	want := routeSummary{
+		Index:    1,
+		Hosts:    []string{"www.example.com", "example.com"},
+		Handlers: []string{"file_server"},
+		Terminal: true,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("resolve_route = %+v, want %+v", got, want)
+	}
+
+	if _, err := callTool(resolveRouteHandler, "resolve_route", map[string]any{"server": "srv1", "route": "0"}); err == nil {
+		t.Errorf("resolve_route of a missing server returned no error")
+	}
+}
+
+func TestSetRouteEnabled(t *testing.T) {
+	fc := newFakeCaddy(t, routesTestConfig)
+
+	setEnabled := func(enabled bool) routeEnabledResult {
+		result, err := callTool(setRouteEnabledHandler, "set_route_enabled", map[string]any{"server": "srv0", "route": "1", "enabled": enabled})
+		if err != nil {
+			t.Fatalf("set_route_enabled: %v", err)
+		}
+
+		var got routeEnabledResult
+		if err := json.Unmarshal([]byte(resultText(t, result)), &got); err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	if got := setEnabled(false); !got.Changed || got.Enabled {
+		t.Errorf("disabling a route = %+v, want it changed and disabled", got)
+	}
+
+	var cfg map[string]any
+	if err := json.Unmarshal([]byte(fc.current()), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	srv, err := httpServer(cfg, "srv0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The disabled route keeps its position and no longer matches its hosts
+	if _, _, err := resolveRoute(srv, "www.example.com"); err == nil {
+		t.Errorf("a disabled route still matches its hosts")
+	}
+	if len(serverRoutes(srv)) != 4 {
+		t.Errorf("server has %d routes after disabling one, want 4", len(serverRoutes(srv)))
+	}
+
+	loads := fc.loadCount()
+	if got := setEnabled(false); got.Changed {
+		t.Errorf("disabling a disabled route = %+v, want it unchanged", got)
+	}
+	if fc.loadCount() != loads {
+		t.Errorf("disabling a disabled route loaded a configuration")
+	}
+
+	if got := setEnabled(true); !got.Changed || !got.Enabled {
+		t.Errorf("enabling a route = %+v, want it changed and enabled", got)
+	}
+
+	var want map[string]any
+	if err := json.Unmarshal([]byte(routesTestConfig), &want); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(fc.current()), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("configuration after disabling and enabling a route = %s, want the original", fc.current())
+	}
+}
+
+func TestCloneRoute(t *testing.T) {
+	fc := newFakeCaddy(t, `{"apps":{"http":{"servers":{
+		"srv0":{"routes":[{"@id":"api","match":[{"host":["api.example.com"]}],"handle":[{"handler":"subroute","routes":[{"@id":"inner","handle":[{"handler":"reverse_proxy"}]}]}]}]},
+		"srv1":{"routes":[{"match":[{"host":["www.example.com"]}],"handle":[{"handler":"file_server"}]}]}
+	}}}}`)
+
+	result, err := callTool(cloneRouteHandler, "clone_route", map[string]any{"server": "srv0", "route": "api.example.com", "target_server": "srv1"})
+	if err != nil {
+		t.Fatalf("clone_route: %v", err)
+	}
+
+	var got clonedRoute
+	if err := json.Unmarshal([]byte(resultText(t, result)), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Source.ID != "api" || got.Clone.Index != 1 || got.Clone.ID != "" {
+		t.Errorf("clone_route = %+v, want the api route appended to srv1 without its @id", got)
+	}
+
+	var cfg map[string]any
+	if err := json.Unmarshal([]byte(fc.current()), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	target, err := httpServer(cfg, "srv1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// @ids are removed at every depth so they stay unique
+	clone, _ := json.Marshal(serverRoutes(target)[1])
+	if want := `{"handle":[{"handler":"subroute","routes":[{"handle":[{"handler":"reverse_proxy"}]}]}],"match":[{"host":["api.example.com"]}]}`; string(clone) != want {
+		t.Errorf("cloned route = %s, want %s", clone, want)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// The parts of an http server's configuration summarized by list_http_servers
+type httpServerSummary struct {
+	Listen []string          `json:"listen"`
+	Routes []json.RawMessage `json:"routes"`
+}
+
+func registerServerTools(s *server.MCPServer) {
+	listHTTPServers := mcp.NewTool("list_http_servers",
+		mcp.WithDescription(`
+		Use the list_http_servers tool to get a short summary of the http servers in the caddy configuration, for example to answer what is listening on the host.
+
+		The result is a table with the name of each server, its listen addresses and its number of routes.
+
+		Notes:
+			Routes count the top-level routes of each server; routes inside subroutes are not counted.
+			Use the get_config_path tool with apps/http/servers/<name> to see the full configuration of a server.
+		`),
+	)
+
+	// Add list HTTP servers tool handler
+	s.AddTool(listHTTPServers, listHTTPServersHandler)
+}
+
+// Summarize the http servers and their listen addresses as a table
+func listHTTPServersHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	status, body, err := adminRequest(ctx, http.MethodGet, "/config/apps/http/servers", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if status != http.StatusOK {
+		return caddyErrorResult(&caddyError{
+			StatusCode: status,
+			Message:    string(body),
+		})
+	}
+
+	var servers map[string]httpServerSummary
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && string(trimmed) != "null" {
+		if err := json.Unmarshal(body, &servers); err != nil {
+			return nil, fmt.Errorf("failed to parse http servers: %v", err)
+		}
+	}
+
+	if len(servers) == 0 {
+		return mcp.NewToolResultText("No http servers are configured."), nil
+	}
+
+	names := make([]string, 0, len(servers))
+	for name := range servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SERVER\tLISTEN\tROUTES")
+	for _, name := range names {
+		srv := servers[name]
+		listen := strings.Join(srv.Listen, ", ")
+		if listen == "" {
+			listen = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\n", name, listen, len(srv.Routes))
+	}
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(b.String()), nil
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func registerStaticTools(s *server.MCPServer) {
+	setupSPA := mcp.NewTool("setup_spa",
+		mcp.WithDescription(`
+		Use the setup_spa tool to serve a single-page application (SPA) for a host from a directory, so client-side routing works.
+
+		A route for the host is added that serves files from root and rewrites requests for paths that are not files to /index.html, like try_files {path} /index.html followed by file_server in a Caddyfile. Calling the tool again for the same host and server replaces the route.
+		The updated configuration is applied to the caddy server and returned in JSON format.
+
+		Notes:
+			The route is added after the existing routes of the server but before its fallback route, so more specific routes for the host, like an /api reverse proxy, keep working when they come first.
+			server can be omitted when the configuration has a single server.
+		`),
+		mcp.WithString("host",
+			mcp.Required(),
+			mcp.Description("The host to serve the application for, for example app.example.com"),
+		),
+		mcp.WithString("root",
+			mcp.Required(),
+			mcp.Description("The absolute path of the directory containing index.html"),
+		),
+		mcp.WithString("server",
+			mcp.Description("The name of the server in apps/http/servers"),
+		),
+		mcp.WithBoolean("compress",
+			mcp.Description("Whether to compress responses with gzip and zstd"),
+			mcp.DefaultBool(true),
+		),
+	)
+
+	// Add setup SPA tool handler
+	s.AddTool(setupSPA, setupSPAHandler)
+
+	addFileServer := mcp.NewTool("add_file_server",
+		mcp.WithDescription(`
+		Use the add_file_server tool to serve the static files of a directory for a host, for example "serve /var/www/site for example.com".
+
+		A route for the host is added that sets the site root and serves files with file_server, like root and file_server in a Caddyfile. Calling the tool again for the same host and server replaces the route.
+		The updated configuration is applied to the caddy server and returned in JSON format.
+
+		Notes:
+			The route is added after the existing routes of the server but before its fallback route.
+			server can be omitted when the configuration has a single server.
+			Use the setup_spa tool instead for single-page applications that need an index.html fallback.
+		`),
+		mcp.WithString("host",
+			mcp.Required(),
+			mcp.Description("The host to serve the files for, for example example.com"),
+		),
+		mcp.WithString("root",
+			mcp.Required(),
+			mcp.Description("The absolute path of the directory to serve"),
+		),
+		mcp.WithString("server",
+			mcp.Description("The name of the server in apps/http/servers"),
+		),
+	)
+
+	// Add add file server tool handler
+	s.AddTool(addFileServer, addFileServerHandler)
+}
+
+// Find the server to add a route to, defaulting to the only server
+func targetServer(cfg map[string]any, name string) (string, map[string]any, error) {
+	if name == "" {
+		names := httpServerNames(cfg)
+		if len(names) != 1 {
+			return "", nil, fmt.Errorf("a server name is required when the configuration does not have exactly one server (servers: %s)", strings.Join(names, ", "))
+		}
+		name = names[0]
+	}
+
+	srv, err := httpServer(cfg, name)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return name, srv, nil
+}
+
+// The @id of a route generated for a host, unique across servers so the same host can be added to several of them
+func generatedRouteID(kind, serverName, host string) string {
+	return fmt.Sprintf("caddy_mcp_%s_%s_%s", kind, serverName, host)
+}
+
+// Add a route before the fallback route of a server, replacing any route with the same @id, and return its index
+func insertRoute(srv map[string]any, serverName string, route map[string]any) int {
+	id, _ := route["@id"].(string)
+	fallbackID := fallbackRouteID(serverName)
+
+	routes := []any{}
+	var fallback []any
+	for _, r := range serverRoutes(srv) {
+		existing, _ := r.(map[string]any)
+		switch {
+		case id != "" && existing["@id"] == id:
+			continue
+		case existing["@id"] == fallbackID:
+			fallback = append(fallback, r)
+			continue
+		}
+		routes = append(routes, r)
+	}
+
+	routes = append(routes, route)
+	srv["routes"] = append(routes, fallback...)
+
+	return len(routes) - 1
+}
+
+// Serve a single-page application with an index.html fallback
+func setupSPAHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	host, err := request.RequireString("host")
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := request.RequireString("root")
+	if err != nil {
+		return nil, err
+	}
+
+	if host == "" {
+		return nil, fmt.Errorf("host must not be empty")
+	}
+	if !filepath.IsAbs(root) {
+		return nil, fmt.Errorf("root must be an absolute path: %s", root)
+	}
+
+	var handlers []any
+	if request.GetBool("compress", true) {
+		handlers = append(handlers, map[string]any{
+			"handler": "encode",
+			"encodings": map[string]any{
+				"gzip": map[string]any{},
+				"zstd": map[string]any{},
+			},
+			"prefer": []string{"zstd", "gzip"},
+		})
+	}
+	handlers = append(handlers, map[string]any{
+		"handler": "subroute",
+		"routes": []any{
+			map[string]any{
+				"handle": []any{
+					map[string]any{
+						"handler": "vars",
+						"root":    root,
+					},
+				},
+			},
+			map[string]any{
+				"match": []any{
+					map[string]any{
+						"file": map[string]any{
+							"try_files": []string{"{http.request.uri.path}", "/index.html"},
+						},
+					},
+				},
+				"handle": []any{
+					map[string]any{
+						"handler": "rewrite",
+						"uri":     "{http.matchers.file.relative}",
+					},
+				},
+			},
+			map[string]any{
+				"handle": []any{
+					map[string]any{
+						"handler": "file_server",
+					},
+				},
+			},
+		},
+	})
+
+	cfg, err := fetchConfigMap(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	serverName, srv, err := targetServer(cfg, request.GetString("server", ""))
+	if err != nil {
+		return nil, err
+	}
+
+	insertRoute(srv, serverName, map[string]any{
+		"@id": generatedRouteID("spa", serverName, host),
+		"match": []any{
+			map[string]any{"host": []string{host}},
+		},
+		"handle":   handlers,
+		"terminal": true,
+	})
+
+	return applyConfigMap(ctx, cfg)
+}
+
+// Add a route serving the files of a directory for a host
+func addFileServerHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	host, err := request.RequireString("host")
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := request.RequireString("root")
+	if err != nil {
+		return nil, err
+	}
+
+	if host == "" {
+		return nil, fmt.Errorf("host must not be empty")
+	}
+	if !filepath.IsAbs(root) {
+		return nil, fmt.Errorf("root must be an absolute path: %s", root)
+	}
+
+	cfg, err := fetchConfigMap(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	serverName, srv, err := targetServer(cfg, request.GetString("server", ""))
+	if err != nil {
+		return nil, err
+	}
+
+	insertRoute(srv, serverName, map[string]any{
+		"@id": generatedRouteID("files", serverName, host),
+		"match": []any{
+			map[string]any{"host": []string{host}},
+		},
+		"handle": []any{
+			map[string]any{
+				"handler": "vars",
+				"root":    root,
+			},
+			map[string]any{
+				"handler": "file_server",
+			},
+		},
+		"terminal": true,
+	})
+
+	return applyConfigMap(ctx, cfg)
+}
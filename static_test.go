@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// Get the @id of each route of a server
+func routeIDs(srv map[string]any) []any {
+	ids := []any{}
+	for _, r := range serverRoutes(srv) {
+		route, _ := r.(map[string]any)
+		ids = append(ids, route["@id"])
+	}
+	return ids
+}
+
+func TestInsertRoute(t *testing.T) {
+	fallback := fallbackRouteID("srv0")
+
+	tests := []struct {
+		name    string
+		routes  []any
+		route   map[string]any
+		wantIDs []any
+	}{
+		{
+			name:    "empty server",
+			route:   map[string]any{"@id": "new"},
+			wantIDs: []any{"new"},
+		},
+		{
+			name:    "appended",
+			routes:  []any{map[string]any{"@id": "a"}},
+			route:   map[string]any{"@id": "new"},
+			wantIDs: []any{"a", "new"},
+		},
+		{
+			name:    "before the fallback",
+			routes:  []any{map[string]any{"@id": "a"}, map[string]any{"@id": fallback}},
+			route:   map[string]any{"@id": "new"},
+			wantIDs: []any{"a", "new", fallback},
+		},
+		{
+			name:    "replaces the same @id",
+			routes:  []any{map[string]any{"@id": "new", "old": true}, map[string]any{"@id": "a"}},
+			route:   map[string]any{"@id": "new"},
+			wantIDs: []any{"a", "new"},
+		},
+		{
+			name:    "keeps routes without @id",
+			routes:  []any{map[string]any{"handle": []any{}}, map[string]any{"handle": []any{}}},
+			route:   map[string]any{"handle": []any{}},
+			wantIDs: []any{nil, nil, nil},
+		},
+		{
+			name:    "keeps the fallback of another server",
+			routes:  []any{map[string]any{"@id": fallbackRouteID("srv1")}},
+			route:   map[string]any{"@id": "new"},
+			wantIDs: []any{fallbackRouteID("srv1"), "new"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := map[string]any{}
+			if tt.routes != nil {
+				srv["routes"] = tt.routes
+			}
+
+			insertRoute(srv, "srv0", tt.route)
+
+			if ids := routeIDs(srv); !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("insertRoute() route ids = %v, want %v", ids, tt.wantIDs)
+			}
+
+			for _, r := range serverRoutes(srv) {
+				if r.(map[string]any)["old"] != nil {
+					t.Errorf("insertRoute() kept the replaced route")
+				}
+			}
+		})
+	}
+}
+
+func TestSetupSPA(t *testing.T) {
+	fc := newFakeCaddy(t, `{"apps":{"http":{"servers":{"srv0":{"listen":[":443"]}}}}}`)
+
+	args := map[string]any{"host": "app.example.com", "root": "/srv/app"}
+	for range 2 {
+		if _, err := callTool(setupSPAHandler, "setup_spa", args); err != nil {
+			t.Fatalf("setup_spa: %v", err)
+		}
+	}
+
+	var cfg map[string]any
+	if err := json.Unmarshal([]byte(fc.current()), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	srv, err := httpServer(cfg, "srv0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Calling the tool again replaces its route
+	routes := serverRoutes(srv)
+	if len(routes) != 1 {
+		t.Fatalf("server has %d routes after two setup_spa calls, want 1", len(routes))
+	}
+	if hosts := routeHosts(routes[0].(map[string]any)); !reflect.DeepEqual(hosts, []string{"app.example.com"}) {
+		t.Errorf("SPA route hosts = %v, want [app.example.com]", hosts)
+	}
+
+	if _, err := callTool(setupSPAHandler, "setup_spa", map[string]any{"host": "app.example.com", "root": "srv/app"}); err == nil {
+		t.Errorf("setup_spa with a relative root returned no error")
+	}
+}
+
+func TestSetupSPAServer(t *testing.T) {
+	fc := newFakeCaddy(t, `{"apps":{"http":{"servers":{"srv0":{},"srv1":{}}}}}`)
+
+	if _, err := callTool(setupSPAHandler, "setup_spa", map[string]any{"host": "app.example.com", "root": "/srv/app"}); err == nil {
+		t.Errorf("setup_spa without server on a configuration with two servers returned no error")
+	}
+
+	if _, err := callTool(setupSPAHandler, "setup_spa", map[string]any{"host": "app.example.com", "root": "/srv/app", "server": "srv1"}); err != nil {
+		t.Fatalf("setup_spa: %v", err)
+	}
+
+	var cfg map[string]any
+	if err := json.Unmarshal([]byte(fc.current()), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]int{"srv0": 0, "srv1": 1} {
+		srv, err := httpServer(cfg, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := len(serverRoutes(srv)); got != want {
+			t.Errorf("%s has %d routes, want %d", name, got, want)
+		}
+	}
+}
+
+func TestGeneratedRouteIDIncludesServer(t *testing.T) {
+	if generatedRouteID("proxy", "srv0", "example.com") == generatedRouteID("proxy", "srv1", "example.com") {
+		t.Errorf("generatedRouteID() is the same for two servers")
+	}
+}
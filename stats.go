@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+type toolCounters struct {
+	calls   int
+	errors  int
+	latency time.Duration
+}
+
+type toolStats struct {
+	Name       string  `json:"name"`
+	Calls      int     `json:"calls"`
+	Errors     int     `json:"errors"`
+	ErrorRate  float64 `json:"error_rate"`
+	AvgLatency string  `json:"avg_latency"`
+}
+
+type mcpStats struct {
+	StartedAt   time.Time   `json:"started_at"`
+	Uptime      string      `json:"uptime"`
+	TotalCalls  int         `json:"total_calls"`
+	TotalErrors int         `json:"total_errors"`
+	Tools       []toolStats `json:"tools"`
+}
+
+// Counters of the tool calls handled since startup
+var (
+	statsMu    sync.Mutex
+	statsStart = time.Now()
+	statsTools = map[string]*toolCounters{}
+)
+
+func registerStatsTools(s *server.MCPServer) {
+	getMCPStats := mcp.NewTool("get_mcp_stats",
+		mcp.WithDescription(`
+		Use the get_mcp_stats tool to see how caddy-mcp itself has been used since it started.
+
+		The result is a JSON document with the uptime and, for each tool that was called, the number of calls, the number and rate of errors and the average latency.
+
+		Notes:
+			A call counts as an error when the tool failed or returned an error result. Rejections from caddy that are returned as a result, like an invalid configuration, are not counted.
+			The counters are kept in memory and reset when the MCP server restarts.
+		`),
+	)
+
+	// Add get MCP stats tool handler
+	s.AddTool(getMCPStats, getMCPStatsHandler)
+}
+
+// Record the outcome and latency of every tool call
+func statsMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		start := time.Now()
+		result, err := next(ctx, request)
+		elapsed := time.Since(start)
+
+		statsMu.Lock()
+		defer statsMu.Unlock()
+
+		counters, ok := statsTools[request.Params.Name]
+		if !ok {
+			counters = &toolCounters{}
+			statsTools[request.Params.Name] = counters
+		}
+
+		counters.calls++
+		counters.latency += elapsed
+		if err != nil || (result != nil && result.IsError) {
+			counters.errors++
+		}
+
+		return result, err
+	}
+}
+
+// Report the tool call counters
+func getMCPStatsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	statsMu.Lock()
+	stats := mcpStats{
+		StartedAt: statsStart,
+		Uptime:    time.Since(statsStart).Round(time.Second).String(),
+		Tools:     []toolStats{},
+	}
+	for name, counters := range statsTools {
+		stats.TotalCalls += counters.calls
+		stats.TotalErrors += counters.errors
+		stats.Tools = append(stats.Tools, toolStats{
+			Name:       name,
+			Calls:      counters.calls,
+			Errors:     counters.errors,
+			ErrorRate:  float64(counters.errors) / float64(counters.calls),
+			AvgLatency: (counters.latency / time.Duration(counters.calls)).String(),
+		})
+	}
+	statsMu.Unlock()
+
+	sort.Slice(stats.Tools, func(i, j int) bool {
+		return stats.Tools[i].Name < stats.Tools[j].Name
+	})
+
+	data, err := json.Marshal(stats)
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
+}
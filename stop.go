@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+type stopResult struct {
+	Stopped bool   `json:"stopped"`
+	Message string `json:"message"`
+}
+
+func registerStopTools(s *server.MCPServer) {
+	stopCaddy := mcp.NewTool("stop_caddy",
+		mcp.WithDescription(`
+		Use the stop_caddy tool to gracefully stop the caddy server, for example before maintenance of the host.
+
+		Caddy stops all its apps, closing the servers after their current requests, and exits. The admin API is no longer reachable afterwards, so every other tool fails until caddy is started again outside of this MCP server.
+
+		Notes:
+			This is destructive and only available when the MCP server runs with -allow-stop.
+			Ask the user before stopping caddy.
+		`),
+	)
+
+	// Add stop Caddy tool handler
+	s.AddTool(stopCaddy, stopCaddyHandler)
+}
+
+// Stop the caddy process through the admin API
+func stopCaddyHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if !allowStop {
+		return nil, fmt.Errorf("stop is disabled; start the MCP server with -allow-stop")
+	}
+
+	status, body, err := adminRequest(ctx, http.MethodPost, "/stop", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if status != http.StatusOK {
+		return caddyErrorResult(&caddyError{
+			StatusCode: status,
+			Message:    string(body),
+		})
+	}
+
+	data, err := json.Marshal(stopResult{
+		Stopped: true,
+		Message: "caddy is shutting down; start it again outside of this MCP server to use the other tools",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"regexp"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// The pattern tickets must match when -require-ticket is set
+var ticketRegexp *regexp.Regexp
+
+type toolCallKey struct{}
+
+// The tool call a request to the admin API is made for
+type toolCall struct {
+	tool   string
+	ticket string
+}
+
+// Compile the -require-ticket pattern so it must match the whole ticket
+func compileTicketPattern(pattern string) error {
+	if pattern == "" {
+		return nil
+	}
+
+	re, err := regexp.Compile(`^(?:` + pattern + `)$`)
+	if err != nil {
+		return fmt.Errorf("invalid -require-ticket pattern: %v", err)
+	}
+
+	ticketRegexp = re
+	return nil
+}
+
+// Remember the tool and ticket of a call so changes made for it can be checked and audited
+func ticketMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		ctx = context.WithValue(ctx, toolCallKey{}, toolCall{
+			tool:   request.Params.Name,
+			ticket: request.GetString("ticket", ""),
+		})
+
+		return next(ctx, request)
+	}
+}
+
+// Add the ticket argument to the schema of every tool when tickets are required
+func ticketToolFilter(ctx context.Context, tools []mcp.Tool) []mcp.Tool {
+	if ticketRegexp == nil {
+		return tools
+	}
+
+	for i, tool := range tools {
+		// The properties map is shared with the registered tool, so copy it
+		properties := maps.Clone(tool.InputSchema.Properties)
+		if properties == nil {
+			properties = map[string]any{}
+		}
+		properties["ticket"] = map[string]any{
+			"type":        "string",
+			"description": fmt.Sprintf("The change ticket this change is made for, matching %s. Required by tools that change the caddy configuration.", requireTicket),
+		}
+		tools[i].InputSchema.Properties = properties
+	}
+
+	return tools
+}
+
+// Check the ticket of a change to the admin API and record the change in the audit log
+func checkTicket(ctx context.Context, method, path string) error {
+	// Adapting a configuration with /adapt does not change it
+	if ticketRegexp == nil || method == http.MethodGet || path == "/adapt" {
+		return nil
+	}
+
+	// Changes made outside of a tool call, like an automatic revert, are not tied to a ticket
+	call, ok := ctx.Value(toolCallKey{}).(toolCall)
+	if !ok {
+		slog.Info("audit", "method", method, "path", path, "automatic", true)
+		return nil
+	}
+
+	switch {
+	case call.ticket == "":
+		return fmt.Errorf("change rejected: this MCP server requires a change ticket; pass the ticket argument with a ticket matching %s", requireTicket)
+	case !ticketRegexp.MatchString(call.ticket):
+		return fmt.Errorf("change rejected: ticket %q does not match the required pattern %s", call.ticket, requireTicket)
+	}
+
+	slog.Info("audit", "method", method, "path", path, "tool", call.tool, "ticket", call.ticket)
+	return nil
+}
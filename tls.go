@@ -0,0 +1,710 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/modules/caddytls"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+type automationPolicyMatch struct {
+	Index    int      `json:"index"`
+	Subjects []string `json:"subjects,omitempty"`
+	CatchAll bool     `json:"catch_all,omitempty"`
+	Implicit bool     `json:"implicit,omitempty"`
+	Issuers  []string `json:"issuers"`
+	OnDemand bool     `json:"on_demand,omitempty"`
+	KeyType  string   `json:"key_type,omitempty"`
+}
+
+type connectionPolicyMatch struct {
+	Server       string   `json:"server"`
+	Index        int      `json:"index"`
+	Implicit     bool     `json:"implicit,omitempty"`
+	Conditional  bool     `json:"conditional,omitempty"`
+	Match        any      `json:"match,omitempty"`
+	ProtocolMin  string   `json:"protocol_min"`
+	ProtocolMax  string   `json:"protocol_max"`
+	CipherSuites []string `json:"cipher_suites"`
+	Curves       []string `json:"curves,omitempty"`
+	ClientAuth   bool     `json:"client_auth,omitempty"`
+}
+
+type tlsExplanation struct {
+	Domain             string                  `json:"domain"`
+	Servers            []string                `json:"servers"`
+	AutomationPolicy   *automationPolicyMatch  `json:"automation_policy,omitempty"`
+	ConnectionPolicies []connectionPolicyMatch `json:"connection_policies"`
+	Notes              []string                `json:"notes"`
+}
+
+type domainReadiness struct {
+	Domain      string   `json:"domain"`
+	Server      string   `json:"server"`
+	HTTPS       bool     `json:"https"`
+	Certificate string   `json:"certificate"`
+	Reasons     []string `json:"reasons"`
+}
+
+type httpsReadiness struct {
+	Domains  []domainReadiness `json:"domains"`
+	Warnings []string          `json:"warnings"`
+}
+
+func registerTLSTools(s *server.MCPServer) {
+	setTLSConnectionPolicy := mcp.NewTool("set_tls_connection_policy",
+		mcp.WithDescription(`
+		Use the set_tls_connection_policy tool to set the TLS protocol versions, cipher suites and curves of the default TLS connection policy of a caddy server.
+
+		The default policy is the one without matchers. It is created as the last policy when it does not exist yet; policies with matchers are left untouched.
+		The updated configuration is applied to the caddy server and returned in JSON format.
+
+		Notes:
+			Protocol versions are tls1.2 or tls1.3.
+			Cipher suites use the Go names, for example TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384. They only apply to TLS 1.2; TLS 1.3 cipher suites are not configurable.
+			Omitted settings keep their current value.
+		`),
+		mcp.WithString("server_name",
+			mcp.Required(),
+			mcp.Description("The name of the server in apps/http/servers"),
+		),
+		mcp.WithString("protocol_min",
+			mcp.Description("The minimum TLS version: tls1.2 or tls1.3"),
+			mcp.Enum("tls1.2", "tls1.3"),
+		),
+		mcp.WithString("protocol_max",
+			mcp.Description("The maximum TLS version: tls1.2 or tls1.3"),
+			mcp.Enum("tls1.2", "tls1.3"),
+		),
+		mcp.WithArray("cipher_suites",
+			mcp.Description("The allowed TLS 1.2 cipher suites, in order of preference"),
+			mcp.Items(map[string]any{"type": "string"}),
+		),
+		mcp.WithArray("curves",
+			mcp.Description("The allowed elliptic curves, for example x25519 or secp256r1"),
+			mcp.Items(map[string]any{"type": "string"}),
+		),
+	)
+
+	// Add set TLS connection policy tool handler
+	s.AddTool(setTLSConnectionPolicy, setTLSConnectionPolicyHandler)
+
+	explainTLSForDomain := mcp.NewTool("explain_tls_for_domain",
+		mcp.WithDescription(`
+		Use the explain_tls_for_domain tool to find out which TLS settings a domain gets, for example to debug why a domain gets the wrong certificate or protocol.
+
+		The result is a JSON document with the servers answering for the domain, the automation policy that manages its certificate with the issuers, and for each server the connection policy used for the domain's handshakes with the effective TLS versions and cipher suites.
+
+		Notes:
+			Automation policies are matched by their subjects, falling back to the first policy without subjects. Connection policies are tried in order and the first one whose matchers match is used.
+			Connection policies with matchers other than sni depend on the client; they are listed as conditional before the policy used otherwise.
+			Policies caddy adds implicitly through automatic HTTPS are reported as implicit with their defaults.
+		`),
+		mcp.WithString("domain",
+			mcp.Required(),
+			mcp.Description("The domain to explain, for example example.com"),
+		),
+	)
+
+	// Add explain TLS for domain tool handler
+	s.AddTool(explainTLSForDomain, explainTLSForDomainHandler)
+
+	checkHTTPSReadiness := mcp.NewTool("check_https_readiness",
+		mcp.WithDescription(`
+		Use the check_https_readiness tool to find out, before applying a caddy JSON configuration, whether each of its domains will actually be served over HTTPS.
+
+		Automatic HTTPS follows the rules caddy applies when the configuration is loaded: a server listening only on the HTTP port, disabled automatic HTTPS, skipped domains and host matchers that are not on a top-level route all leave a domain without HTTPS. Host matchers with a scheme or a port never match any request.
+		The result is a JSON document listing for each domain whether it gets HTTPS, where its certificate comes from (public, internal or none) and the reasons, along with warnings about the servers.
+
+		Notes:
+			A certificate of public is requested from a public CA like Let's Encrypt, which needs ports 80 or 443 to be reachable from the internet for the ACME challenges unless a DNS challenge is configured.
+			If json_config is not provided the current caddy server configuration is checked.
+		`),
+		mcp.WithString("json_config",
+			mcp.Description("The caddy JSON configuration to check instead of the current configuration"),
+		),
+	)
+
+	// Add check HTTPS readiness tool handler
+	s.AddTool(checkHTTPSReadiness, checkHTTPSReadinessHandler)
+}
+
+// List the keys of a map of supported values, sorted
+func supportedNames[V any](values map[string]V) string {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// Set the protocols and ciphers of the default TLS connection policy of a server
+func setTLSConnectionPolicyHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	serverName, err := request.RequireString("server_name")
+	if err != nil {
+		return nil, err
+	}
+
+	protocolMin := request.GetString("protocol_min", "")
+	protocolMax := request.GetString("protocol_max", "")
+	cipherSuites := request.GetStringSlice("cipher_suites", nil)
+	curves := request.GetStringSlice("curves", nil)
+
+	for _, protocol := range []string{protocolMin, protocolMax} {
+		if _, ok := caddytls.SupportedProtocols[protocol]; protocol != "" && !ok {
+			return nil, fmt.Errorf("unsupported protocol %q: expected one of %s", protocol, supportedNames(caddytls.SupportedProtocols))
+		}
+	}
+
+	if protocolMin != "" && protocolMax != "" && caddytls.SupportedProtocols[protocolMin] > caddytls.SupportedProtocols[protocolMax] {
+		return nil, fmt.Errorf("protocol_min %s is higher than protocol_max %s", protocolMin, protocolMax)
+	}
+
+	for _, suite := range cipherSuites {
+		if !caddytls.CipherSuiteNameSupported(suite) {
+			return nil, fmt.Errorf("unsupported cipher suite: %q", suite)
+		}
+	}
+
+	for _, curve := range curves {
+		if _, ok := caddytls.SupportedCurves[curve]; !ok {
+			return nil, fmt.Errorf("unsupported curve %q: expected one of %s", curve, supportedNames(caddytls.SupportedCurves))
+		}
+	}
+
+	if protocolMin == "" && protocolMax == "" && cipherSuites == nil && curves == nil {
+		return nil, fmt.Errorf("at least one setting is required")
+	}
+
+	cfg, err := fetchConfigMap(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	srv, err := httpServer(cfg, serverName)
+	if err != nil {
+		return nil, err
+	}
+
+	policies, _ := srv["tls_connection_policies"].([]any)
+
+	var policy map[string]any
+	for _, p := range policies {
+		if candidate, ok := p.(map[string]any); ok && candidate["match"] == nil {
+			policy = candidate
+			break
+		}
+	}
+	if policy == nil {
+		policy = map[string]any{}
+		policies = append(policies, policy)
+	}
+
+	if protocolMin != "" {
+		policy["protocol_min"] = protocolMin
+	}
+	if protocolMax != "" {
+		policy["protocol_max"] = protocolMax
+	}
+	if cipherSuites != nil {
+		policy["cipher_suites"] = cipherSuites
+	}
+	if curves != nil {
+		policy["curves"] = curves
+	}
+	srv["tls_connection_policies"] = policies
+
+	return applyConfigMap(ctx, cfg)
+}
+
+// Check whether a domain matches a name that may start with a wildcard label
+func matchDomain(pattern, domain string) bool {
+	pattern = strings.ToLower(pattern)
+	domain = strings.ToLower(domain)
+	if pattern == domain {
+		return true
+	}
+
+	patternLabels := strings.Split(pattern, ".")
+	domainLabels := strings.Split(domain, ".")
+	if len(patternLabels) != len(domainLabels) {
+		return false
+	}
+
+	for i := range patternLabels {
+		if patternLabels[i] != "*" && patternLabels[i] != domainLabels[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Check whether automatic HTTPS gets a certificate for a domain from the internal CA
+func isInternalDomain(domain string) bool {
+	if domain == "localhost" || net.ParseIP(domain) != nil {
+		return true
+	}
+
+	for _, suffix := range []string{".localhost", ".local", ".internal", ".home.arpa"} {
+		if strings.HasSuffix(domain, suffix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Describe an issuer of an automation policy
+func describeIssuer(issuer any) string {
+	i, _ := issuer.(map[string]any)
+	module, _ := i["module"].(string)
+
+	switch module {
+	case "acme":
+		if ca, ok := i["ca"].(string); ok && ca != "" {
+			return fmt.Sprintf("acme (%s)", ca)
+		}
+		return "acme (Let's Encrypt)"
+	case "internal":
+		if ca, ok := i["ca"].(string); ok && ca != "" {
+			return fmt.Sprintf("internal (CA %s)", ca)
+		}
+		return "internal (CA local)"
+	case "":
+		return "unknown"
+	default:
+		return module
+	}
+}
+
+// Find the automation policy managing the certificate of a domain
+func findAutomationPolicy(cfg map[string]any, domain string) *automationPolicyMatch {
+	automation, _ := configObject(cfg, false, "apps", "tls", "automation")
+	policies, _ := automation["policies"].([]any)
+
+	defaultIssuers := []string{"acme (Let's Encrypt)", "acme (ZeroSSL)"}
+	if isInternalDomain(domain) {
+		defaultIssuers = []string{"internal (CA local)"}
+	}
+
+	var catchAll *automationPolicyMatch
+	for i, p := range policies {
+		policy, ok := p.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		match := &automationPolicyMatch{Index: i, Issuers: []string{}}
+		match.OnDemand, _ = policy["on_demand"].(bool)
+		match.KeyType, _ = policy["key_type"].(string)
+
+		issuers, _ := policy["issuers"].([]any)
+		for _, issuer := range issuers {
+			match.Issuers = append(match.Issuers, describeIssuer(issuer))
+		}
+		if len(match.Issuers) == 0 {
+			match.Issuers = defaultIssuers
+		}
+
+		subjects, _ := policy["subjects"].([]any)
+		if len(subjects) == 0 {
+			if catchAll == nil {
+				match.CatchAll = true
+				catchAll = match
+			}
+			continue
+		}
+
+		for _, subject := range subjects {
+			if name, ok := subject.(string); ok {
+				match.Subjects = append(match.Subjects, name)
+			}
+		}
+		for _, name := range match.Subjects {
+			if matchDomain(name, domain) {
+				return match
+			}
+		}
+	}
+
+	if catchAll != nil {
+		return catchAll
+	}
+
+	return &automationPolicyMatch{
+		Index:    -1,
+		Implicit: true,
+		Issuers:  defaultIssuers,
+	}
+}
+
+// Describe a connection policy with the defaults filled in
+func describeConnectionPolicy(serverName string, index int, policy map[string]any) connectionPolicyMatch {
+	match := connectionPolicyMatch{
+		Server:       serverName,
+		Index:        index,
+		ProtocolMin:  "tls1.2",
+		ProtocolMax:  "tls1.3",
+		CipherSuites: []string{"Go defaults"},
+	}
+
+	if policy == nil {
+		match.Implicit = true
+		return match
+	}
+
+	match.Match = policy["match"]
+	if v, ok := policy["protocol_min"].(string); ok {
+		match.ProtocolMin = v
+	}
+	if v, ok := policy["protocol_max"].(string); ok {
+		match.ProtocolMax = v
+	}
+	if suites, ok := policy["cipher_suites"].([]any); ok && len(suites) > 0 {
+		match.CipherSuites = nil
+		for _, suite := range suites {
+			match.CipherSuites = append(match.CipherSuites, fmt.Sprint(suite))
+		}
+	}
+	if curves, ok := policy["curves"].([]any); ok {
+		for _, curve := range curves {
+			match.Curves = append(match.Curves, fmt.Sprint(curve))
+		}
+	}
+	_, match.ClientAuth = policy["client_authentication"]
+
+	return match
+}
+
+// Find the connection policies a server may use for a domain, ending with the one used by default
+func findConnectionPolicies(serverName string, srv map[string]any, domain string) []connectionPolicyMatch {
+	policies, _ := srv["tls_connection_policies"].([]any)
+	if len(policies) == 0 {
+		return []connectionPolicyMatch{describeConnectionPolicy(serverName, -1, nil)}
+	}
+
+	var result []connectionPolicyMatch
+	for i, p := range policies {
+		policy, ok := p.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		matchers, _ := policy["match"].(map[string]any)
+		conditional := false
+		matches := true
+		for key, value := range matchers {
+			if key != "sni" {
+				conditional = true
+				continue
+			}
+
+			matches = false
+			names, _ := value.([]any)
+			for _, name := range names {
+				if pattern, ok := name.(string); ok && matchDomain(pattern, domain) {
+					matches = true
+				}
+			}
+		}
+
+		if !matches {
+			continue
+		}
+
+		match := describeConnectionPolicy(serverName, i, policy)
+		match.Conditional = conditional
+		result = append(result, match)
+
+		if !conditional {
+			break
+		}
+	}
+
+	return result
+}
+
+// Explain which automation and connection policies apply to a domain
+func explainTLSForDomainHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	domain, err := request.RequireString("domain")
+	if err != nil {
+		return nil, err
+	}
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	if domain == "" {
+		return nil, fmt.Errorf("domain must not be empty")
+	}
+
+	cfg, err := fetchConfigMap(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	explanation := tlsExplanation{
+		Domain:             domain,
+		Servers:            []string{},
+		ConnectionPolicies: []connectionPolicyMatch{},
+		Notes:              []string{},
+	}
+
+	httpsPort := 443
+	if httpApp, err := configObject(cfg, false, "apps", "http"); err == nil {
+		if port, ok := httpApp["https_port"].(float64); ok {
+			httpsPort = int(port)
+		}
+	}
+
+	for _, name := range httpServerNames(cfg) {
+		srv, err := httpServer(cfg, name)
+		if err != nil {
+			continue
+		}
+
+		served := false
+		walkRoutes(serverRoutes(srv), func(route map[string]any) {
+			for _, host := range routeHosts(route) {
+				if matchDomain(host, domain) {
+					served = true
+				}
+			}
+		})
+		if !served {
+			continue
+		}
+
+		explanation.Servers = append(explanation.Servers, name)
+
+		if autoHTTPS, ok := srv["automatic_https"].(map[string]any); ok {
+			if autoHTTPS["disable"] == true {
+				explanation.Notes = append(explanation.Notes, fmt.Sprintf("automatic HTTPS is disabled on server %s", name))
+			}
+			for _, field := range []string{"skip", "skip_certificates"} {
+				skipped, _ := autoHTTPS[field].([]any)
+				for _, s := range skipped {
+					if pattern, ok := s.(string); ok && matchDomain(pattern, domain) {
+						explanation.Notes = append(explanation.Notes, fmt.Sprintf("server %s lists the domain in automatic_https/%s", name, field))
+					}
+				}
+			}
+		}
+
+		if !serverUsesTLS(srv, httpsPort) {
+			explanation.Notes = append(explanation.Notes, fmt.Sprintf("server %s does not appear to serve HTTPS", name))
+			continue
+		}
+
+		explanation.ConnectionPolicies = append(explanation.ConnectionPolicies, findConnectionPolicies(name, srv, domain)...)
+	}
+
+	if len(explanation.Servers) == 0 {
+		explanation.Notes = append(explanation.Notes, "no route matches the domain with a host matcher, so automatic HTTPS does not manage a certificate for it")
+	}
+
+	explanation.AutomationPolicy = findAutomationPolicy(cfg, domain)
+
+	if automate, err := configObject(cfg, false, "apps", "tls", "certificates"); err == nil {
+		names, _ := automate["automate"].([]any)
+		for _, n := range names {
+			if pattern, ok := n.(string); ok && matchDomain(pattern, domain) {
+				explanation.Notes = append(explanation.Notes, "the domain is listed in apps/tls/certificates/automate, so its certificate is managed even without a host matcher")
+			}
+		}
+		if _, ok := automate["load_files"]; ok {
+			explanation.Notes = append(explanation.Notes, "manually loaded certificates (load_files) take precedence when one covers the domain")
+		}
+	}
+
+	data, err := json.Marshal(explanation)
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+// Check whether every listener of a server uses only the given port
+func listensOnlyOn(srv map[string]any, port int) bool {
+	listen, _ := srv["listen"].([]any)
+	for _, l := range listen {
+		address, _ := l.(string)
+		addr, err := caddy.ParseNetworkAddress(address)
+		if err != nil || int(addr.StartPort) != port || int(addr.EndPort) != port {
+			return false
+		}
+	}
+
+	return len(listen) > 0
+}
+
+// Check whether a domain is in an automatic_https list of a server
+func autoHTTPSListed(autoHTTPS map[string]any, field, domain string) bool {
+	values, _ := autoHTTPS[field].([]any)
+	return slices.Contains(values, any(domain))
+}
+
+// Work out whether a domain of a server gets HTTPS and why not
+func domainHTTPSReadiness(cfg map[string]any, serverName string, srv map[string]any, domain string, httpPort, httpsPort int) domainReadiness {
+	readiness := domainReadiness{
+		Domain:      domain,
+		Server:      serverName,
+		Certificate: "none",
+		Reasons:     []string{},
+	}
+
+	autoHTTPS, _ := srv["automatic_https"].(map[string]any)
+
+	switch {
+	case strings.Contains(domain, "://"):
+		readiness.Reasons = append(readiness.Reasons, "the host matcher contains a scheme, so it never matches a request; use the bare host name and listen on the HTTP port for plain HTTP")
+		return readiness
+	case strings.Contains(domain, "{"):
+		readiness.Reasons = append(readiness.Reasons, "the host matcher contains a placeholder; only {env.*} placeholders are replaced when automatic HTTPS runs")
+	case net.ParseIP(domain) == nil && strings.Contains(domain, ":"):
+		readiness.Reasons = append(readiness.Reasons, "the host matcher contains a port, so it never matches a request; the port comes from the listen addresses")
+		return readiness
+	}
+
+	if autoHTTPS["disable"] == true {
+		readiness.Reasons = append(readiness.Reasons, "automatic HTTPS is disabled on the server")
+		return readiness
+	}
+
+	if listensOnlyOn(srv, httpPort) {
+		readiness.Reasons = append(readiness.Reasons, fmt.Sprintf("the server only listens on the HTTP port %d", httpPort))
+		return readiness
+	}
+
+	if autoHTTPSListed(autoHTTPS, "skip", domain) {
+		readiness.Reasons = append(readiness.Reasons, "the domain is listed in automatic_https/skip")
+		if _, ok := srv["tls_connection_policies"]; !ok {
+			return readiness
+		}
+		readiness.Reasons = append(readiness.Reasons, "the server has TLS connection policies, so it still serves HTTPS with a certificate loaded some other way")
+	}
+
+	readiness.HTTPS = true
+
+	switch {
+	case autoHTTPS["disable_certificates"] == true:
+		readiness.Reasons = append(readiness.Reasons, "automatic certificate management is disabled on the server; a certificate must be loaded manually")
+	case autoHTTPSListed(autoHTTPS, "skip_certificates", domain):
+		readiness.Reasons = append(readiness.Reasons, "the domain is listed in automatic_https/skip_certificates; a certificate must be loaded manually")
+	case strings.Contains(domain, "*") && strings.Count(strings.Trim(domain, "."), ".") == 1:
+		readiness.Certificate = "public"
+		readiness.Reasons = append(readiness.Reasons, "most clients do not trust second-level wildcard certificates like *.tld")
+	default:
+		readiness.Certificate = "public"
+		if isInternalDomain(domain) {
+			readiness.Certificate = "internal"
+			readiness.Reasons = append(readiness.Reasons, "the certificate is issued by caddy's internal CA, which clients only trust once its root is installed")
+		} else if policy := findAutomationPolicy(cfg, domain); policy != nil && slices.ContainsFunc(policy.Issuers, func(issuer string) bool {
+			return strings.HasPrefix(issuer, "internal")
+		}) {
+			readiness.Certificate = "internal"
+			readiness.Reasons = append(readiness.Reasons, fmt.Sprintf("automation policy %d uses the internal issuer", policy.Index))
+		}
+	}
+
+	if !serverUsesTLS(srv, httpsPort) {
+		readiness.Reasons = append(readiness.Reasons, fmt.Sprintf("HTTPS is served on the server's listen ports instead of the HTTPS port %d", httpsPort))
+	}
+
+	return readiness
+}
+
+// Report which domains of a configuration will be served over HTTPS
+func checkHTTPSReadinessHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	value, err := configArgument(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("the configuration must be a JSON object")
+	}
+
+	httpPort, httpsPort := 80, 443
+	if httpApp, err := configObject(cfg, false, "apps", "http"); err == nil {
+		if port, ok := httpApp["http_port"].(float64); ok {
+			httpPort = int(port)
+		}
+		if port, ok := httpApp["https_port"].(float64); ok {
+			httpsPort = int(port)
+		}
+	}
+
+	report := httpsReadiness{
+		Domains:  []domainReadiness{},
+		Warnings: []string{},
+	}
+
+	for _, name := range httpServerNames(cfg) {
+		srv, err := httpServer(cfg, name)
+		if err != nil {
+			continue
+		}
+
+		// Automatic HTTPS only looks at the host matchers of top-level routes
+		seen := map[string]bool{}
+		for _, r := range serverRoutes(srv) {
+			route, _ := r.(map[string]any)
+			for _, host := range routeHosts(route) {
+				if !seen[host] {
+					seen[host] = true
+					report.Domains = append(report.Domains, domainHTTPSReadiness(cfg, name, srv, host, httpPort, httpsPort))
+				}
+			}
+		}
+
+		nested := map[string]bool{}
+		for _, r := range serverRoutes(srv) {
+			route, _ := r.(map[string]any)
+			handlers, _ := route["handle"].([]any)
+			for _, h := range handlers {
+				handler, _ := h.(map[string]any)
+				if handler["handler"] != "subroute" {
+					continue
+				}
+
+				subroutes, _ := handler["routes"].([]any)
+				walkRoutes(subroutes, func(inner map[string]any) {
+					for _, host := range routeHosts(inner) {
+						if !seen[host] && !nested[host] {
+							nested[host] = true
+							report.Domains = append(report.Domains, domainReadiness{
+								Domain:      host,
+								Server:      name,
+								Certificate: "none",
+								Reasons:     []string{"the host matcher is inside a subroute; automatic HTTPS only uses the host matchers of the server's top-level routes"},
+							})
+						}
+					}
+				})
+			}
+		}
+
+		if len(seen) == 0 && len(nested) == 0 && !listensOnlyOn(srv, httpPort) {
+			if _, ok := srv["tls_connection_policies"]; !ok {
+				report.Warnings = append(report.Warnings, fmt.Sprintf("server %s has no host matchers and no TLS connection policies, so it serves plain HTTP on all its ports", name))
+			}
+		}
+	}
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Configurations replaced by update_caddy_config for each instance, oldest first
+var (
+	undoMu     sync.Mutex
+	undoStacks = map[string][][]byte{}
+)
+
+type undoResult struct {
+	Undone    bool        `json:"undone"`
+	Message   string      `json:"message,omitempty"`
+	Remaining int         `json:"remaining"`
+	Load      *loadResult `json:"load,omitempty"`
+}
+
+func registerUndoTools(s *server.MCPServer) {
+	undoCaddyConfig := mcp.NewTool("undo_caddy_config",
+		mcp.WithDescription(`
+		Use the undo_caddy_config tool to undo the last change made with the update_caddy_config tool by loading the configuration it replaced.
+
+		Each call undoes one more change, up to the -undo-depth most recent ones (5 by default). The result is a JSON document telling whether a change was undone, the warnings of the load and how many changes can still be undone.
+
+		Notes:
+			Only changes made with update_caddy_config are remembered, and only while this MCP server runs. Each instance has its own changes to undo.
+			Undoing loads the whole previous configuration, so changes made since by other tools or outside of this MCP server are lost as well.
+			With -require-confirmation the undo is proposed like any other change, and the change only stops being undoable once the undo is confirmed.
+		`),
+	)
+
+	// Add undo Caddy config tool handler
+	s.AddTool(undoCaddyConfig, undoCaddyConfigHandler)
+}
+
+// Remember a configuration that is about to be replaced, dropping the oldest beyond -undo-depth
+func pushUndo(ctx context.Context, config []byte) {
+	if undoDepth <= 0 {
+		return
+	}
+
+	undoMu.Lock()
+	defer undoMu.Unlock()
+
+	instance := instanceName(ctx)
+	stack := append(undoStacks[instance], config)
+	if len(stack) > undoDepth {
+		stack = stack[len(stack)-undoDepth:]
+	}
+	undoStacks[instance] = stack
+}
+
+// Take the most recently replaced configuration off the stack
+func popUndo(ctx context.Context) ([]byte, bool) {
+	undoMu.Lock()
+	defer undoMu.Unlock()
+
+	instance := instanceName(ctx)
+	stack := undoStacks[instance]
+	if len(stack) == 0 {
+		return nil, false
+	}
+
+	undoStacks[instance] = stack[:len(stack)-1]
+	return stack[len(stack)-1], true
+}
+
+// Remove the most recent copy of a configuration from the stack once an undo loading it is confirmed
+func dropUndo(ctx context.Context, config []byte) {
+	undoMu.Lock()
+	defer undoMu.Unlock()
+
+	instance := instanceName(ctx)
+	stack := undoStacks[instance]
+	for i := len(stack) - 1; i >= 0; i-- {
+		if bytes.Equal(stack[i], config) {
+			undoStacks[instance] = slices.Delete(stack, i, i+1)
+			return
+		}
+	}
+}
+
+// Count the changes that can still be undone
+func undoRemaining(ctx context.Context) int {
+	undoMu.Lock()
+	defer undoMu.Unlock()
+	return len(undoStacks[instanceName(ctx)])
+}
+
+// Load the configuration replaced by the last update
+func undoCaddyConfigHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	result := undoResult{}
+
+	config, ok := popUndo(ctx)
+	if !ok {
+		result.Message = "nothing to undo; no update_caddy_config change is remembered"
+	} else {
+		body, err := loadConfig(ctx, config)
+		if err != nil {
+			// Keep the snapshot so the undo can be retried, or removed once a proposed undo is confirmed
+			pushUndo(ctx, config)
+			return caddyErrorResult(err)
+		}
+
+		load := parseLoadResponse(body)
+		result.Undone = true
+		result.Load = &load
+	}
+	result.Remaining = undoRemaining(ctx)
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Start a test with empty undo stacks
+func resetUndo(t *testing.T) {
+	t.Helper()
+
+	undoMu.Lock()
+	undoStacks = map[string][][]byte{}
+	undoMu.Unlock()
+
+	t.Cleanup(func() {
+		undoMu.Lock()
+		undoStacks = map[string][][]byte{}
+		undoMu.Unlock()
+	})
+}
+
+// Load a configuration with update_caddy_config
+func updateConfig(t *testing.T, config string) {
+	t.Helper()
+
+	if _, err := callTool(updateCaddyConfigHandler, "update_caddy_config", map[string]any{"json_config": config}); err != nil {
+		t.Fatalf("update_caddy_config: %v", err)
+	}
+}
+
+// Call undo_caddy_config and decode its result
+func undo(t *testing.T) undoResult {
+	t.Helper()
+
+	result, err := callTool(undoCaddyConfigHandler, "undo_caddy_config", nil)
+	if err != nil {
+		t.Fatalf("undo_caddy_config: %v", err)
+	}
+
+	var got undoResult
+	if err := json.Unmarshal([]byte(resultText(t, result)), &got); err != nil {
+		t.Fatalf("undo_caddy_config returned %q: %v", resultText(t, result), err)
+	}
+	return got
+}
+
+// A configuration listening on an HTTP port
+func portConfig(port int) string {
+	return fmt.Sprintf(`{"apps":{"http":{"http_port":%d}}}`, port)
+}
+
+func TestUndo(t *testing.T) {
+	resetUndo(t)
+	fc := newFakeCaddy(t, portConfig(80))
+
+	updateConfig(t, portConfig(81))
+	updateConfig(t, portConfig(82))
+
+	if got := undo(t); !got.Undone || got.Remaining != 1 {
+		t.Errorf("first undo = %+v, want undone with 1 remaining", got)
+	}
+	if got := fc.current(); got != portConfig(81) {
+		t.Errorf("configuration after the first undo = %s, want %s", got, portConfig(81))
+	}
+
+	if got := undo(t); !got.Undone || got.Remaining != 0 {
+		t.Errorf("second undo = %+v, want undone with 0 remaining", got)
+	}
+	if got := fc.current(); got != portConfig(80) {
+		t.Errorf("configuration after the second undo = %s, want %s", got, portConfig(80))
+	}
+
+	loads := fc.loadCount()
+	if got := undo(t); got.Undone {
+		t.Errorf("undo with nothing to undo = %+v, want nothing undone", got)
+	}
+	if fc.loadCount() != loads {
+		t.Errorf("undo with nothing to undo loaded a configuration")
+	}
+}
+
+func TestUndoDepth(t *testing.T) {
+	resetUndo(t)
+	fc := newFakeCaddy(t, portConfig(80))
+
+	oldDepth := undoDepth
+	undoDepth = 2
+	defer func() { undoDepth = oldDepth }()
+
+	for port := 81; port <= 84; port++ {
+		updateConfig(t, portConfig(port))
+	}
+
+	undo(t)
+	if got := undo(t); !got.Undone || got.Remaining != 0 {
+		t.Errorf("second undo = %+v, want undone with 0 remaining", got)
+	}
+	if got := fc.current(); got != portConfig(82) {
+		t.Errorf("configuration after undoing -undo-depth changes = %s, want %s", got, portConfig(82))
+	}
+}
+
+func TestUndoKeepsSnapshotWhenRejected(t *testing.T) {
+	resetUndo(t)
+	fc := newFakeCaddy(t, portConfig(80))
+
+	updateConfig(t, portConfig(81))
+
+	fc.reject(http.StatusBadRequest)
+	result, err := callTool(undoCaddyConfigHandler, "undo_caddy_config", nil)
+	if err != nil {
+		t.Fatalf("undo_caddy_config: %v", err)
+	}
+	var caddyerr caddyError
+	if err := json.Unmarshal([]byte(resultText(t, result)), &caddyerr); err != nil || caddyerr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("rejected undo = %s, want the caddy error", resultText(t, result))
+	}
+
+	fc.reject(0)
+	if got := undo(t); !got.Undone {
+		t.Errorf("undo after a rejected undo = %+v, want it undone", got)
+	}
+	if got := fc.current(); got != portConfig(80) {
+		t.Errorf("configuration after retrying the undo = %s, want %s", got, portConfig(80))
+	}
+}
+
+// Call a tool like the MCP server does, with the tool name in the context, and confirm the change it proposes
+func callAndConfirm(t *testing.T, handler func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error), name string, args map[string]any) {
+	t.Helper()
+
+	result, err := callTool(ticketMiddleware(handler), name, args)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+
+	var proposal confirmationRequired
+	if err := json.Unmarshal([]byte(resultText(t, result)), &proposal); err != nil || proposal.Token == "" {
+		t.Fatalf("%s = %s, want a proposal", name, resultText(t, result))
+	}
+
+	if _, err := callTool(confirmChangeHandler, "confirm_change", map[string]any{"token": proposal.Token}); err != nil {
+		t.Fatalf("confirm_change: %v", err)
+	}
+}
+
+func TestUndoWithConfirmation(t *testing.T) {
+	resetUndo(t)
+	useConfirmation(t)
+	fc := newFakeCaddy(t, portConfig(80))
+
+	callAndConfirm(t, updateCaddyConfigHandler, "update_caddy_config", map[string]any{"json_config": portConfig(81)})
+	if got := undoRemaining(context.Background()); got != 1 {
+		t.Fatalf("%d changes can be undone after a confirmed update, want 1", got)
+	}
+
+	// A proposed undo keeps its snapshot until it is confirmed
+	if _, err := callTool(ticketMiddleware(undoCaddyConfigHandler), "undo_caddy_config", nil); err != nil {
+		t.Fatalf("undo_caddy_config: %v", err)
+	}
+	if got := undoRemaining(context.Background()); got != 1 {
+		t.Errorf("%d changes can be undone after an unconfirmed undo, want 1", got)
+	}
+
+	callAndConfirm(t, undoCaddyConfigHandler, "undo_caddy_config", nil)
+	if got := fc.current(); got != portConfig(80) {
+		t.Errorf("configuration after a confirmed undo = %s, want %s", got, portConfig(80))
+	}
+	if got := undoRemaining(context.Background()); got != 0 {
+		t.Errorf("%d changes can be undone after a confirmed undo, want 0", got)
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Maximum size of a configuration assembled from chunks
+const maxUploadSize = 32 << 20
+
+// Maximum number of uploads in progress at the same time
+const maxUploads = 4
+
+// How long an upload is kept after its last chunk before it is discarded
+const uploadTTL = 15 * time.Minute
+
+type pendingUpload struct {
+	buf       bytes.Buffer
+	expiresAt time.Time
+}
+
+var (
+	uploadsMu sync.Mutex
+	uploads   = map[string]*pendingUpload{}
+)
+
+func registerUploadTools(s *server.MCPServer) {
+	beginConfigUpload := mcp.NewTool("begin_config_upload",
+		mcp.WithDescription(`
+		Use the begin_config_upload tool to start uploading a large caddy JSON configuration in chunks.
+
+		Returns an upload id to pass to the append_config_chunk and commit_config_upload tools.
+
+		Notes:
+			Use the chunked upload only when the configuration is too large to pass to the update_caddy_config tool in a single call.
+			An upload is discarded 15 minutes after its last chunk, and at most 4 uploads can be in progress at the same time.
+		`),
+	)
+
+	// Add begin config upload tool handler
+	s.AddTool(beginConfigUpload, beginConfigUploadHandler)
+
+	appendConfigChunk := mcp.NewTool("append_config_chunk",
+		mcp.WithDescription(`
+		Use the append_config_chunk tool to append the next chunk of a configuration to an upload started with the begin_config_upload tool.
+
+		Notes:
+			Chunks are concatenated exactly as given and in the order they are appended.
+		`),
+		mcp.WithString("upload_id",
+			mcp.Required(),
+			mcp.Description("The upload id returned by the begin_config_upload tool"),
+		),
+		mcp.WithString("data",
+			mcp.Required(),
+			mcp.Description("The next chunk of the JSON configuration"),
+		),
+	)
+
+	// Add append config chunk tool handler
+	s.AddTool(appendConfigChunk, appendConfigChunkHandler)
+
+	commitConfigUpload := mcp.NewTool("commit_config_upload",
+		mcp.WithDescription(`
+		Use the commit_config_upload tool to validate the uploaded configuration and load it into the caddy server.
+
+		Notes:
+			The upload is discarded after it is committed, whether or not the configuration was valid.
+			On success the result is a JSON document with the warnings caddy returned for the load.
+		`),
+		mcp.WithString("upload_id",
+			mcp.Required(),
+			mcp.Description("The upload id returned by the begin_config_upload tool"),
+		),
+	)
+
+	// Add commit config upload tool handler
+	s.AddTool(commitConfigUpload, commitConfigUploadHandler)
+}
+
+// Start a new chunked upload
+func beginConfigUploadHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	uploadID := hex.EncodeToString(id)
+
+	uploadsMu.Lock()
+	defer uploadsMu.Unlock()
+
+	// Drop the uploads that were abandoned so they do not hold memory
+	for id, upload := range uploads {
+		if time.Now().After(upload.expiresAt) {
+			delete(uploads, id)
+		}
+	}
+
+	if len(uploads) >= maxUploads {
+		return nil, fmt.Errorf("%d uploads are already in progress; commit them or wait for them to expire", len(uploads))
+	}
+
+	uploads[uploadID] = &pendingUpload{expiresAt: time.Now().Add(uploadTTL)}
+
+	return mcp.NewToolResultText(fmt.Sprintf("Started upload %s", uploadID)), nil
+}
+
+// Append a chunk to an upload
+func appendConfigChunkHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	uploadID, err := request.RequireString("upload_id")
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := request.RequireString("data")
+	if err != nil {
+		return nil, err
+	}
+
+	uploadsMu.Lock()
+	defer uploadsMu.Unlock()
+
+	upload, ok := uploads[uploadID]
+	if !ok || time.Now().After(upload.expiresAt) {
+		delete(uploads, uploadID)
+		return nil, fmt.Errorf("unknown or expired upload id: %s", uploadID)
+	}
+
+	if upload.buf.Len()+len(data) > maxUploadSize {
+		delete(uploads, uploadID)
+		return nil, fmt.Errorf("upload exceeds the maximum size of %d bytes and was discarded", maxUploadSize)
+	}
+
+	upload.buf.WriteString(data)
+	upload.expiresAt = time.Now().Add(uploadTTL)
+
+	return mcp.NewToolResultText(fmt.Sprintf("Upload %s now holds %d bytes", uploadID, upload.buf.Len())), nil
+}
+
+// Validate and load an uploaded configuration
+func commitConfigUploadHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	uploadID, err := request.RequireString("upload_id")
+	if err != nil {
+		return nil, err
+	}
+
+	uploadsMu.Lock()
+	upload, ok := uploads[uploadID]
+	delete(uploads, uploadID)
+	uploadsMu.Unlock()
+
+	if !ok || time.Now().After(upload.expiresAt) {
+		return nil, fmt.Errorf("unknown or expired upload id: %s", uploadID)
+	}
+
+	config := upload.buf.Bytes()
+	if err := validateConfig(config); err != nil {
+		return nil, err
+	}
+
+	body, err := loadConfig(ctx, config)
+	if err != nil {
+		return caddyErrorResult(err)
+	}
+
+	data, err := json.Marshal(parseLoadResponse(body))
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
+}
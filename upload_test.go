@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Start an upload and return its id
+func beginUpload(t *testing.T) string {
+	t.Helper()
+
+	result, err := callTool(beginConfigUploadHandler, "begin_config_upload", nil)
+	if err != nil {
+		t.Fatalf("begin_config_upload: %v", err)
+	}
+
+	return strings.TrimPrefix(resultText(t, result), "Started upload ")
+}
+
+func TestChunkedUpload(t *testing.T) {
+	fc := newFakeCaddy(t, `{}`)
+
+	uploadID := beginUpload(t)
+	for _, chunk := range []string{`{"apps":`, `{"http":`, `{"servers":{}}}}`} {
+		if _, err := callTool(appendConfigChunkHandler, "append_config_chunk", map[string]any{"upload_id": uploadID, "data": chunk}); err != nil {
+			t.Fatalf("append_config_chunk: %v", err)
+		}
+	}
+
+	result, err := callTool(commitConfigUploadHandler, "commit_config_upload", map[string]any{"upload_id": uploadID})
+	if err != nil {
+		t.Fatalf("commit_config_upload: %v", err)
+	}
+
+	var load loadResult
+	if err := json.Unmarshal([]byte(resultText(t, result)), &load); err != nil || !load.Loaded {
+		t.Errorf("commit_config_upload = %s, want a load result", resultText(t, result))
+	}
+
+	if got := fc.current(); got != `{"apps":{"http":{"servers":{}}}}` {
+		t.Errorf("loaded configuration = %s, want the assembled chunks", got)
+	}
+
+	// An upload can only be committed once
+	if _, err := callTool(commitConfigUploadHandler, "commit_config_upload", map[string]any{"upload_id": uploadID}); err == nil {
+		t.Errorf("committing an upload twice returned no error")
+	}
+}
+
+func TestChunkedUploadInvalidConfig(t *testing.T) {
+	fc := newFakeCaddy(t, `{}`)
+
+	uploadID := beginUpload(t)
+	if _, err := callTool(appendConfigChunkHandler, "append_config_chunk", map[string]any{"upload_id": uploadID, "data": `{"apps":`}); err != nil {
+		t.Fatalf("append_config_chunk: %v", err)
+	}
+
+	if _, err := callTool(commitConfigUploadHandler, "commit_config_upload", map[string]any{"upload_id": uploadID}); err == nil {
+		t.Errorf("commit_config_upload of a truncated configuration returned no error")
+	}
+	if fc.loadCount() != 0 {
+		t.Errorf("a truncated configuration was loaded")
+	}
+}
+
+func TestChunkedUploadTooLarge(t *testing.T) {
+	uploadID := beginUpload(t)
+
+	chunk := strings.Repeat(" ", maxUploadSize/2+1)
+	if _, err := callTool(appendConfigChunkHandler, "append_config_chunk", map[string]any{"upload_id": uploadID, "data": chunk}); err != nil {
+		t.Fatalf("append_config_chunk: %v", err)
+	}
+	if _, err := callTool(appendConfigChunkHandler, "append_config_chunk", map[string]any{"upload_id": uploadID, "data": chunk}); err == nil {
+		t.Fatalf("append_config_chunk beyond the maximum size returned no error")
+	}
+
+	// The upload was discarded
+	if _, err := callTool(appendConfigChunkHandler, "append_config_chunk", map[string]any{"upload_id": uploadID, "data": "{}"}); err == nil {
+		t.Errorf("append_config_chunk to a discarded upload returned no error")
+	}
+}
+
+// Start a test without uploads in progress
+func resetUploads(t *testing.T) {
+	t.Helper()
+
+	uploadsMu.Lock()
+	uploads = map[string]*pendingUpload{}
+	uploadsMu.Unlock()
+
+	t.Cleanup(func() {
+		uploadsMu.Lock()
+		uploads = map[string]*pendingUpload{}
+		uploadsMu.Unlock()
+	})
+}
+
+func TestChunkedUploadLimit(t *testing.T) {
+	resetUploads(t)
+
+	for range maxUploads {
+		beginUpload(t)
+	}
+
+	if _, err := callTool(beginConfigUploadHandler, "begin_config_upload", nil); err == nil {
+		t.Errorf("begin_config_upload beyond %d uploads in progress returned no error", maxUploads)
+	}
+}
+
+func TestChunkedUploadExpires(t *testing.T) {
+	resetUploads(t)
+
+	expired := beginUpload(t)
+	for range maxUploads - 1 {
+		beginUpload(t)
+	}
+
+	uploadsMu.Lock()
+	uploads[expired].expiresAt = time.Now().Add(-time.Second)
+	uploadsMu.Unlock()
+
+	// The expired upload no longer counts against the limit
+	beginUpload(t)
+
+	if _, err := callTool(appendConfigChunkHandler, "append_config_chunk", map[string]any{"upload_id": expired, "data": "{}"}); err == nil {
+		t.Errorf("append_config_chunk to an expired upload returned no error")
+	}
+}
@@ -0,0 +1,948 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Maximum number of upstreams probed at the same time by health_sweep
+const healthSweepWorkers = 16
+
+type upstreamProbe struct {
+	Dial      string `json:"dial"`
+	Address   string `json:"address"`
+	Network   string `json:"network"`
+	Reachable bool   `json:"reachable"`
+	Status    int    `json:"status,omitempty"`
+	Latency   string `json:"latency,omitempty"`
+	Error     string `json:"error,omitempty"`
+
+	tls bool
+}
+
+type upstreamStatus struct {
+	Address     string `json:"address"`
+	NumRequests int    `json:"num_requests"`
+	Fails       int    `json:"fails"`
+}
+
+type upstreamSnapshot struct {
+	CapturedAt time.Time        `json:"captured_at"`
+	Upstreams  []upstreamStatus `json:"upstreams"`
+}
+
+type upstreamHealthStatus struct {
+	upstreamStatus
+	Health string `json:"health"`
+}
+
+type upstreamHealthChange struct {
+	Address       string `json:"address"`
+	Change        string `json:"change"`
+	BeforeStatus  string `json:"before_status,omitempty"`
+	AfterStatus   string `json:"after_status,omitempty"`
+	BeforeFails   int    `json:"before_fails"`
+	AfterFails    int    `json:"after_fails"`
+	RequestsDelta int    `json:"requests_delta"`
+}
+
+type upstreamHealthComparison struct {
+	Compared  bool                   `json:"compared"`
+	Note      string                 `json:"note,omitempty"`
+	Previous  *upstreamSnapshot      `json:"previous,omitempty"`
+	Current   upstreamSnapshot       `json:"current"`
+	Changes   []upstreamHealthChange `json:"changes"`
+	Unchanged int                    `json:"unchanged"`
+}
+
+// The snapshot taken by the last call to compare_upstream_health
+var (
+	upstreamSnapshotMu   sync.Mutex
+	lastUpstreamSnapshot *upstreamSnapshot
+)
+
+type socketUpstream struct {
+	Server string `json:"server"`
+	Dial   string `json:"dial"`
+	Path   string `json:"path"`
+	Exists bool   `json:"exists"`
+	Socket bool   `json:"socket"`
+	Error  string `json:"error,omitempty"`
+}
+
+type socketUpstreamReport struct {
+	Upstreams []socketUpstream `json:"upstreams"`
+	Missing   []string         `json:"missing"`
+}
+
+type drainedUpstream struct {
+	Server    string         `json:"server"`
+	Route     string         `json:"route"`
+	Upstream  map[string]any `json:"upstream"`
+	DrainedAt time.Time      `json:"drained_at"`
+}
+
+type upstreamListResult struct {
+	Server    string   `json:"server"`
+	Route     int      `json:"route"`
+	Upstreams []string `json:"upstreams"`
+	Drained   []string `json:"drained"`
+}
+
+// Upstreams removed by drain_upstream, keyed by server name and dial address
+var (
+	drainedMu        sync.Mutex
+	drainedUpstreams = map[string]*drainedUpstream{}
+)
+
+type healthSweepResult struct {
+	Mode        string          `json:"mode"`
+	Total       int             `json:"total"`
+	Reachable   int             `json:"reachable"`
+	Unreachable int             `json:"unreachable"`
+	Upstreams   []upstreamProbe `json:"upstreams"`
+}
+
+func registerUpstreamTools(s *server.MCPServer) {
+	healthSweep := mcp.NewTool("health_sweep",
+		mcp.WithDescription(`
+		Use the health_sweep tool to probe every reverse proxy upstream in the caddy configuration from this MCP server and report which are reachable.
+
+		The result is a JSON document with a summary and, for each upstream, whether it was reachable and how long the probe took.
+
+		Notes:
+			The "tcp" mode only opens a connection. The "http" mode sends a HEAD request and reports the status code (certificates of HTTPS upstreams are not verified).
+			Upstreams are probed from the host running this MCP server, which may see the network differently than caddy.
+			Upstreams using placeholders or dynamic upstreams cannot be probed and are skipped.
+		`),
+		mcp.WithString("mode",
+			mcp.Description("How to probe each upstream: tcp or http"),
+			mcp.Enum("tcp", "http"),
+			mcp.DefaultString("tcp"),
+		),
+		mcp.WithNumber("timeout_ms",
+			mcp.Description("The timeout of each probe in milliseconds (default 2000)"),
+			mcp.DefaultNumber(2000),
+		),
+	)
+
+	// Add health sweep tool handler
+	s.AddTool(healthSweep, healthSweepHandler)
+
+	compareUpstreamHealth := mcp.NewTool("compare_upstream_health",
+		mcp.WithDescription(`
+		Use the compare_upstream_health tool to see how the health of the reverse proxy upstreams changed over time, for example to detect flapping backends.
+
+		The current upstream statuses are captured and compared with a previous snapshot: the one passed in previous, or else the one captured by the last call to this tool. The result is a JSON document listing the upstreams that appeared, disappeared, changed status or had their fail count increase, and both snapshots.
+
+		Notes:
+			The first call only captures a snapshot; call the tool again later to compare.
+			An upstream is "healthy" when its fail count is 0 and "failing" otherwise. Fail counts come from passive health checks and drop again once the fail duration expires.
+			The snapshot is kept in memory and is lost when the MCP server restarts.
+		`),
+		mcp.WithString("previous",
+			mcp.Description("A previous snapshot to compare with, as returned by this tool or the upstream_proxy_statuses tool"),
+		),
+	)
+
+	// Add compare upstream health tool handler
+	s.AddTool(compareUpstreamHealth, compareUpstreamHealthHandler)
+
+	checkSocketUpstreams := mcp.NewTool("check_socket_upstreams",
+		mcp.WithDescription(`
+		Use the check_socket_upstreams tool to check that the Unix socket upstreams of the reverse proxies in a caddy JSON configuration exist.
+
+		Every upstream dialing a Unix socket, like unix//run/app.sock, is checked. The result is a JSON document with, for each socket upstream, whether the path exists and is a socket, and the list of paths that are missing or are not sockets.
+
+		Notes:
+			Paths are checked on the host running this MCP server, which is only caddy's host when both run on the same machine and see the same filesystem.
+			Upstreams using placeholders are skipped.
+			If json_config is not provided the current caddy server configuration is checked.
+		`),
+		mcp.WithString("json_config",
+			mcp.Description("The caddy JSON configuration to check instead of the current configuration"),
+		),
+	)
+
+	// Add check socket upstreams tool handler
+	s.AddTool(checkSocketUpstreams, checkSocketUpstreamsHandler)
+
+	drainUpstream := mcp.NewTool("drain_upstream",
+		mcp.WithDescription(`
+		Use the drain_upstream tool to take one upstream of a route's reverse proxy out of rotation, for example before restarting that backend during a rolling deploy.
+
+		The upstream is removed from the upstreams of the reverse_proxy handlers of the route and remembered so the restore_upstream tool can add it back with the same settings.
+		The result is a JSON document with the remaining upstreams of the route and the upstreams of the server that are drained.
+
+		Notes:
+			Requests already sent to the upstream are completed; new requests go to the other upstreams.
+			The last upstream of a reverse proxy cannot be drained.
+			Drained upstreams are kept in memory and are lost when the MCP server restarts; the configuration of the caddy server then has to be fixed by hand with the dial address.
+		`),
+		mcp.WithString("server_name",
+			mcp.Required(),
+			mcp.Description("The name of the server in apps/http/servers"),
+		),
+		mcp.WithString("route",
+			mcp.Required(),
+			mcp.Description("The route index or a host matched by the route"),
+		),
+		mcp.WithString("address",
+			mcp.Required(),
+			mcp.Description("The dial address of the upstream, for example 10.0.0.5:8080"),
+		),
+	)
+
+	// Add drain upstream tool handler
+	s.AddTool(drainUpstream, drainUpstreamHandler)
+
+	restoreUpstream := mcp.NewTool("restore_upstream",
+		mcp.WithDescription(`
+		Use the restore_upstream tool to put an upstream taken out of rotation by the drain_upstream tool back into its route's reverse proxy.
+
+		The result is a JSON document with the upstreams of the route after the upstream was added back and the upstreams of the server that are still drained.
+
+		Notes:
+			The upstream is added back to the route it was drained from, with the settings it had. Use the same server_name and address as when draining it.
+		`),
+		mcp.WithString("server_name",
+			mcp.Required(),
+			mcp.Description("The name of the server in apps/http/servers"),
+		),
+		mcp.WithString("address",
+			mcp.Required(),
+			mcp.Description("The dial address of the drained upstream"),
+		),
+	)
+
+	// Add restore upstream tool handler
+	s.AddTool(restoreUpstream, restoreUpstreamHandler)
+
+	getUpstreamStatus := mcp.NewTool("get_upstream_status",
+		mcp.WithDescription(`
+		Use the get_upstream_status tool to check the health of a single reverse proxy upstream, for example to answer whether a backend is up.
+
+		The result is a JSON document with the upstream's address, its number of active requests, its fail count and its health: "healthy" when the fail count is 0 and "failing" otherwise.
+
+		Notes:
+			The address must match the upstream's dial address as caddy reports it, for example localhost:8080; the known addresses are listed when there is no such upstream.
+			Fail counts come from passive health checks; use the health_sweep tool to probe upstreams directly.
+		`),
+		mcp.WithString("address",
+			mcp.Required(),
+			mcp.Description("The dial address of the upstream, for example localhost:8080"),
+		),
+	)
+
+	// Add get upstream status tool handler
+	s.AddTool(getUpstreamStatus, getUpstreamStatusHandler)
+}
+
+// Get the dial addresses of the upstreams of a reverse_proxy handler
+func proxyUpstreams(handler map[string]any) []string {
+	var dials []string
+
+	upstreams, _ := handler["upstreams"].([]any)
+	for _, u := range upstreams {
+		upstream, ok := u.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		if dial, ok := upstream["dial"].(string); ok {
+			dials = append(dials, dial)
+		}
+	}
+
+	return dials
+}
+
+// Check that an upstream address is a host:port
+func validateUpstream(address string) error {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("invalid upstream %q: expected host:port", address)
+	}
+
+	if host == "" {
+		return fmt.Errorf("invalid upstream %q: missing host", address)
+	}
+
+	if n, err := strconv.Atoi(port); err != nil || n <= 0 || n > 65535 {
+		return fmt.Errorf("invalid upstream %q: invalid port %q", address, port)
+	}
+
+	return nil
+}
+
+// Check whether a reverse_proxy handler connects to its upstreams over TLS
+func proxyUsesTLS(handler map[string]any) bool {
+	transport, _ := handler["transport"].(map[string]any)
+	_, ok := transport["tls"]
+	return ok
+}
+
+// Call fn for every reverse_proxy handler in a decoded config
+func walkProxyHandlers(cfg map[string]any, fn func(serverName string, handler map[string]any)) {
+	for _, name := range httpServerNames(cfg) {
+		srv, err := httpServer(cfg, name)
+		if err != nil {
+			continue
+		}
+
+		walkHandlers(serverRoutes(srv), func(handler map[string]any) {
+			if handler["handler"] == "reverse_proxy" {
+				fn(name, handler)
+			}
+		})
+	}
+}
+
+// Build the probes for every upstream in a decoded config, expanding port ranges
+func upstreamProbes(cfg map[string]any) []upstreamProbe {
+	seen := map[string]bool{}
+	var probes []upstreamProbe
+
+	walkProxyHandlers(cfg, func(_ string, handler map[string]any) {
+		useTLS := proxyUsesTLS(handler)
+		for _, dial := range proxyUpstreams(handler) {
+			if strings.Contains(dial, "{") {
+				continue
+			}
+
+			addr, err := caddy.ParseNetworkAddress(dial)
+			if err != nil {
+				probes = append(probes, upstreamProbe{Dial: dial, Address: dial, Error: err.Error()})
+				continue
+			}
+
+			targets := []caddy.NetworkAddress{addr}
+			if !addr.IsUnixNetwork() {
+				targets = addr.Expand()
+			}
+
+			for _, target := range targets {
+				address := target.JoinHostPort(0)
+				if seen[target.Network+address] {
+					continue
+				}
+				seen[target.Network+address] = true
+
+				probes = append(probes, upstreamProbe{
+					Dial:    dial,
+					Address: address,
+					Network: target.Network,
+					tls:     useTLS,
+				})
+			}
+		}
+	})
+
+	return probes
+}
+
+// Probe a single upstream by connecting to it or sending a HEAD request
+func probeUpstream(ctx context.Context, probe *upstreamProbe, mode string, timeout time.Duration) {
+	if probe.Error != "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	dialer := &net.Dialer{}
+
+	if mode != "http" || strings.HasPrefix(probe.Network, "unix") {
+		conn, err := dialer.DialContext(ctx, probe.Network, probe.Address)
+		probe.Latency = time.Since(start).String()
+		if err != nil {
+			probe.Error = err.Error()
+			return
+		}
+		conn.Close()
+		probe.Reachable = true
+		return
+	}
+
+	scheme := "http"
+	if probe.tls {
+		scheme = "https"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, fmt.Sprintf("%s://%s/", scheme, probe.Address), nil)
+	if err != nil {
+		probe.Error = err.Error()
+		return
+	}
+
+	probeClient := &http.Client{
+		Transport: &http.Transport{
+			DialContext:     dialer.DialContext,
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	resp, err := probeClient.Do(req)
+	probe.Latency = time.Since(start).String()
+	if err != nil {
+		probe.Error = err.Error()
+		return
+	}
+	resp.Body.Close()
+
+	probe.Status = resp.StatusCode
+	probe.Reachable = true
+}
+
+// Probe every configured upstream with a bounded number of workers
+func healthSweepHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	mode := request.GetString("mode", "tcp")
+	if mode != "tcp" && mode != "http" {
+		return nil, fmt.Errorf("unsupported mode: %s", mode)
+	}
+
+	timeoutMs := request.GetInt("timeout_ms", 2000)
+	if timeoutMs <= 0 {
+		return nil, fmt.Errorf("timeout_ms must be positive")
+	}
+	timeout := time.Duration(timeoutMs) * time.Millisecond
+
+	cfg, err := fetchConfigMap(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	probes := upstreamProbes(cfg)
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(healthSweepWorkers, len(probes)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				probeUpstream(ctx, &probes[i], mode, timeout)
+			}
+		}()
+	}
+
+	for i := range probes {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	sort.Slice(probes, func(i, j int) bool {
+		return probes[i].Address < probes[j].Address
+	})
+
+	result := healthSweepResult{
+		Mode:      mode,
+		Total:     len(probes),
+		Upstreams: []upstreamProbe{},
+	}
+	for _, probe := range probes {
+		if probe.Reachable {
+			result.Reachable++
+		} else {
+			result.Unreachable++
+		}
+		result.Upstreams = append(result.Upstreams, probe)
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+// Get the status of the reverse proxy upstreams from the admin API
+func fetchUpstreamStatuses(ctx context.Context) ([]upstreamStatus, error) {
+	status, body, err := adminRequest(ctx, http.MethodGet, "/reverse_proxy/upstreams", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if status != http.StatusOK {
+		return nil, &caddyError{
+			StatusCode: status,
+			Message:    string(body),
+		}
+	}
+
+	statuses := []upstreamStatus{}
+	if err := json.Unmarshal(body, &statuses); err != nil {
+		return nil, fmt.Errorf("invalid upstream statuses: %v", err)
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Address < statuses[j].Address
+	})
+
+	return statuses, nil
+}
+
+// Parse a previous upstream snapshot, either a full snapshot or a bare list of statuses
+func parseUpstreamSnapshot(data string) (*upstreamSnapshot, error) {
+	var snapshot upstreamSnapshot
+	if err := json.Unmarshal([]byte(data), &snapshot); err == nil && snapshot.Upstreams != nil {
+		return &snapshot, nil
+	}
+
+	if err := json.Unmarshal([]byte(data), &snapshot.Upstreams); err != nil {
+		return nil, fmt.Errorf("invalid previous snapshot: expected a snapshot or a list of upstream statuses")
+	}
+
+	return &snapshot, nil
+}
+
+// Describe the health of an upstream from its fail count
+func upstreamHealth(fails int) string {
+	if fails > 0 {
+		return "failing"
+	}
+	return "healthy"
+}
+
+// Compare two upstream snapshots and list the upstreams whose health changed
+func diffUpstreamSnapshots(previous, current []upstreamStatus) ([]upstreamHealthChange, int) {
+	before := map[string]upstreamStatus{}
+	for _, u := range previous {
+		before[u.Address] = u
+	}
+
+	changes := []upstreamHealthChange{}
+	unchanged := 0
+	seen := map[string]bool{}
+
+	for _, after := range current {
+		seen[after.Address] = true
+
+		old, ok := before[after.Address]
+		if !ok {
+			changes = append(changes, upstreamHealthChange{
+				Address:     after.Address,
+				Change:      "added",
+				AfterStatus: upstreamHealth(after.Fails),
+				AfterFails:  after.Fails,
+			})
+			continue
+		}
+
+		change := upstreamHealthChange{
+			Address:       after.Address,
+			BeforeStatus:  upstreamHealth(old.Fails),
+			AfterStatus:   upstreamHealth(after.Fails),
+			BeforeFails:   old.Fails,
+			AfterFails:    after.Fails,
+			RequestsDelta: after.NumRequests - old.NumRequests,
+		}
+
+		switch {
+		case change.BeforeStatus != change.AfterStatus && after.Fails == 0:
+			change.Change = "recovered"
+		case change.BeforeStatus != change.AfterStatus:
+			change.Change = "started_failing"
+		case after.Fails > old.Fails:
+			change.Change = "fails_increased"
+		default:
+			unchanged++
+			continue
+		}
+
+		changes = append(changes, change)
+	}
+
+	for _, old := range previous {
+		if !seen[old.Address] {
+			changes = append(changes, upstreamHealthChange{
+				Address:      old.Address,
+				Change:       "removed",
+				BeforeStatus: upstreamHealth(old.Fails),
+				BeforeFails:  old.Fails,
+			})
+		}
+	}
+
+	return changes, unchanged
+}
+
+// Capture the upstream statuses and compare them with a previous snapshot
+func compareUpstreamHealthHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var previous *upstreamSnapshot
+	if data := request.GetString("previous", ""); data != "" {
+		snapshot, err := parseUpstreamSnapshot(data)
+		if err != nil {
+			return nil, err
+		}
+		previous = snapshot
+	}
+
+	statuses, err := fetchUpstreamStatuses(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	current := upstreamSnapshot{
+		CapturedAt: time.Now(),
+		Upstreams:  statuses,
+	}
+
+	upstreamSnapshotMu.Lock()
+	if previous == nil {
+		previous = lastUpstreamSnapshot
+	}
+	lastUpstreamSnapshot = &current
+	upstreamSnapshotMu.Unlock()
+
+	result := upstreamHealthComparison{
+		Previous: previous,
+		Current:  current,
+		Changes:  []upstreamHealthChange{},
+	}
+
+	if previous == nil {
+		result.Note = "no previous snapshot to compare with; the current statuses were stored, call the tool again later to compare"
+	} else {
+		result.Compared = true
+		result.Changes, result.Unchanged = diffUpstreamSnapshots(previous.Upstreams, current.Upstreams)
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+// Check that the Unix socket upstreams of a config exist
+func checkSocketUpstreamsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	value, err := configArgument(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("the configuration must be a JSON object")
+	}
+
+	report := socketUpstreamReport{
+		Upstreams: []socketUpstream{},
+		Missing:   []string{},
+	}
+
+	walkProxyHandlers(cfg, func(serverName string, handler map[string]any) {
+		for _, dial := range proxyUpstreams(handler) {
+			if strings.Contains(dial, "{") {
+				continue
+			}
+
+			addr, err := caddy.ParseNetworkAddress(dial)
+			if err != nil || !addr.IsUnixNetwork() {
+				continue
+			}
+
+			upstream := socketUpstream{
+				Server: serverName,
+				Dial:   dial,
+				Path:   addr.Host,
+			}
+
+			info, err := os.Stat(addr.Host)
+			switch {
+			case err != nil:
+				upstream.Error = err.Error()
+			case info.Mode()&os.ModeSocket == 0:
+				upstream.Exists = true
+				upstream.Error = fmt.Sprintf("%s is not a socket", addr.Host)
+			default:
+				upstream.Exists = true
+				upstream.Socket = true
+			}
+
+			if !upstream.Socket && !slices.Contains(report.Missing, upstream.Path) {
+				report.Missing = append(report.Missing, upstream.Path)
+			}
+
+			report.Upstreams = append(report.Upstreams, upstream)
+		}
+	})
+
+	sort.Strings(report.Missing)
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+// Key of a drained upstream
+func drainedKey(serverName, address string) string {
+	return serverName + "/" + address
+}
+
+// List the drained upstreams of a server, sorted
+func drainedAddresses(serverName string) []string {
+	drainedMu.Lock()
+	defer drainedMu.Unlock()
+
+	addresses := []string{}
+	for _, drained := range drainedUpstreams {
+		if drained.Server == serverName {
+			dial, _ := drained.Upstream["dial"].(string)
+			addresses = append(addresses, dial)
+		}
+	}
+	sort.Strings(addresses)
+
+	return addresses
+}
+
+// Find the reverse_proxy handlers of a route
+func routeProxies(route map[string]any) []map[string]any {
+	var proxies []map[string]any
+	walkHandlers([]any{route}, func(handler map[string]any) {
+		if handler["handler"] == "reverse_proxy" {
+			proxies = append(proxies, handler)
+		}
+	})
+
+	return proxies
+}
+
+// Apply a configuration whose route upstreams changed and describe the route's upstreams
+func applyUpstreamChange(ctx context.Context, cfg map[string]any, serverName string, index int, route map[string]any) (*mcp.CallToolResult, error) {
+	if _, err := loadConfigMap(ctx, cfg); err != nil {
+		return caddyErrorResult(err)
+	}
+
+	result := upstreamListResult{
+		Server:    serverName,
+		Route:     index,
+		Upstreams: []string{},
+		Drained:   drainedAddresses(serverName),
+	}
+	for _, proxy := range routeProxies(route) {
+		result.Upstreams = append(result.Upstreams, proxyUpstreams(proxy)...)
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+// Remove an upstream from the reverse proxies of a route and remember it
+func drainUpstreamHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	serverName, err := request.RequireString("server_name")
+	if err != nil {
+		return nil, err
+	}
+
+	selector, err := request.RequireString("route")
+	if err != nil {
+		return nil, err
+	}
+
+	address, err := request.RequireString("address")
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := fetchConfigMap(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	srv, err := httpServer(cfg, serverName)
+	if err != nil {
+		return nil, err
+	}
+
+	index, route, err := resolveRoute(srv, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed map[string]any
+	for _, proxy := range routeProxies(route) {
+		upstreams, _ := proxy["upstreams"].([]any)
+		remaining := slices.DeleteFunc(slices.Clone(upstreams), func(u any) bool {
+			upstream, _ := u.(map[string]any)
+			if upstream["dial"] != address {
+				return false
+			}
+			removed = upstream
+			return true
+		})
+		if len(remaining) == len(upstreams) {
+			continue
+		}
+		if len(remaining) == 0 {
+			return nil, fmt.Errorf("%s is the last upstream of the reverse proxy of route %d and cannot be drained", address, index)
+		}
+		proxy["upstreams"] = remaining
+	}
+
+	if removed == nil {
+		return nil, fmt.Errorf("route %d has no reverse proxy upstream %s", index, address)
+	}
+
+	// Restore the route by @id when it has one, since indexes change as routes are added
+	routeKey, _ := route["@id"].(string)
+	if routeKey == "" {
+		routeKey = strconv.Itoa(index)
+	}
+
+	drainedMu.Lock()
+	drainedUpstreams[drainedKey(serverName, address)] = &drainedUpstream{
+		Server:    serverName,
+		Route:     routeKey,
+		Upstream:  removed,
+		DrainedAt: time.Now(),
+	}
+	drainedMu.Unlock()
+
+	result, err := applyUpstreamChange(ctx, cfg, serverName, index, route)
+	if err != nil || result.IsError {
+		drainedMu.Lock()
+		delete(drainedUpstreams, drainedKey(serverName, address))
+		drainedMu.Unlock()
+	}
+
+	return result, err
+}
+
+// Add a drained upstream back to the reverse proxies of its route
+func restoreUpstreamHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	serverName, err := request.RequireString("server_name")
+	if err != nil {
+		return nil, err
+	}
+
+	address, err := request.RequireString("address")
+	if err != nil {
+		return nil, err
+	}
+
+	key := drainedKey(serverName, address)
+
+	drainedMu.Lock()
+	drained, ok := drainedUpstreams[key]
+	drainedMu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("upstream %s of server %s was not drained", address, serverName)
+	}
+
+	cfg, err := fetchConfigMap(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	srv, err := httpServer(cfg, serverName)
+	if err != nil {
+		return nil, err
+	}
+
+	var index int
+	var route map[string]any
+	if _, err := strconv.Atoi(drained.Route); err == nil {
+		index, route, err = resolveRoute(srv, drained.Route)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		for i, r := range serverRoutes(srv) {
+			if candidate, _ := r.(map[string]any); candidate["@id"] == drained.Route {
+				index, route = i, candidate
+			}
+		}
+		if route == nil {
+			return nil, fmt.Errorf("the route with @id %s the upstream was drained from no longer exists", drained.Route)
+		}
+	}
+
+	proxies := routeProxies(route)
+	if len(proxies) == 0 {
+		return nil, fmt.Errorf("route %d no longer has a reverse_proxy handler", index)
+	}
+
+	for _, proxy := range proxies {
+		if slices.Contains(proxyUpstreams(proxy), address) {
+			continue
+		}
+		upstreams, _ := proxy["upstreams"].([]any)
+		proxy["upstreams"] = append(upstreams, drained.Upstream)
+	}
+
+	drainedMu.Lock()
+	delete(drainedUpstreams, key)
+	drainedMu.Unlock()
+
+	result, err := applyUpstreamChange(ctx, cfg, serverName, index, route)
+	if err != nil || result.IsError {
+		drainedMu.Lock()
+		drainedUpstreams[key] = drained
+		drainedMu.Unlock()
+	}
+
+	return result, err
+}
+
+// Report the status of one upstream
+func getUpstreamStatusHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	address, err := request.RequireString("address")
+	if err != nil {
+		return nil, err
+	}
+
+	statuses, err := fetchUpstreamStatuses(ctx)
+	if err != nil {
+		return caddyErrorResult(err)
+	}
+
+	known := make([]string, 0, len(statuses))
+	for _, status := range statuses {
+		if status.Address == address {
+			data, err := json.Marshal(upstreamHealthStatus{
+				upstreamStatus: status,
+				Health:         upstreamHealth(status.Fails),
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			return mcp.NewToolResultText(string(data)), nil
+		}
+		known = append(known, status.Address)
+	}
+
+	return nil, fmt.Errorf("no such upstream: %s (known upstreams: %s)", address, strings.Join(known, ", "))
+}